@@ -2,16 +2,30 @@
 package configs
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
-// ConfigI defines the methods to retrieve the host, port, and site directory.
+// Default values of the optional settings
+const (
+	DEFAULT_SESSION_TTL        = 30 * time.Minute
+	DEFAULT_SESSIONS_MEMORY_MB = 1024
+)
+
+// ConfigI defines the methods to retrieve the host, port, site directory,
+// presets directory, session limits and the number of image workers.
 type ConfigI interface {
 	GetHost() string
 	GetPort() string
 	GetSiteDir() string
+	GetPresetsDir() string
+	GetSessionTTL() time.Duration
+	GetSessionsMemory() int
+	GetWorkers() int
 }
 
 // Config stores the server configuration
@@ -19,6 +33,15 @@ type Config struct {
 	host    string
 	port    string
 	siteDir string
+	// presetsDir is the directory of the edit presets. It may be empty.
+	presetsDir string
+	// sessionTTL is the time after which an unused session expires.
+	sessionTTL time.Duration
+	// sessionsMemory is the memory limit of all sessions in bytes.
+	sessionsMemory int
+	// workers is the number of goroutines that edit the pixels of an image, or
+	// 0 for the number of CPUs.
+	workers int
 }
 
 // GetHost returns the server host
@@ -36,6 +59,27 @@ func (conf *Config) GetSiteDir() string {
 	return conf.siteDir
 }
 
+// GetPresetsDir returns the presets directory
+func (conf *Config) GetPresetsDir() string {
+	return conf.presetsDir
+}
+
+// GetSessionTTL returns the time after which an unused session expires
+func (conf *Config) GetSessionTTL() time.Duration {
+	return conf.sessionTTL
+}
+
+// GetSessionsMemory returns the memory limit of all sessions in bytes
+func (conf *Config) GetSessionsMemory() int {
+	return conf.sessionsMemory
+}
+
+// GetWorkers returns the number of goroutines that edit the pixels of an image,
+// or 0 for the number of CPUs
+func (conf *Config) GetWorkers() int {
+	return conf.workers
+}
+
 // New creates the server configuration by reading information from the 
 // configuration file. Returns an error if the file is read unsuccessfully
 func New(envPath string) (*Config, error) {
@@ -43,10 +87,42 @@ func New(envPath string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	sessionTTL := DEFAULT_SESSION_TTL
+	if value := os.Getenv("SESSION_TTL"); value != "" {
+		sessionTTL, err = time.ParseDuration(value)
+		if err != nil || sessionTTL <= 0 {
+			return nil, fmt.Errorf("SESSION_TTL must be a positive duration, got %q",
+				value)
+		}
+	}
+
+	sessionsMemory := DEFAULT_SESSIONS_MEMORY_MB
+	if value := os.Getenv("SESSIONS_MEMORY_MB"); value != "" {
+		sessionsMemory, err = strconv.Atoi(value)
+		if err != nil || sessionsMemory <= 0 {
+			return nil, fmt.Errorf(
+				"SESSIONS_MEMORY_MB must be a positive integer, got %q", value)
+		}
+	}
+
+	var workers int
+	if value := os.Getenv("WORKERS"); value != "" {
+		workers, err = strconv.Atoi(value)
+		if err != nil || workers <= 0 {
+			return nil, fmt.Errorf("WORKERS must be a positive integer, got %q",
+				value)
+		}
+	}
+
 	return &Config{
 			os.Getenv("SERVER_HOST"),
 			os.Getenv("SERVER_PORT"),
 			os.Getenv("SITE_DIR"),
+			os.Getenv("PRESETS_DIR"),
+			sessionTTL,
+			sessionsMemory << 20,
+			workers,
 		},
 		nil
 }
@@ -2,44 +2,319 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
 	"log"
 	"net/http"
 	"strconv"
 
+	"github.com/NooFreeNames/ImageEditor/internal/server/presets"
 	"github.com/NooFreeNames/ImageEditor/internal/server/utils"
 	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor"
 	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/geom"
 	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/mods"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/resample"
 )
 
-// pingHandler is the handler function for the "/image" URL.
+// maxResizeSize is the maximum width and height of a resized image.
+const maxResizeSize = 10000
+
+// maxPipelineSteps is the maximum number of steps in the ops field.
+const maxPipelineSteps = 32
+
+// maxKernelSize is the maximum number of rows and columns of a custom
+// convolution kernel.
+const maxKernelSize = 25
+
+// maxBlurRadius is the maximum radius of the box_blur filter. The cost of the
+// filter grows with the radius.
+const maxBlurRadius = 100
+
+// autoLevelsClip is the fraction of the darkest and of the brightest pixels
+// ignored by the auto_levels field.
+const autoLevelsClip = 0.005
+
+// adjustments are the numeric color adjustments in the order they are applied.
+var adjustments = []struct {
+	name     string
+	min, max float64
+	modifier func(value float64) mods.PixelModifier
+}{
+	{"brightness", -1, 1, func(value float64) mods.PixelModifier {
+		return mods.NewBrightness(value)
+	}},
+	{"contrast", -1, 1, func(value float64) mods.PixelModifier {
+		return mods.NewContrast(value)
+	}},
+	{"gamma", mods.MIN_GAMMA, mods.MAX_GAMMA,
+		func(value float64) mods.PixelModifier {
+			return mods.NewGamma(value)
+		}},
+	{"exposure", -mods.MAX_EXPOSURE_STOPS, mods.MAX_EXPOSURE_STOPS,
+		func(value float64) mods.PixelModifier {
+			return mods.NewExposure(value)
+		}},
+}
+
+// NewImageHandler creates the handler function for the "/image" URL, which
+// applies the presets from the store and edits the pixels with the given number
+// of workers, or with one worker per CPU if it is 0.
 // Edits the image and writes the result to the request body, and in case of an
-// error writes the error text to the request body. All errors are logged.
+// error writes the error text to the request body. All errors are logged. The
+// editing stops if the client cancels the request.
 //
 // Read values of the POST request:
 //   - image - image file to edit
+//   - auto_orient - whether the image is rotated according to its EXIF
+//     orientation, true by default
+//   - rotate - clockwise rotation angle in degrees
+//   - expand - whether the rotated image is enlarged to fit all its corners,
+//     true by default
+//   - flip - mirror direction: horizontal or vertical
 //   - width - crop width
 //   - height - crop height
 //   - vertical - crop vertical position
 //   - horizontal - crop horizontal position
-//   - filter - image filter
-//   - blure_sigma - degree of blur
-func ImageHandler(response http.ResponseWriter, request *http.Request) {
-	file, meta, err := request.FormFile("image")
-	if err != nil {
-		http.Error(response, "Could not read the file", http.StatusBadRequest)
-		log.Println("Failed to parse 'image' parameter: ", err)
-		return
+//   - resize_width - width of the resized image
+//   - resize_height - height of the resized image
+//   - resample - resampling kernel: nearest, bilinear, bicubic or lanczos3
+//   - fit - how the image is scaled into the resize box: fit, fill or cover.
+//     The image is aligned using the vertical and horizontal values, which
+//     are centered by default
+//   - background - color of the empty parts of the rotated image or of the
+//     box, e.g. #ffffff
+//   - auto_levels - whether the range of each color channel is stretched to
+//     the full range before the adjustments
+//   - brightness - brightness change from -1 to 1
+//   - contrast - contrast change from -1 to 1
+//   - gamma - gamma correction from 0.01 to 10
+//   - exposure - exposure change in stops from -10 to 10
+//   - hue - hue rotation in degrees from -180 to 180
+//   - saturation - saturation multiplier from 0 to 4, 1 by default
+//   - lightness - lightness multiplier from 0 to 4, 1 by default
+//   - hue_target - colors changed by the hue, saturation and lightness: all
+//     (default), reds, yellows, greens, cyans, blues or magentas
+//   - filter - image filter: grayscale, negative, sepia, tint, duotone, blure,
+//     sharpen, emboss, edge_detect, box_blur, unsharp_mask or convolution
+//   - grayscale_mode - how the grayscale filter calculates the gray value:
+//     average (default), rec601, rec709, lightness or desaturate
+//   - sepia_strength - strength of the sepia filter from 0 to 1, 1 by default
+//   - tint_color - color of the tint filter, e.g. #ff8000. It is required by
+//     the tint filter
+//   - tint_amount - strength of the tint filter from 0 to 1, 0.5 by default
+//   - duotone_shadows - color of the dark parts of the image for the duotone
+//     filter, #000000 by default
+//   - duotone_highlights - color of the light parts of the image for the
+//     duotone filter, #ffffff by default
+//   - blure_sigma - degree of blur up to 1000, 2 by default, also used by the
+//     unsharp_mask filter
+//   - blur_radius - radius of the box_blur filter up to 100, 1 by default
+//   - unsharp_amount - strength of the unsharp_mask filter from 0 to 10, 1 by
+//     default
+//   - kernel - JSON array of the rows of the convolution filter kernel, e.g.
+//     [[0, -1, 0], [-1, 5, -1], [0, -1, 0]]. The rows and columns must be odd
+//     in number and at most 25
+//   - kernel_divisor - divisor of the convolution, the sum of the kernel by
+//     default
+//   - kernel_bias - value added to the channels by the convolution as a
+//     fraction of the full range
+//   - edge - how the filters that combine neighbouring pixels treat the
+//     pixels outside the image: clamp, mirror, wrap, transparent or
+//     renormalize. The blure filter uses renormalize by default, the other
+//     filters use clamp
+//   - linear - whether the filters that combine neighbouring pixels work in
+//     linear light, false by default
+//   - preset - name of a preset from the store. It is applied before the
+//     other edits and can also be given in the query string
+//   - ops - JSON array of edit steps applied in order, e.g.
+//     [{"op": "crop", "width": 100}, {"op": "blur", "sigma": 3}]. If it is
+//     set, the edit fields from rotate to blure_sigma are ignored
+//   - metadata - metadata of the result: strip (default), keep, keep-icc or
+//     keep-copyright, which keeps only the color profile and the EXIF
+//     orientation, artist and copyright
+//   - quality - JPEG quality from 1 to 100. JPEG images are always baseline,
+//     progressive encoding is not supported
+//   - compression - PNG compression level: default, none, fast or best
+//   - max_bytes - maximum size of a JPEG image in bytes
+//   - format - output format, e.g. png or image/jpeg. If it is not set, the
+//     format is negotiated using the Accept header, falling back to the format
+//     of the uploaded image, or PNG if that format can only be decoded. The
+//     background replaces transparency in JPEG
+func NewImageHandler(store *presets.Store, workers int) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		editor, ok := readImage(response, request, workers)
+		if !ok {
+			return
+		}
+
+		background, err := imageEditor.ParseHexColor(
+			request.FormValue("background"))
+		if err != nil {
+			utils.LogAndWriteError(response,
+				"Incorrect background value",
+				http.StatusBadRequest)
+			return
+		}
+
+		output, ok := parseOutput(response, request, editor)
+		if !ok {
+			return
+		}
+
+		if name := request.FormValue("preset"); name != "" {
+			if !applyPreset(response, request, store, name, editor) {
+				return
+			}
+		}
+
+		if ops := request.FormValue("ops"); ops != "" {
+			if !applyOps(response, request, ops, editor) {
+				return
+			}
+		} else if !applyFormEdits(response, request, editor, background) {
+			return
+		}
+
+		writeImage(response, editor, output)
 	}
-	defer file.Close()
-	contentType := meta.Header.Get("Content-Type")
+}
 
-	editor, err := imageEditor.NewImageEditor(file)
-	if err != nil {
-		http.Error(response, "Unsupported file format",
-			http.StatusUnsupportedMediaType)
-		log.Println("Failed to create ImageEditor: ", err)
-		return
+// applyPreset applies the pipeline of the preset to the editor. It writes the
+// error and returns false if the preset does not exist or cannot be applied,
+// and returns false if the request is cancelled.
+func applyPreset(response http.ResponseWriter, request *http.Request,
+	store *presets.Store, name string, editor *imageEditor.ImageEditor) bool {
+	pipeline, ok := store.Get(name)
+	if !ok {
+		utils.LogAndWriteError(response,
+			"Unknown preset",
+			http.StatusNotFound)
+		return false
+	}
+
+	// The presets are validated when they are loaded, so they usually fail
+	// only when the request is cancelled.
+	if err := pipeline.ApplyContext(request.Context(), editor); err != nil {
+		if isCancelled(request) {
+			return false
+		}
+		utils.LogAndWriteError(response,
+			"Could not apply the preset: "+err.Error(),
+			http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// applyOps applies the pipeline from the JSON value of the ops field to the
+// editor. It writes the error and returns false if the pipeline is incorrect or
+// the request is cancelled.
+func applyOps(response http.ResponseWriter, request *http.Request, ops string,
+	editor *imageEditor.ImageEditor) bool {
+	pipeline, ok := parseOps(response, ops)
+	if !ok {
+		return false
+	}
+
+	if err := pipeline.ApplyContext(request.Context(), editor); err != nil {
+		if isCancelled(request) {
+			return false
+		}
+		utils.LogAndWriteError(response,
+			"Incorrect ops value: "+err.Error(),
+			http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// parseOps decodes the pipeline from the JSON value of the ops field and checks
+// its limits. It writes the error and returns false if the pipeline exceeds
+// them or cannot be decoded. The steps themselves are validated when the
+// pipeline is applied.
+func parseOps(response http.ResponseWriter,
+	ops string) (imageEditor.Pipeline, bool) {
+	var pipeline imageEditor.Pipeline
+	if err := json.Unmarshal([]byte(ops), &pipeline); err != nil {
+		utils.LogAndWriteError(response,
+			"The ops must be a JSON array of steps",
+			http.StatusBadRequest)
+		return nil, false
+	}
+
+	if len(pipeline) > maxPipelineSteps {
+		utils.LogAndWriteError(response,
+			"The ops must not contain more than "+
+				strconv.Itoa(maxPipelineSteps)+" steps",
+			http.StatusBadRequest)
+		return nil, false
+	}
+
+	for _, step := range pipeline {
+		switch step.Op {
+		case imageEditor.OP_RESIZE:
+			width, _ := step.Params.Int("width", 0)
+			height, _ := step.Params.Int("height", 0)
+			if width > maxResizeSize || height > maxResizeSize {
+				utils.LogAndWriteError(response,
+					"The resize width and height must not be greater than "+
+						strconv.Itoa(maxResizeSize),
+					http.StatusBadRequest)
+				return nil, false
+			}
+		case imageEditor.OP_CONVOLVE:
+			kernel, _ := step.Params.Matrix("kernel")
+			if !checkKernelSize(response, kernel) {
+				return nil, false
+			}
+		case imageEditor.OP_BOX_BLUR:
+			radius, _ := step.Params.Int("radius", 1)
+			if !checkBlurRadius(response, radius) {
+				return nil, false
+			}
+		}
+	}
+	return pipeline, true
+}
+
+// applyFormEdits applies the edits from the separate form fields to the
+// editor. It writes the error and returns false if a field is incorrect or the
+// request is cancelled.
+func applyFormEdits(response http.ResponseWriter, request *http.Request,
+	editor *imageEditor.ImageEditor, background color.Color) bool {
+	if rotate := request.FormValue("rotate"); rotate != "" {
+		degrees, err := strconv.ParseFloat(rotate, 64)
+		if err != nil {
+			utils.LogAndWriteError(response,
+				"The rotation angle must be a number",
+				http.StatusBadRequest)
+			return false
+		}
+
+		expand := true
+		if value := request.FormValue("expand"); value != "" {
+			expand, err = strconv.ParseBool(value)
+			if err != nil {
+				utils.LogAndWriteError(response,
+					"Incorrect expand value",
+					http.StatusBadRequest)
+				return false
+			}
+		}
+		editor.Rotate(degrees, background, expand)
+	}
+
+	switch flip := request.FormValue("flip"); flip {
+	case "":
+	case imageEditor.HORIZONTAL, imageEditor.VERTICAL:
+		editor.Flip(flip)
+	default:
+		utils.LogAndWriteError(response,
+			"Incorrect flip value",
+			http.StatusBadRequest)
+		return false
 	}
 
 	width, err := utils.ParsePositiveInt(request.FormValue("width"))
@@ -47,7 +322,7 @@ func ImageHandler(response http.ResponseWriter, request *http.Request) {
 		utils.LogAndWriteError(response,
 			"The width must be a positive integer",
 			http.StatusBadRequest)
-		return
+		return false
 	}
 
 	height, err := utils.ParsePositiveInt(request.FormValue("height"))
@@ -55,7 +330,7 @@ func ImageHandler(response http.ResponseWriter, request *http.Request) {
 		utils.LogAndWriteError(response,
 			"The height must be a positive integer",
 			http.StatusBadRequest)
-		return
+		return false
 	}
 
 	size := geom.NewSize(width, height)
@@ -66,14 +341,14 @@ func ImageHandler(response http.ResponseWriter, request *http.Request) {
 			utils.LogAndWriteError(response,
 				"The width should not be greater than the width of the image",
 				http.StatusBadRequest)
-			return
+			return false
 		}
 
 		if size.Height() > imageSize.Height() {
 			utils.LogAndWriteError(response,
 				"The height should not be greater than the height of the image",
 				http.StatusBadRequest)
-			return
+			return false
 		}
 
 		vertical := request.FormValue("vertical")
@@ -81,42 +356,652 @@ func ImageHandler(response http.ResponseWriter, request *http.Request) {
 			utils.LogAndWriteError(response,
 				"Incorrect vertical value",
 				http.StatusBadRequest)
-			return
+			return false
 		}
 		horizontal := request.FormValue("horizontal")
 		if !geom.ValidateHorizontal(horizontal) {
 			utils.LogAndWriteError(response,
 				"Incorrect horizontal value",
 				http.StatusBadRequest)
-			return
+			return false
 		}
 
 		alignment := geom.NewAlignment(vertical, horizontal)
 		editor.CropBySizeAndAlignment(size, alignment)
 	}
 
+	resizeWidth, err := utils.ParsePositiveInt(request.FormValue("resize_width"))
+	if err != nil || resizeWidth > maxResizeSize {
+		utils.LogAndWriteError(response,
+			"The resize width must be a positive integer not greater than "+
+				strconv.Itoa(maxResizeSize),
+			http.StatusBadRequest)
+		return false
+	}
+
+	resizeHeight, err := utils.ParsePositiveInt(
+		request.FormValue("resize_height"))
+	if err != nil || resizeHeight > maxResizeSize {
+		utils.LogAndWriteError(response,
+			"The resize height must be a positive integer not greater than "+
+				strconv.Itoa(maxResizeSize),
+			http.StatusBadRequest)
+		return false
+	}
+
+	kernel, ok := resample.KernelByName(request.FormValue("resample"))
+	if !ok {
+		utils.LogAndWriteError(response,
+			"Incorrect resample value",
+			http.StatusBadRequest)
+		return false
+	}
+
+	resizeSize := geom.NewSize(resizeWidth, resizeHeight)
+	fit := request.FormValue("fit")
+	if fit == "" {
+		editor.Resize(resizeSize, kernel)
+	} else {
+		if !geom.ValidateFit(fit) {
+			utils.LogAndWriteError(response,
+				"Incorrect fit value",
+				http.StatusBadRequest)
+			return false
+		}
+
+		if resizeSize.IsEmpty() {
+			utils.LogAndWriteError(response,
+				"The resize width and height are required to fit the image",
+				http.StatusBadRequest)
+			return false
+		}
+
+		alignment, ok := parseFitAlignment(response, request)
+		if !ok {
+			return false
+		}
+		editor.ResizeToBox(resizeSize, fit, alignment, kernel, background)
+	}
+
+	if value := request.FormValue("auto_levels"); value != "" {
+		autoLevels, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.LogAndWriteError(response,
+				"Incorrect auto_levels value",
+				http.StatusBadRequest)
+			return false
+		}
+		if autoLevels {
+			if err := editor.ApplyOperationContext(request.Context(),
+				mods.NewAutoLevels(autoLevelsClip)); err != nil {
+				if isCancelled(request) {
+					return false
+				}
+				utils.LogAndWriteError(response,
+					"Could not apply auto levels: "+err.Error(),
+					http.StatusInternalServerError)
+				return false
+			}
+		}
+	}
+
+	for _, adjustment := range adjustments {
+		value := request.FormValue(adjustment.name)
+		if value == "" {
+			continue
+		}
+
+		amount, err := utils.ParseFloatInRange(value, adjustment.min,
+			adjustment.max)
+		if err != nil {
+			utils.LogAndWriteError(response,
+				fmt.Sprintf("The %s must be a number from %v to %v",
+					adjustment.name, adjustment.min, adjustment.max),
+				http.StatusBadRequest)
+			return false
+		}
+		if !modifyPixels(response, request, editor,
+			adjustment.modifier(amount)) {
+			return false
+		}
+	}
+
+	if !applyHSLAdjust(response, request, editor) {
+		return false
+	}
+
+	options, ok := parseFilterOptions(response, request)
+	if !ok {
+		return false
+	}
+
+	var modifier mods.PixelModifier
 	filter := request.FormValue("filter")
 	switch filter {
 	case "grayscale":
-		editor.ModifyPixels(mods.NewGrayscale())
+		grayscale, err := mods.NewGrayscaleWithMode(
+			request.FormValue("grayscale_mode"))
+		if err != nil {
+			utils.LogAndWriteError(response,
+				"Incorrect grayscale_mode value",
+				http.StatusBadRequest)
+			return false
+		}
+		modifier = grayscale
 	case "negative":
-		editor.ModifyPixels(mods.NewNegative())
+		modifier = mods.NewNegative()
+	case "sepia":
+		strength, ok := parseFloatField(response, request, "sepia_strength", 1,
+			0, 1)
+		if !ok {
+			return false
+		}
+		modifier = mods.NewSepia(strength)
+	case "tint":
+		if request.FormValue("tint_color") == "" {
+			utils.LogAndWriteError(response,
+				"The tint_color is required",
+				http.StatusBadRequest)
+			return false
+		}
+		tint, ok := parseColorField(response, request, "tint_color", "")
+		if !ok {
+			return false
+		}
+		amount, ok := parseFloatField(response, request, "tint_amount", 0.5,
+			0, 1)
+		if !ok {
+			return false
+		}
+		modifier = mods.NewTint(tint, amount)
+	case "duotone":
+		shadows, ok := parseColorField(response, request, "duotone_shadows",
+			"#000000")
+		if !ok {
+			return false
+		}
+		highlights, ok := parseColorField(response, request,
+			"duotone_highlights", "#ffffff")
+		if !ok {
+			return false
+		}
+		modifier = mods.NewDuotone(shadows, highlights)
 	case "blure":
-		sigma, _ := strconv.ParseFloat(request.FormValue("blure_sigma"), 64)
-		if sigma <= 0 {
-			sigma = 2
+		sigma, ok := parseSigma(response, request)
+		if !ok {
+			return false
+		}
+		modifier = mods.NewGaussianBlurWithOptions(sigma, options)
+	case "sharpen":
+		modifier = mods.NewSharpen(options)
+	case "emboss":
+		modifier = mods.NewEmboss(options)
+	case "edge_detect":
+		modifier = mods.NewEdgeDetect(options)
+	case "box_blur":
+		radius, ok := parseBlurRadius(response, request)
+		if !ok {
+			return false
 		}
-		editor.ModifyPixels(mods.NewGaussianBlur(sigma))
+		modifier = mods.NewBoxBlur(radius, options)
+	case "unsharp_mask":
+		sigma, ok := parseSigma(response, request)
+		if !ok {
+			return false
+		}
+		amount, ok := parseFloatField(response, request, "unsharp_amount", 1,
+			0, 10)
+		if !ok {
+			return false
+		}
+		modifier = mods.NewUnsharpMask(sigma, amount, 0, options)
+	case "convolution":
+		convolution, ok := parseConvolution(response, request, options)
+		if !ok {
+			return false
+		}
+		modifier = convolution
 	default:
 		if filter != "" {
 			utils.LogAndWriteError(response,
 				"Invalid filter value",
 				http.StatusBadRequest)
-			return
+			return false
 		}
 	}
+	return modifier == nil || modifyPixels(response, request, editor, modifier)
+}
+
+// applyHSLAdjust changes the hue, the saturation and the lightness of the image
+// if one of the fields is set. It writes the error and returns false if a field
+// is incorrect or the request is cancelled.
+func applyHSLAdjust(response http.ResponseWriter, request *http.Request,
+	editor *imageEditor.ImageEditor) bool {
+	if request.FormValue("hue") == "" &&
+		request.FormValue("saturation") == "" &&
+		request.FormValue("lightness") == "" {
+		return true
+	}
+
+	hue, ok := parseFloatField(response, request, "hue", 0, -180, 180)
+	if !ok {
+		return false
+	}
+	saturation, ok := parseFloatField(response, request, "saturation", 1,
+		0, 4)
+	if !ok {
+		return false
+	}
+	lightness, ok := parseFloatField(response, request, "lightness", 1, 0, 4)
+	if !ok {
+		return false
+	}
+	hues, ok := mods.HueRangeByName(request.FormValue("hue_target"))
+	if !ok {
+		utils.LogAndWriteError(response,
+			"Incorrect hue_target value",
+			http.StatusBadRequest)
+		return false
+	}
+	return modifyPixels(response, request, editor,
+		mods.NewHSLAdjust(hue, saturation, lightness, hues))
+}
+
+// parseFitAlignment returns the alignment of the image fitted into the resize
+// box. The empty vertical and horizontal values are centered. It writes the
+// error and returns false if a value is incorrect.
+func parseFitAlignment(response http.ResponseWriter,
+	request *http.Request) (geom.Alignment, bool) {
+	vertical := request.FormValue("vertical")
+	if vertical == "" {
+		vertical = geom.DEFAULT_VERTICAL
+	}
+	if !geom.ValidateVertical(vertical) {
+		utils.LogAndWriteError(response,
+			"Incorrect vertical value",
+			http.StatusBadRequest)
+		return geom.Alignment{}, false
+	}
+
+	horizontal := request.FormValue("horizontal")
+	if horizontal == "" {
+		horizontal = geom.DEFAULT_HORIZONTL
+	}
+	if !geom.ValidateHorizontal(horizontal) {
+		utils.LogAndWriteError(response,
+			"Incorrect horizontal value",
+			http.StatusBadRequest)
+		return geom.Alignment{}, false
+	}
+	return geom.NewAlignment(vertical, horizontal), true
+}
+
+// parseSigma returns the sigma of the blure_sigma field, or 2 if the field is
+// not set. It writes the error and returns false if the sigma is not a positive
+// number up to mods.MAX_SIGMA.
+func parseSigma(response http.ResponseWriter,
+	request *http.Request) (float64, bool) {
+	sigma, ok := parseFloatField(response, request, "blure_sigma", 2, 0,
+		mods.MAX_SIGMA)
+	if ok && sigma == 0 {
+		utils.LogAndWriteError(response,
+			fmt.Sprintf("The blure_sigma must be a positive number up to %v",
+				mods.MAX_SIGMA),
+			http.StatusBadRequest)
+		return 0, false
+	}
+	return sigma, ok
+}
+
+// parseFloatField returns the number of the form field, or def if the field is
+// not set. It writes the error and returns false if the number is not in the
+// range from min to max.
+func parseFloatField(response http.ResponseWriter, request *http.Request,
+	name string, def, min, max float64) (float64, bool) {
+	value := request.FormValue(name)
+	if value == "" {
+		return def, true
+	}
+
+	number, err := utils.ParseFloatInRange(value, min, max)
+	if err != nil {
+		utils.LogAndWriteError(response,
+			fmt.Sprintf("The %s must be a number from %v to %v", name, min,
+				max),
+			http.StatusBadRequest)
+		return 0, false
+	}
+	return number, true
+}
+
+// parseColorField returns the color of the form field, or the def color if
+// the field is not set. It writes the error and returns false if the color is
+// incorrect.
+func parseColorField(response http.ResponseWriter, request *http.Request,
+	name string, def string) (color.NRGBA, bool) {
+	value := request.FormValue(name)
+	if value == "" {
+		value = def
+	}
+
+	col, err := imageEditor.ParseHexColor(value)
+	if err != nil {
+		utils.LogAndWriteError(response,
+			"Incorrect "+name+" value",
+			http.StatusBadRequest)
+		return color.NRGBA{}, false
+	}
+	return col, true
+}
+
+// modifyPixels applies the modifier to the editor until the request is
+// cancelled. It returns false if the request is cancelled, and also writes the
+// error if the pixels cannot be modified for another reason.
+func modifyPixels(response http.ResponseWriter, request *http.Request,
+	editor *imageEditor.ImageEditor, modifier mods.PixelModifier) bool {
+	if err := editor.ModifyPixelsContext(request.Context(),
+		modifier); err != nil {
+		if isCancelled(request) {
+			return false
+		}
+		utils.LogAndWriteError(response,
+			"Could not modify the pixels: "+err.Error(),
+			http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// isCancelled reports whether the client has cancelled the request, which
+// stops the editing. Nothing is written to the response of a cancelled request,
+// because the client no longer reads it, but the cancellation is logged.
+func isCancelled(request *http.Request) bool {
+	if err := request.Context().Err(); err != nil {
+		log.Println("Request cancelled:", err)
+		return true
+	}
+	return false
+}
+
+// parseFilterOptions returns the options of the neighbourhood filters from the
+// edge and linear fields. It writes the error and returns false if a field is
+// incorrect.
+func parseFilterOptions(response http.ResponseWriter,
+	request *http.Request) (mods.FilterOptions, bool) {
+	options := mods.FilterOptions{Edge: request.FormValue("edge")}
+	if options.Edge != "" && !mods.ValidateEdge(options.Edge) {
+		utils.LogAndWriteError(response,
+			"Incorrect edge value",
+			http.StatusBadRequest)
+		return options, false
+	}
+
+	if value := request.FormValue("linear"); value != "" {
+		var err error
+		options.Linear, err = strconv.ParseBool(value)
+		if err != nil {
+			utils.LogAndWriteError(response,
+				"Incorrect linear value",
+				http.StatusBadRequest)
+			return options, false
+		}
+	}
+	return options, true
+}
+
+// parseConvolution creates the convolution from the kernel, kernel_divisor and
+// kernel_bias fields and the filter options. It writes the error and returns
+// false if a field is incorrect.
+func parseConvolution(response http.ResponseWriter, request *http.Request,
+	filterOptions mods.FilterOptions) (*mods.Convolution, bool) {
+	var kernel [][]float64
+	if err := json.Unmarshal([]byte(request.FormValue("kernel")),
+		&kernel); err != nil {
+		utils.LogAndWriteError(response,
+			"The kernel must be a JSON array of rows of numbers",
+			http.StatusBadRequest)
+		return nil, false
+	}
+	if !checkKernelSize(response, kernel) {
+		return nil, false
+	}
+
+	options := mods.ConvolutionOptions{
+		Edge:   filterOptions.Edge,
+		Linear: filterOptions.Linear,
+	}
+	fields := []struct {
+		name  string
+		value *float64
+	}{
+		{"kernel_divisor", &options.Divisor},
+		{"kernel_bias", &options.Bias},
+	}
+	for _, field := range fields {
+		value := request.FormValue(field.name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			utils.LogAndWriteError(response,
+				"The "+field.name+" must be a number",
+				http.StatusBadRequest)
+			return nil, false
+		}
+		*field.value = number
+	}
+
+	convolution, err := mods.NewConvolution(kernel, options)
+	if err != nil {
+		utils.LogAndWriteError(response,
+			"Incorrect kernel value: "+err.Error(),
+			http.StatusBadRequest)
+		return nil, false
+	}
+	return convolution, true
+}
+
+// checkKernelSize checks that the kernel is not larger than maxKernelSize. It
+// writes the error and returns false if it is.
+func checkKernelSize(response http.ResponseWriter, kernel [][]float64) bool {
+	tooLarge := len(kernel) > maxKernelSize
+	for _, row := range kernel {
+		tooLarge = tooLarge || len(row) > maxKernelSize
+	}
+	if tooLarge {
+		utils.LogAndWriteError(response,
+			"The kernel must not have more than "+
+				strconv.Itoa(maxKernelSize)+" rows and columns",
+			http.StatusBadRequest)
+	}
+	return !tooLarge
+}
+
+// parseBlurRadius returns the radius of the blur_radius field, or 1 if the
+// field is not set. It writes the error and returns false if the radius is not
+// a positive integer or is greater than maxBlurRadius.
+func parseBlurRadius(response http.ResponseWriter,
+	request *http.Request) (int, bool) {
+	value := request.FormValue("blur_radius")
+	if value == "" {
+		return 1, true
+	}
+
+	radius, err := strconv.Atoi(value)
+	if err != nil || radius <= 0 {
+		utils.LogAndWriteError(response,
+			"The blur radius must be a positive integer",
+			http.StatusBadRequest)
+		return 0, false
+	}
+	return radius, checkBlurRadius(response, radius)
+}
+
+// checkBlurRadius checks that the radius of the box blur is not greater than
+// maxBlurRadius. It writes a 400 response otherwise.
+func checkBlurRadius(response http.ResponseWriter, radius int) bool {
+	if radius > maxBlurRadius {
+		utils.LogAndWriteError(response,
+			"The blur radius must not be greater than "+
+				strconv.Itoa(maxBlurRadius),
+			http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// readImage decodes the image from the image field of the request and sets the
+// number of workers of the editor. It writes the error and returns false if the
+// image cannot be decoded.
+func readImage(response http.ResponseWriter, request *http.Request,
+	workers int) (*imageEditor.ImageEditor, bool) {
+	file, _, err := request.FormFile("image")
+	if err != nil {
+		http.Error(response, "Could not read the file", http.StatusBadRequest)
+		log.Println("Failed to parse 'image' parameter: ", err)
+		return nil, false
+	}
+	defer file.Close()
+
+	autoOrient := true
+	if value := request.FormValue("auto_orient"); value != "" {
+		autoOrient, err = strconv.ParseBool(value)
+		if err != nil {
+			utils.LogAndWriteError(response,
+				"Incorrect auto_orient value",
+				http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	editor, err := imageEditor.NewImageEditorWithOptions(file,
+		&imageEditor.DecodeOptions{IgnoreOrientation: !autoOrient})
+	if errors.Is(err, imageEditor.ErrTooLarge) {
+		utils.LogAndWriteError(response, "The image is too large",
+			http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(response, "Unsupported file format",
+			http.StatusUnsupportedMediaType)
+		log.Println("Failed to create ImageEditor: ", err)
+		return nil, false
+	}
+	editor.SetWorkers(workers)
+	return editor, true
+}
+
+// imageOutput describes how the edited image is encoded.
+type imageOutput struct {
+	contentType string
+	// negotiated is true if the content type depends on the Accept header.
+	negotiated bool
+	options    *imageEditor.EncodeOptions
+}
+
+// parseOutput parses the encoding fields of the request. It is called before
+// the image is edited, so that incorrect fields do not waste the editing. It
+// writes the error and returns false if a field is incorrect.
+func parseOutput(response http.ResponseWriter, request *http.Request,
+	editor *imageEditor.ImageEditor) (imageOutput, bool) {
+	metadata := request.FormValue("metadata")
+	if metadata != "" && !imageEditor.ValidateMetadata(metadata) {
+		utils.LogAndWriteError(response,
+			"Incorrect metadata value",
+			http.StatusBadRequest)
+		return imageOutput{}, false
+	}
+
+	quality, err := utils.ParsePositiveInt(request.FormValue("quality"))
+	if err != nil || quality > 100 {
+		utils.LogAndWriteError(response,
+			"The quality must be an integer from 1 to 100",
+			http.StatusBadRequest)
+		return imageOutput{}, false
+	}
+
+	compression, ok := imageEditor.CompressionLevel(
+		request.FormValue("compression"))
+	if !ok {
+		utils.LogAndWriteError(response,
+			"Incorrect compression value",
+			http.StatusBadRequest)
+		return imageOutput{}, false
+	}
+
+	maxBytes, err := utils.ParsePositiveInt(request.FormValue("max_bytes"))
+	if err != nil {
+		utils.LogAndWriteError(response,
+			"The max_bytes must be a positive integer",
+			http.StatusBadRequest)
+		return imageOutput{}, false
+	}
+
+	contentType := editor.Format()
+	if !imageEditor.IsEncodableImageFormat(contentType) {
+		// Images in read-only formats are returned as PNG by default.
+		contentType = imageEditor.MIMEPNG
+	}
+	negotiated := false
+	if format := request.FormValue("format"); format != "" {
+		contentType, ok = imageEditor.MIMETypeByFormat(format)
+		if !ok {
+			utils.LogAndWriteError(response,
+				"Unsupported output format",
+				http.StatusBadRequest)
+			return imageOutput{}, false
+		}
+	} else {
+		negotiated = true
+		if accept := request.Header.Get("Accept"); accept != "" {
+			offers := append([]string{contentType},
+				imageEditor.EncodableMIMETypes()...)
+			if match := utils.NegotiateContentType(accept,
+				offers); match != "" {
+				contentType = match
+			}
+		}
+	}
+
+	// The background replaces transparency only if it has been set.
+	var flattenBackground color.Color
+	if value := request.FormValue("background"); value != "" {
+		background, err := imageEditor.ParseHexColor(value)
+		if err != nil {
+			utils.LogAndWriteError(response,
+				"Incorrect background value",
+				http.StatusBadRequest)
+			return imageOutput{}, false
+		}
+		flattenBackground = background
+	}
+
+	return imageOutput{
+		contentType: contentType,
+		negotiated:  negotiated,
+		options: &imageEditor.EncodeOptions{
+			Metadata:    metadata,
+			Quality:     quality,
+			Compression: compression,
+			MaxBytes:    maxBytes,
+			Background:  flattenBackground,
+		},
+	}, true
+}
+
+// writeImage encodes the edited image as described by the output and writes it
+// to the response.
+func writeImage(response http.ResponseWriter,
+	editor *imageEditor.ImageEditor, output imageOutput) {
+	if output.negotiated {
+		// Without the format field the response depends on the Accept header,
+		// even when it is not sent, so caches must take it into account.
+		response.Header().Add("Vary", "Accept")
+	}
 
-	buff, err := editor.BytesBuffer(contentType)
+	buff, err := editor.BytesBufferWithOptions(output.contentType,
+		output.options)
 	if err != nil {
 		http.Error(response, "File decoding error",
 			http.StatusInternalServerError)
@@ -124,7 +1009,7 @@ func ImageHandler(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	response.Header().Set("Content-Type", contentType)
+	response.Header().Set("Content-Type", output.contentType)
 	response.Header().Set("Content-Length", strconv.Itoa(buff.Len()))
 	response.Write(buff.Bytes())
 }
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NooFreeNames/ImageEditor/internal/server/presets"
+	"github.com/NooFreeNames/ImageEditor/internal/server/sessions"
+	"github.com/NooFreeNames/ImageEditor/internal/server/utils"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor"
+)
+
+// maxOpsBodySize is the maximum size of the JSON body with the ops.
+const maxOpsBodySize = 1 << 20
+
+// sessionState is the JSON representation of a session.
+type sessionState struct {
+	ID        string    `json:"id"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	Format    string    `json:"format"`
+	CanUndo   bool      `json:"can_undo"`
+	CanRedo   bool      `json:"can_redo"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewSessionsHandler creates the handler function for the "/sessions" URLs,
+// which edit an image over several requests. The sessions are kept in the
+// store, the presets are applied from the presets store and the pixels are
+// edited with the given number of workers, or with one worker per CPU if it is
+// 0.
+//
+// Routes:
+//   - POST /sessions - creates a session from the image field, which is read
+//     like the one of the "/image" URL, and returns its state
+//   - GET /sessions/{id} - returns the state of the session
+//   - DELETE /sessions/{id} - removes the session
+//   - POST /sessions/{id}/ops - applies the JSON array of edit steps from the
+//     request body, or the ops and preset fields of a form, as a single change
+//   - GET /sessions/{id}/image - returns the edited image, encoded according to
+//     the same fields as the "/image" URL
+//   - POST /sessions/{id}/undo - reverts the last change
+//   - POST /sessions/{id}/redo - repeats the last undone change
+//
+// The state is a JSON object with the id, width, height, format, can_undo,
+// can_redo and expires_at fields.
+func NewSessionsHandler(store *sessions.Store, presetStore *presets.Store,
+	workers int) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(request.URL.Path, "/sessions"),
+			"/")
+		if path == "" {
+			if !allowMethod(response, request, http.MethodPost) {
+				return
+			}
+			createSession(response, request, store, workers)
+			return
+		}
+
+		id, action, _ := strings.Cut(path, "/")
+		session, ok := store.Get(id)
+		if !ok {
+			utils.LogAndWriteError(response,
+				"Unknown or expired session",
+				http.StatusNotFound)
+			return
+		}
+
+		switch action {
+		case "":
+			if !allowMethod(response, request, http.MethodGet,
+				http.MethodDelete) {
+				return
+			}
+			if request.Method == http.MethodDelete {
+				store.Delete(id)
+				response.WriteHeader(http.StatusNoContent)
+				return
+			}
+			session.Lock()
+			defer session.Unlock()
+			writeSessionState(response, store, session)
+		case "ops":
+			if !allowMethod(response, request, http.MethodPost) {
+				return
+			}
+			session.Lock()
+			defer session.Unlock()
+			applySessionOps(response, request, store, presetStore, session)
+		case "image":
+			if !allowMethod(response, request, http.MethodGet) {
+				return
+			}
+			session.Lock()
+			defer session.Unlock()
+			output, ok := parseOutput(response, request, session.Editor)
+			if !ok {
+				return
+			}
+			writeImage(response, session.Editor, output)
+		case "undo", "redo":
+			if !allowMethod(response, request, http.MethodPost) {
+				return
+			}
+			session.Lock()
+			defer session.Unlock()
+			changeSessionHistory(response, action, store, session)
+		default:
+			http.NotFound(response, request)
+		}
+	}
+}
+
+// allowMethod checks the method of the request. It writes the error and
+// returns false if the method is not allowed.
+func allowMethod(response http.ResponseWriter, request *http.Request,
+	methods ...string) bool {
+	for _, method := range methods {
+		if request.Method == method {
+			return true
+		}
+	}
+
+	response.Header().Set("Allow", strings.Join(methods, ", "))
+	utils.LogAndWriteError(response,
+		"Method not allowed",
+		http.StatusMethodNotAllowed)
+	return false
+}
+
+// createSession creates a session from the uploaded image.
+func createSession(response http.ResponseWriter, request *http.Request,
+	store *sessions.Store, workers int) {
+	editor, ok := readImage(response, request, workers)
+	if !ok {
+		return
+	}
+
+	session, err := store.Create(editor)
+	if err != nil {
+		writeSessionError(response, err)
+		return
+	}
+
+	response.Header().Set("Location", "/sessions/"+session.ID)
+	response.WriteHeader(http.StatusCreated)
+	writeSessionState(response, store, session)
+}
+
+// applySessionOps applies the preset and the ops to the session as a single
+// change, which is discarded if the request is cancelled. The session must be
+// locked.
+func applySessionOps(response http.ResponseWriter, request *http.Request,
+	store *sessions.Store, presetStore *presets.Store,
+	session *sessions.Session) {
+	var pipeline imageEditor.Pipeline
+
+	contentType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if contentType == "application/json" {
+		body, err := io.ReadAll(io.LimitReader(request.Body, maxOpsBodySize))
+		if err != nil {
+			utils.LogAndWriteError(response,
+				"Could not read the ops",
+				http.StatusBadRequest)
+			return
+		}
+		bodyPipeline, ok := parseOps(response, string(body))
+		if !ok {
+			return
+		}
+		pipeline = bodyPipeline
+	} else {
+		preset, ops := request.FormValue("preset"), request.FormValue("ops")
+		if preset == "" && ops == "" {
+			utils.LogAndWriteError(response,
+				"The ops or preset is required",
+				http.StatusBadRequest)
+			return
+		}
+
+		if preset != "" {
+			presetPipeline, ok := presetStore.Get(preset)
+			if !ok {
+				utils.LogAndWriteError(response,
+					"Unknown preset",
+					http.StatusNotFound)
+				return
+			}
+			pipeline = append(pipeline, presetPipeline...)
+		}
+		if ops != "" {
+			opsPipeline, ok := parseOps(response, ops)
+			if !ok {
+				return
+			}
+			pipeline = append(pipeline, opsPipeline...)
+		}
+	}
+
+	checkpoint := session.Editor.Checkpoint()
+	if err := pipeline.ApplyContext(request.Context(),
+		session.Editor); err != nil {
+		if isCancelled(request) {
+			return
+		}
+		utils.LogAndWriteError(response,
+			"Incorrect ops value: "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	if err := store.Update(session); err != nil {
+		// Only a change can exceed the memory limit, and the session cannot
+		// keep it, not even to redo it.
+		session.Editor.Rollback(checkpoint)
+		writeSessionError(response, err)
+		return
+	}
+	writeSessionState(response, store, session)
+}
+
+// changeSessionHistory undoes or redoes the last change of the session. The
+// session must be locked.
+func changeSessionHistory(response http.ResponseWriter, action string,
+	store *sessions.Store, session *sessions.Session) {
+	checkpoint := session.Editor.Checkpoint()
+	var ok bool
+	if action == "undo" {
+		ok = session.Editor.Undo()
+	} else {
+		ok = session.Editor.Redo()
+	}
+	if !ok {
+		utils.LogAndWriteError(response,
+			"Nothing to "+action,
+			http.StatusConflict)
+		return
+	}
+
+	if err := store.Update(session); err != nil {
+		// The restored image can be larger than the current one.
+		session.Editor.Rollback(checkpoint)
+		writeSessionError(response, err)
+		return
+	}
+	writeSessionState(response, store, session)
+}
+
+// writeSessionState writes the state of the session as JSON. The session must
+// be locked.
+func writeSessionState(response http.ResponseWriter, store *sessions.Store,
+	session *sessions.Session) {
+	size := session.Editor.Size()
+	state := sessionState{
+		ID:        session.ID,
+		Width:     size.Width(),
+		Height:    size.Height(),
+		Format:    session.Editor.Format(),
+		CanUndo:   session.Editor.CanUndo(),
+		CanRedo:   session.Editor.CanRedo(),
+		ExpiresAt: time.Now().Add(store.TTL()).UTC(),
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(response).Encode(state); err != nil {
+		log.Println("Error writing response: ", err)
+	}
+}
+
+// writeSessionError writes the error of the session store.
+func writeSessionError(response http.ResponseWriter, err error) {
+	if errors.Is(err, sessions.ErrTooLarge) {
+		utils.LogAndWriteError(response,
+			"The image exceeds the memory limit of the sessions",
+			http.StatusInsufficientStorage)
+		return
+	}
+
+	http.Error(response, "Could not save the session",
+		http.StatusInternalServerError)
+	log.Println("Failed to save the session: ", err)
+}
@@ -0,0 +1,105 @@
+// Package presets provides the named edit recipes of the server.
+package presets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor"
+)
+
+// namePattern is the pattern of valid preset names, e.g. "avatar-small".
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// extensions are the extensions of the preset files.
+var extensions = map[string]bool{".json": true, ".yaml": true, ".yml": true}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{make(map[string]imageEditor.Pipeline)}
+}
+
+// Load creates a Store from the preset files in the directory. Every .json,
+// .yaml or .yml file is a list of pipeline steps, and the name of the file
+// without the extension is the name of the preset. An empty directory path
+// gives an empty Store. Returns an error if a file cannot be read or contains
+// an invalid pipeline.
+func Load(dir string) (*Store, error) {
+	store := NewStore()
+	if dir == "" {
+		return store, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !extensions[ext] {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		pipeline, err := imageEditor.ParsePipeline(data)
+		if err != nil {
+			return nil, fmt.Errorf("preset %s: %w", path, err)
+		}
+
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if err := store.Add(name, pipeline); err != nil {
+			return nil, fmt.Errorf("preset %s: %w", path, err)
+		}
+	}
+	return store, nil
+}
+
+// Store stores the edit pipelines by name. The presets are added before the
+// server starts, so reading a Store concurrently is safe while adding is not.
+type Store struct {
+	pipelines map[string]imageEditor.Pipeline
+}
+
+// Add validates the pipeline and saves it under the name. Returns an error if
+// the name is invalid or taken, or the pipeline is invalid.
+func (store *Store) Add(name string, pipeline imageEditor.Pipeline) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid preset name %q", name)
+	}
+	if _, ok := store.pipelines[name]; ok {
+		return fmt.Errorf("duplicate preset name %q", name)
+	}
+	if err := pipeline.Validate(); err != nil {
+		return err
+	}
+
+	store.pipelines[name] = pipeline
+	return nil
+}
+
+// Get returns the pipeline of the preset. The second value reports whether the
+// preset exists.
+func (store *Store) Get(name string) (imageEditor.Pipeline, bool) {
+	pipeline, ok := store.pipelines[name]
+	return pipeline, ok
+}
+
+// Names returns the sorted names of all presets.
+func (store *Store) Names() []string {
+	names := make([]string, 0, len(store.pipelines))
+	for name := range store.pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
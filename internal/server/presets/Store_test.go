@@ -0,0 +1,135 @@
+package presets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePresets creates a temporary directory with the files and returns its
+// path.
+func writePresets(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writePresets(t, map[string]string{
+		"thumbnail.json": `[{"op": "resize", "width": 40, "height": 30}]`,
+		"avatar.yaml":    "- op: grayscale\n- op: negative\n",
+		"sepia_1.yml":    "- op: sepia\n",
+		"notes.txt":      "not a preset",
+	})
+	if err := os.Mkdir(filepath.Join(dir, "nested.json"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Load(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"avatar", "sepia_1", "thumbnail"}, store.Names())
+
+	pipeline, ok := store.Get("avatar")
+	assert.True(t, ok)
+	assert.Equal(t, imageEditor.Pipeline{
+		imageEditor.NewStep(imageEditor.OP_GRAYSCALE, imageEditor.Params{}),
+		imageEditor.NewStep(imageEditor.OP_NEGATIVE, imageEditor.Params{}),
+	}, pipeline)
+	_, ok = store.Get("notes")
+	assert.False(t, ok)
+}
+
+func TestLoad_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{
+			name: "duplicate names",
+			files: map[string]string{
+				"avatar.json": `[{"op": "negative"}]`,
+				"avatar.yaml": "- op: negative\n",
+			},
+		},
+		{
+			name:  "invalid name",
+			files: map[string]string{"Avatar.json": `[{"op": "negative"}]`},
+		},
+		{
+			name:  "unknown operation",
+			files: map[string]string{"avatar.json": `[{"op": "explode"}]`},
+		},
+		{
+			name: "incorrect parameter",
+			files: map[string]string{
+				"avatar.yaml": "- op: blur\n  sigma: -1\n",
+			},
+		},
+		{
+			name:  "not a list",
+			files: map[string]string{"avatar.json": `{"op": "negative"}`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := Load(writePresets(t, test.files))
+			assert.Error(t, err)
+			assert.Nil(t, store)
+		})
+	}
+
+	_, err := Load(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err, "a missing directory must be reported")
+}
+
+func TestLoad_emptyDir(t *testing.T) {
+	store, err := Load("")
+	assert.NoError(t, err)
+	assert.Empty(t, store.Names())
+}
+
+func TestStore_Add(t *testing.T) {
+	pipeline := imageEditor.Pipeline{
+		imageEditor.NewStep(imageEditor.OP_NEGATIVE, nil),
+	}
+	tests := []struct {
+		name     string
+		pipeline imageEditor.Pipeline
+		wantErr  bool
+	}{
+		{"avatar-small", pipeline, false},
+		{"v2_thumbnail", pipeline, false},
+		{"", pipeline, true},
+		{"-avatar", pipeline, true},
+		{"Avatar", pipeline, true},
+		{"avatar.small", pipeline, true},
+		{"../avatar", pipeline, true},
+		{"avatar-small", pipeline, true},
+		{"sepia", imageEditor.Pipeline{
+			imageEditor.NewStep(imageEditor.OP_SEPIA, imageEditor.Params{
+				"strength": 2,
+			}),
+		}, true},
+	}
+
+	store := NewStore()
+	for _, test := range tests {
+		err := store.Add(test.name, test.pipeline)
+		if test.wantErr {
+			assert.Error(t, err, "name: %q", test.name)
+		} else {
+			assert.NoError(t, err, "name: %q", test.name)
+		}
+	}
+	assert.Equal(t, []string{"avatar-small", "v2_thumbnail"}, store.Names())
+}
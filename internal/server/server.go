@@ -4,10 +4,13 @@ package server
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/NooFreeNames/ImageEditor/configs"
 	hndls "github.com/NooFreeNames/ImageEditor/internal/server/handlers"
 	mw "github.com/NooFreeNames/ImageEditor/internal/server/middleware"
+	"github.com/NooFreeNames/ImageEditor/internal/server/presets"
+	"github.com/NooFreeNames/ImageEditor/internal/server/sessions"
 )
 
 // Function Run starts the server and listens for incoming HTTP requests.
@@ -15,12 +18,28 @@ func Run(conf configs.ConfigI) {
 	fs := http.FileServer(http.Dir(conf.GetSiteDir()))
 	http.Handle("/", mw.LogRequest(fs))
 	http.Handle("/ping", mw.LogRequest(http.HandlerFunc(hndls.PingHandler)))
-	http.Handle("/image", mw.LogRequest(http.HandlerFunc(hndls.ImageHandler)))
+
+	store, err := presets.Load(conf.GetPresetsDir())
+	if err != nil {
+		log.Fatalln("Failed to load presets:", err)
+	}
+	log.Println("Loaded presets:", store.Names())
+	http.Handle("/image", mw.LogRequest(hndls.NewImageHandler(store,
+		conf.GetWorkers())))
+
+	sessionStore := sessions.NewStore(conf.GetSessionTTL(),
+		conf.GetSessionsMemory())
+	stopCleanup := sessionStore.StartCleanup(time.Minute)
+	defer stopCleanup()
+	sessionsHandler := mw.LogRequest(
+		hndls.NewSessionsHandler(sessionStore, store, conf.GetWorkers()))
+	http.Handle("/sessions", sessionsHandler)
+	http.Handle("/sessions/", sessionsHandler)
 
 	addr := conf.GetHost() + ":" + conf.GetPort()
 
 	log.Println("Server is listening at http://" + addr + "/")
-	err := http.ListenAndServe(addr, nil)
+	err = http.ListenAndServe(addr, nil)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -0,0 +1,245 @@
+// Package sessions provides the in-process storage of the editing sessions.
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor"
+)
+
+// ErrTooLarge is returned when a session does not fit into the memory limit of
+// the store even after all other sessions are removed.
+var ErrTooLarge = errors.New("the session exceeds the memory limit")
+
+// Session is an image that is edited by several requests. The editor must
+// only be used while the session is locked.
+type Session struct {
+	sync.Mutex
+	// ID is the unique identifier of the session.
+	ID string
+	// Editor is the edited image.
+	Editor *imageEditor.ImageEditor
+	// lastUsed is the time of the last access to the session.
+	lastUsed time.Time
+	// size is the memory size of the editor at the last update.
+	size int
+}
+
+// NewStore creates a new Store. Sessions expire after ttl without access, and
+// all sessions together use at most maxBytes of memory.
+func NewStore(ttl time.Duration, maxBytes int) *Store {
+	return &Store{
+		sessions: make(map[string]*Session),
+		ttl:      ttl,
+		maxBytes: maxBytes,
+	}
+}
+
+// Store stores the editing sessions by ID. When the memory limit is exceeded,
+// the least recently used sessions are removed. It is safe for concurrent use.
+type Store struct {
+	mutex    sync.Mutex
+	sessions map[string]*Session
+	ttl      time.Duration
+	maxBytes int
+	size     int
+}
+
+// TTL returns the time after which an unused session expires.
+func (store *Store) TTL() time.Duration {
+	return store.ttl
+}
+
+// Create creates a session for the editor, enables the history of the editor
+// and returns the session. Returns an error if the editor alone exceeds the
+// memory limit.
+func (store *Store) Create(editor *imageEditor.ImageEditor) (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	editor.EnableHistory(store.historyBudget())
+	session := &Session{
+		ID:       id,
+		Editor:   editor,
+		lastUsed: time.Now(),
+		size:     editor.MemorySize(),
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.removeExpired()
+	if !store.reserve(session.size, nil) {
+		return nil, ErrTooLarge
+	}
+
+	store.sessions[id] = session
+	store.size += session.size
+	return session, nil
+}
+
+// Get returns the session with the ID and extends its lifetime. The second
+// value reports whether the session exists and has not expired.
+func (store *Store) Get(id string) (*Session, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session, ok := store.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	if store.isExpired(session) {
+		store.remove(session)
+		return nil, false
+	}
+
+	session.lastUsed = time.Now()
+	return session, true
+}
+
+// Update recalculates the memory size of the session after its editor has
+// changed. The least recently used other sessions are removed if the memory
+// limit is exceeded. Returns ErrTooLarge if the session alone exceeds the
+// limit; the session keeps its previous size in this case. The session must be
+// locked by the caller.
+func (store *Store) Update(session *Session) error {
+	size := session.Editor.MemorySize()
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if _, ok := store.sessions[session.ID]; !ok {
+		// The session has been removed in the meantime.
+		return nil
+	}
+
+	if !store.reserve(size-session.size, session) {
+		return ErrTooLarge
+	}
+	store.size += size - session.size
+	session.size = size
+	return nil
+}
+
+// Delete removes the session with the ID. It returns false if the session does
+// not exist.
+func (store *Store) Delete(id string) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	session, ok := store.sessions[id]
+	if ok {
+		store.remove(session)
+	}
+	return ok
+}
+
+// Len returns the number of sessions.
+func (store *Store) Len() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return len(store.sessions)
+}
+
+// RemoveExpired removes all expired sessions. It is called periodically by
+// StartCleanup, but expired sessions are never returned even without it.
+func (store *Store) RemoveExpired() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.removeExpired()
+}
+
+// StartCleanup removes the expired sessions every interval until the returned
+// function is called.
+func (store *Store) StartCleanup(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				store.RemoveExpired()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// historyBudget returns the memory budget of the history of a session. A
+// quarter of the memory limit is used, so that a few sessions fit into it.
+func (store *Store) historyBudget() int {
+	if store.maxBytes <= 0 {
+		return imageEditor.DEFAULT_HISTORY_BUDGET
+	}
+	return store.maxBytes / 4
+}
+
+// reserve removes the least recently used sessions except the kept one until
+// the additional bytes fit into the memory limit. It returns false without
+// removing any session if they do not fit even without the other sessions.
+func (store *Store) reserve(bytes int, kept *Session) bool {
+	if store.maxBytes <= 0 {
+		return true
+	}
+
+	keptSize := 0
+	if kept != nil {
+		keptSize = kept.size
+	}
+	if bytes > store.maxBytes-keptSize {
+		return false
+	}
+
+	for store.size+bytes > store.maxBytes {
+		var oldest *Session
+		for _, session := range store.sessions {
+			if session != kept &&
+				(oldest == nil || session.lastUsed.Before(oldest.lastUsed)) {
+				oldest = session
+			}
+		}
+		if oldest == nil {
+			return false
+		}
+		store.remove(oldest)
+	}
+	return true
+}
+
+// removeExpired removes all expired sessions.
+func (store *Store) removeExpired() {
+	for _, session := range store.sessions {
+		if store.isExpired(session) {
+			store.remove(session)
+		}
+	}
+}
+
+// isExpired reports whether the session has not been used for the TTL.
+func (store *Store) isExpired(session *Session) bool {
+	return store.ttl > 0 && time.Since(session.lastUsed) > store.ttl
+}
+
+// remove removes the session from the store.
+func (store *Store) remove(session *Session) {
+	delete(store.sessions, session.ID)
+	store.size -= session.size
+}
+
+// newID returns a new random session ID.
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
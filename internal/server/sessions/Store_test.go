@@ -0,0 +1,209 @@
+package sessions
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/geom"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/mods"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/resample"
+	"github.com/stretchr/testify/assert"
+)
+
+// editorSize is the memory size of the editors created by newEditor.
+const editorSize = 2 * 4 * 10 * 10
+
+// newEditor creates an editor of an opaque 10x10 PNG image.
+func newEditor(t *testing.T) *imageEditor.ImageEditor {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer,
+		image.NewRGBA(image.Rect(0, 0, 10, 10))); err != nil {
+		t.Fatal(err)
+	}
+	editor, err := imageEditor.NewImageEditor(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return editor
+}
+
+// mustCreate creates a session in the store.
+func mustCreate(t *testing.T, store *Store) *Session {
+	session, err := store.Create(newEditor(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func TestStore_Create(t *testing.T) {
+	store := NewStore(time.Hour, 0)
+	session := mustCreate(t, store)
+
+	assert.Len(t, session.ID, 32)
+	assert.Equal(t, editorSize, session.size)
+	assert.Equal(t, editorSize, store.size)
+	assert.Equal(t, 1, store.Len())
+
+	got, ok := store.Get(session.ID)
+	assert.True(t, ok)
+	assert.Same(t, session, got)
+	_, ok = store.Get("unknown")
+	assert.False(t, ok)
+}
+
+func TestStore_Get_expired(t *testing.T) {
+	store := NewStore(time.Minute, 0)
+	expired := mustCreate(t, store)
+	active := mustCreate(t, store)
+	expired.lastUsed = time.Now().Add(-2 * time.Minute)
+
+	_, ok := store.Get(expired.ID)
+	assert.False(t, ok, "an expired session must not be returned")
+	assert.Equal(t, 1, store.Len(), "an expired session must be removed")
+	assert.Equal(t, editorSize, store.size)
+
+	active.lastUsed = time.Now().Add(-2 * time.Minute)
+	store.RemoveExpired()
+	assert.Equal(t, 0, store.Len())
+	assert.Equal(t, 0, store.size)
+
+	store = NewStore(0, 0)
+	session := mustCreate(t, store)
+	session.lastUsed = time.Now().Add(-time.Hour)
+	_, ok = store.Get(session.ID)
+	assert.True(t, ok, "sessions never expire without a TTL")
+}
+
+func TestStore_reserve(t *testing.T) {
+	store := NewStore(time.Hour, 3*editorSize)
+	first := mustCreate(t, store)
+	second := mustCreate(t, store)
+	third := mustCreate(t, store)
+	now := time.Now()
+	first.lastUsed = now.Add(-time.Second)
+	second.lastUsed = now.Add(-3 * time.Second)
+	third.lastUsed = now.Add(-2 * time.Second)
+
+	fourth := mustCreate(t, store)
+	_, ok := store.Get(second.ID)
+	assert.False(t, ok, "the least recently used session must be removed")
+	for _, session := range []*Session{first, third, fourth} {
+		_, ok := store.Get(session.ID)
+		assert.True(t, ok)
+	}
+	assert.Equal(t, 3*editorSize, store.size)
+
+	first.lastUsed = now.Add(-time.Minute)
+	assert.True(t, store.reserve(2*editorSize, first))
+	_, ok = store.Get(first.ID)
+	assert.True(t, ok, "the kept session must not be removed")
+	assert.Equal(t, 1, store.Len())
+	assert.False(t, store.reserve(3*editorSize, first))
+}
+
+func TestStore_Update(t *testing.T) {
+	store := NewStore(time.Hour, 0)
+	session := mustCreate(t, store)
+	other := mustCreate(t, store)
+
+	session.Editor.ModifyPixels(mods.NewNegative())
+	assert.NoError(t, store.Update(session))
+	size := session.Editor.MemorySize()
+	assert.Greater(t, size, editorSize, "the history must be counted")
+	assert.Equal(t, size, session.size)
+	assert.Equal(t, size+other.size, store.size)
+
+	assert.True(t, session.Editor.Undo())
+	assert.NoError(t, store.Update(session))
+	assert.Equal(t, session.Editor.MemorySize(), session.size)
+	assert.Equal(t, session.size+other.size, store.size)
+
+	assert.True(t, store.Delete(session.ID))
+	assert.NoError(t, store.Update(session),
+		"a removed session must be ignored")
+	assert.Equal(t, other.size, store.size)
+}
+
+func TestStore_ErrTooLarge(t *testing.T) {
+	store := NewStore(time.Hour, editorSize-1)
+	_, err := store.Create(newEditor(t))
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, 0, store.Len())
+
+	store = NewStore(time.Hour, 2*editorSize)
+	session := mustCreate(t, store)
+	other := mustCreate(t, store)
+	session.Editor.Resize(geom.NewSize(20, 20), resample.NearestNeighbor)
+
+	assert.ErrorIs(t, store.Update(session), ErrTooLarge)
+	assert.Equal(t, editorSize, session.size,
+		"the session must keep its previous size")
+	assert.Equal(t, 2*editorSize, store.size)
+	_, ok := store.Get(other.ID)
+	assert.True(t, ok, "the other sessions must not be removed in vain")
+}
+
+func TestStore_ErrTooLarge_keepsOthers(t *testing.T) {
+	store := NewStore(time.Hour, 3*editorSize)
+	first := mustCreate(t, store)
+	second := mustCreate(t, store)
+
+	large := newEditor(t)
+	large.Resize(geom.NewSize(40, 40), resample.NearestNeighbor)
+	_, err := store.Create(large)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	first.Editor.Resize(geom.NewSize(40, 40), resample.NearestNeighbor)
+	assert.ErrorIs(t, store.Update(first), ErrTooLarge)
+
+	for _, session := range []*Session{first, second} {
+		_, ok := store.Get(session.ID)
+		assert.True(t, ok, "the other sessions must survive")
+	}
+	assert.Equal(t, 2, store.Len())
+	assert.Equal(t, 2*editorSize, store.size)
+}
+
+func TestStore_concurrent(t *testing.T) {
+	store := NewStore(time.Hour, 8*editorSize)
+	ids := make([]string, 16)
+	for i := range ids {
+		ids[i] = mustCreate(t, store).ID
+	}
+
+	var wait sync.WaitGroup
+	for i, id := range ids {
+		wait.Add(2)
+		go func(id string) {
+			defer wait.Done()
+			if session, ok := store.Get(id); ok {
+				session.Lock()
+				session.Editor.ModifyPixels(mods.NewNegative())
+				store.Update(session)
+				session.Unlock()
+			}
+		}(id)
+		go func(id string, remove bool) {
+			defer wait.Done()
+			if remove {
+				store.Delete(id)
+			} else {
+				store.Get(id)
+			}
+		}(id, i%2 == 0)
+	}
+	wait.Wait()
+
+	size := 0
+	for _, session := range store.sessions {
+		size += session.size
+	}
+	assert.Equal(t, size, store.size)
+	assert.LessOrEqual(t, store.size, 8*editorSize)
+}
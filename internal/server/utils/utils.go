@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 // LogAndWriteError logs an error message and writes it as an HTTP response with
@@ -33,3 +34,76 @@ func ParsePositiveInt(str string) (int, error) {
 	}
 	return num, err
 }
+
+// ParseFloatInRange converts a string to a floating point number in the range
+// [min, max]. Returns an error if it is not possible to convert a string to a
+// number or the number is outside the range.
+func ParseFloatInRange(str string, min, max float64) (float64, error) {
+	num, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", str)
+	}
+
+	if !(num >= min && num <= max) {
+		return 0, fmt.Errorf("%q is not in the range from %v to %v", str, min, max)
+	}
+	return num, nil
+}
+
+// NegotiateContentType returns the offered content type that is most preferred
+// by the Accept header value. Offers that are listed earlier win ties. It
+// returns an empty string if no offer is acceptable.
+func NegotiateContentType(accept string, offers []string) string {
+	best, bestQuality, bestSpecificity := "", 0.0, -1
+
+	for _, offer := range offers {
+		quality, specificity := 0.0, -1
+		for _, mediaRange := range strings.Split(accept, ",") {
+			rangeQuality, rangeSpecificity, ok := matchMediaRange(
+				strings.TrimSpace(mediaRange), offer)
+			// The most specific matching range sets the quality.
+			if ok && rangeSpecificity > specificity {
+				quality, specificity = rangeQuality, rangeSpecificity
+			}
+		}
+
+		if quality > bestQuality ||
+			quality == bestQuality && quality > 0 && specificity > bestSpecificity {
+			best, bestQuality, bestSpecificity = offer, quality, specificity
+		}
+	}
+
+	return best
+}
+
+// matchMediaRange checks whether the media range of the Accept header, such as
+// "image/*;q=0.8", matches the content type. It returns the quality of the
+// range and its specificity: 0 for "*/*", 1 for "type/*" and 2 for an exact
+// type.
+func matchMediaRange(mediaRange, contentType string) (float64, int, bool) {
+	parts := strings.Split(mediaRange, ";")
+	rangeType := strings.ToLower(strings.TrimSpace(parts[0]))
+
+	quality := 1.0
+	for _, parameter := range parts[1:] {
+		name, value, found := strings.Cut(strings.TrimSpace(parameter), "=")
+		if found && strings.TrimSpace(name) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err == nil && parsed >= 0 && parsed <= 1 {
+				quality = parsed
+			}
+		}
+	}
+
+	switch {
+	case rangeType == "*/*":
+		return quality, 0, true
+	case strings.HasSuffix(rangeType, "/*") &&
+		strings.HasPrefix(contentType, strings.TrimSuffix(rangeType, "*")):
+		return quality, 1, true
+	case rangeType == contentType:
+		return quality, 2, true
+	default:
+		return 0, 0, false
+	}
+}
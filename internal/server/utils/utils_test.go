@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"image/png", "image/jpeg", "image/gif"}
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{"exact type", "image/jpeg", "image/jpeg"},
+		{"earlier offer wins ties", "image/gif, image/jpeg", "image/jpeg"},
+		{"higher quality", "image/jpeg;q=0.5, image/gif;q=0.8", "image/gif"},
+		{"any type", "*/*", "image/png"},
+		{"type wildcard", "text/*, image/*;q=0.9", "image/png"},
+		{"specific range overrides wildcard",
+			"image/*, image/png;q=0.2", "image/jpeg"},
+		{"q=0 excludes the offer", "image/png;q=0, */*;q=0.1", "image/jpeg"},
+		{"q=0 excludes every offer", "image/*;q=0", ""},
+		{"no acceptable offer", "text/html", ""},
+		{"spaces and case", " IMAGE/GIF ; q=0.7 , image/jpeg ;q=0.6",
+			"image/gif"},
+		{"incorrect quality is ignored", "image/gif;q=2, image/png;q=0.9",
+			"image/gif"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NegotiateContentType(test.accept, offers)
+			assert.Equal(t, test.want, got,
+				"NegotiateContentType(%q) = %q, want %q",
+				test.accept, got, test.want)
+		})
+	}
+}
+
+func Test_matchMediaRange(t *testing.T) {
+	tests := []struct {
+		mediaRange      string
+		contentType     string
+		wantQuality     float64
+		wantSpecificity int
+		wantOk          bool
+	}{
+		{"image/png", "image/png", 1, 2, true},
+		{"image/png;q=0.4", "image/png", 0.4, 2, true},
+		{"image/png; q=0", "image/png", 0, 2, true},
+		{"image/*;q=0.8", "image/jpeg", 0.8, 1, true},
+		{"*/*;q=0.1", "image/gif", 0.1, 0, true},
+		{"image/png;level=1;q=0.5", "image/png", 0.5, 2, true},
+		{"image/png;q=abc", "image/png", 1, 2, true},
+		{"image/png;q=-1", "image/png", 1, 2, true},
+		{"image/jpeg", "image/png", 0, 0, false},
+		{"text/*", "image/png", 0, 0, false},
+		{"image*", "image/png", 0, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.mediaRange, func(t *testing.T) {
+			quality, specificity, ok := matchMediaRange(test.mediaRange,
+				test.contentType)
+			assert.Equal(t, test.wantQuality, quality)
+			assert.Equal(t, test.wantSpecificity, specificity)
+			assert.Equal(t, test.wantOk, ok)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package imageEditor
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+)
+
+// MAX_ANIMATION_PIXELS is the maximum number of pixels in all frames of an
+// animated image, which are decoded into full images.
+const MAX_ANIMATION_PIXELS = 100 << 20
+
+// ErrTooLarge is returned when the decoded image does not fit into the pixel
+// budget.
+var ErrTooLarge = errors.New("imageEditor: the image is too large")
+
+// animation stores the frames of an animated image.
+type animation struct {
+	// frames are the editors of all frames except the first one, which is
+	// edited by the ImageEditor itself.
+	frames []*ImageEditor
+	// delays are the delays of all frames in 100ths of a second.
+	delays []int
+	// disposals are the disposal methods of all frames.
+	disposals []byte
+	// loopCount is the number of times the animation is repeated.
+	loopCount int
+}
+
+// decodeAnimation decodes all frames of a GIF image. The frames are composed
+// into full images according to their disposal methods, so that every frame
+// can be edited independently. It returns the first frame and the animation,
+// which is nil for images with a single frame. Returns ErrTooLarge if the frames
+// exceed MAX_ANIMATION_PIXELS.
+func decodeAnimation(reader io.Reader) (image.Image, *animation, error) {
+	decoded, err := gif.DecodeAll(reader)
+	if err != nil {
+		return nil, nil, image.ErrFormat
+	}
+	if len(decoded.Image) == 0 {
+		return nil, nil, image.ErrFormat
+	}
+	if len(decoded.Image) == 1 {
+		return decoded.Image[0], nil, nil
+	}
+
+	// Every frame is composed into a full image, so small frames of a large
+	// canvas use much more memory than the encoded image.
+	pixels := decoded.Config.Width * decoded.Config.Height
+	if pixels > MAX_ANIMATION_PIXELS/len(decoded.Image) {
+		return nil, nil, ErrTooLarge
+	}
+
+	bounds := image.Rect(0, 0, decoded.Config.Width, decoded.Config.Height)
+	canvas := image.NewRGBA(bounds)
+	frames := make([]*image.RGBA, len(decoded.Image))
+
+	for i, frame := range decoded.Image {
+		disposal := byte(0)
+		if i < len(decoded.Disposal) {
+			disposal = decoded.Disposal[i]
+		}
+
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		frames[i] = cloneRGBA(canvas)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{},
+				draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	result := &animation{
+		frames:    make([]*ImageEditor, len(frames)-1),
+		delays:    make([]int, len(frames)),
+		disposals: make([]byte, len(frames)),
+		loopCount: decoded.LoopCount,
+	}
+	copy(result.delays, decoded.Delay)
+	copy(result.disposals, decoded.Disposal)
+	for i, frame := range frames[1:] {
+		// The destination only stores the bounds of the frame until the frame
+		// is modified, so that the unchanged frames use no extra memory.
+		result.frames[i] = &ImageEditor{
+			source:      frame,
+			destination: &image.RGBA{Rect: frame.Rect},
+			format:      MIMEGIF,
+		}
+	}
+
+	return frames[0], result, nil
+}
+
+// IsAnimated checks if the image has more than one frame.
+func (editor *ImageEditor) IsAnimated() bool {
+	return editor.animation != nil
+}
+
+// FrameCount returns the number of frames of the image.
+func (editor *ImageEditor) FrameCount() int {
+	if editor.animation == nil {
+		return 1
+	}
+	return len(editor.animation.frames) + 1
+}
+
+// eachFrame applies the operation to the other frames of an animated image.
+// It is called by the operations that change the image, so that every frame is
+// edited in the same way as the first one.
+func (editor *ImageEditor) eachFrame(operation func(frame *ImageEditor)) {
+	if editor.animation == nil {
+		return
+	}
+
+	for _, frame := range editor.animation.frames {
+		operation(frame)
+	}
+}
+
+// encodeAnimation encodes all frames of the animated image as GIF. The frames
+// keep their delays, disposal methods and the loop count.
+func (editor *ImageEditor) encodeAnimation(writer io.Writer) error {
+	result := &gif.GIF{
+		Image:     []*image.Paletted{quantize(editor.EditedImage())},
+		Delay:     editor.animation.delays,
+		Disposal:  editor.animation.disposals,
+		LoopCount: editor.animation.loopCount,
+	}
+	for _, frame := range editor.animation.frames {
+		result.Image = append(result.Image, quantize(frame.EditedImage()))
+	}
+
+	return gif.EncodeAll(writer, result)
+}
+
+// cloneRGBA returns a copy of the image.
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := image.NewRGBA(img.Rect)
+	copy(clone.Pix, img.Pix)
+	return clone
+}
@@ -0,0 +1,125 @@
+package imageEditor
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"os"
+	"testing"
+
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/mods"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAnimatedEditor(t *testing.T) (*ImageEditor, *gif.GIF) {
+	data, err := os.ReadFile("../../test/images/test_image.gif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor, err := NewImageEditor(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return editor, decoded
+}
+
+func TestImageEditor_IsAnimated(t *testing.T) {
+	editor, decoded := newAnimatedEditor(t)
+	assert.True(t, editor.IsAnimated())
+	assert.Equal(t, len(decoded.Image), editor.FrameCount())
+
+	bounds := image.Rect(0, 0, 2, 2)
+	editor = &ImageEditor{
+		source:      image.NewRGBA(bounds),
+		destination: image.NewRGBA(bounds),
+	}
+	assert.False(t, editor.IsAnimated())
+	assert.Equal(t, 1, editor.FrameCount())
+}
+
+func TestImageEditor_encodeGIF(t *testing.T) {
+	editor, decoded := newAnimatedEditor(t)
+	editor.CropByRectangle(image.Rect(10, 20, 60, 50))
+	editor.ModifyPixels(mods.NewNegative())
+
+	buffer, err := editor.BytesBuffer(MIMEGIF)
+	if !assert.NoError(t, err) {
+		return
+	}
+	encoded, err := gif.DecodeAll(buffer)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, encoded.Image, len(decoded.Image))
+	assert.Equal(t, decoded.Delay, encoded.Delay)
+	assert.Equal(t, decoded.Disposal, encoded.Disposal)
+	assert.Equal(t, decoded.LoopCount, encoded.LoopCount)
+	assert.Equal(t, 50, encoded.Config.Width)
+	assert.Equal(t, 30, encoded.Config.Height)
+	for i, frame := range encoded.Image {
+		assert.Equal(t, image.Rect(0, 0, 50, 30), frame.Bounds(),
+			"bounds of frame %d", i)
+	}
+}
+
+func TestImageEditor_ApplyOperation_frames(t *testing.T) {
+	editor, decoded := newAnimatedEditor(t)
+	calls := 0
+	err := editor.ApplyOperation(operationFunc(
+		func(src *image.RGBA) (*image.RGBA, error) {
+			calls++
+			return image.NewRGBA(image.Rect(0, 0, 3, 2)), nil
+		}))
+	assert.NoError(t, err)
+	assert.Equal(t, len(decoded.Image), calls,
+		"the operation must be applied to every frame")
+	editor.eachFrame(func(frame *ImageEditor) {
+		assert.Equal(t, image.Rect(0, 0, 3, 2), frame.EditedImage().Bounds())
+	})
+}
+
+func TestNewImageEditor_animationTooLarge(t *testing.T) {
+	frame := image.NewPaletted(image.Rect(0, 0, 1, 1), palette.Plan9)
+	var buffer bytes.Buffer
+	err := gif.EncodeAll(&buffer, &gif.GIF{
+		Image: []*image.Paletted{frame, frame},
+		Delay: []int{0, 0},
+		Config: image.Config{
+			ColorModel: color.Palette(palette.Plan9),
+			Width:      10000,
+			Height:     10000,
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = NewImageEditor(&buffer)
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestImageEditor_frameDestination(t *testing.T) {
+	editor, _ := newAnimatedEditor(t)
+	editor.eachFrame(func(frame *ImageEditor) {
+		assert.Nil(t, frame.destination.Pix,
+			"the pixels must not be allocated before the frame is modified")
+	})
+
+	bounds := image.Rect(10, 20, 60, 50)
+	editor.CropByRectangle(bounds)
+	editor.eachFrame(func(frame *ImageEditor) {
+		assert.Nil(t, frame.destination.Pix)
+		assert.Equal(t, bounds, frame.bounds())
+		want := image.NewRGBA(bounds)
+		draw.Draw(want, bounds, frame.source, bounds.Min, draw.Src)
+		assert.Equal(t, want, frame.EditedImage())
+	})
+}
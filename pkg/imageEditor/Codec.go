@@ -0,0 +1,174 @@
+package imageEditor
+
+import (
+	"bufio"
+	"image"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/meta"
+)
+
+// Decoder decodes an image from the reader.
+type Decoder func(reader io.Reader) (image.Image, error)
+
+// Encoder encodes the image to the writer using the options. The options are
+// never nil.
+type Encoder func(writer io.Writer, img image.Image, options *EncodeOptions) error
+
+// MetadataWriter inserts the metadata into the encoded image.
+type MetadataWriter func(data []byte, metadata *meta.Metadata) ([]byte, error)
+
+// Codec describes an image format that ImageEditor can read or write.
+type Codec struct {
+	// MIMEType is the mime type of the format, e.g. "image/png".
+	MIMEType string
+	// Names are the names of the format, e.g. "jpeg" and "jpg". They are used
+	// to select the format by name.
+	Names []string
+	// Magic are the prefixes that identify the encoded image. Each "?" byte
+	// matches any byte.
+	Magic []string
+	// Decode decodes the image. A nil Decode means that the format can only be
+	// written.
+	Decode Decoder
+	// Encode encodes the image. A nil Encode means that the format can only be
+	// read.
+	Encode Encoder
+	// WriteMetadata inserts the metadata into the encoded image. A nil
+	// WriteMetadata means that the format does not support metadata.
+	WriteMetadata MetadataWriter
+}
+
+// codecs is the registry of the image formats.
+var codecs = struct {
+	sync.RWMutex
+	byMIMEType map[string]Codec
+}{byMIMEType: make(map[string]Codec)}
+
+// RegisterCodec registers the image format. A codec with the same mime type
+// replaces the previously registered one.
+func RegisterCodec(codec Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.byMIMEType[codec.MIMEType] = codec
+}
+
+// CodecByMIMEType returns the codec registered for the mime type. The second
+// value reports whether the codec exists.
+func CodecByMIMEType(mimeType string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	codec, ok := codecs.byMIMEType[mimeType]
+	return codec, ok
+}
+
+// codecByName returns the codec with the given name, e.g. "png".
+func codecByName(name string) (Codec, bool) {
+	name = strings.ToLower(name)
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+	for _, codec := range codecs.byMIMEType {
+		for _, codecName := range codec.Names {
+			if codecName == name {
+				return codec, true
+			}
+		}
+	}
+	return Codec{}, false
+}
+
+// sniffCodec returns the decodable codec whose magic matches the beginning of
+// the reader.
+func sniffCodec(reader *bufio.Reader) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	for _, codec := range codecs.byMIMEType {
+		if codec.Decode == nil {
+			continue
+		}
+		for _, magic := range codec.Magic {
+			data, err := reader.Peek(len(magic))
+			if err == nil && matchMagic(magic, data) {
+				return codec, true
+			}
+		}
+	}
+	return Codec{}, false
+}
+
+// matchMagic reports whether the data matches the magic, where each "?" byte
+// matches any byte.
+func matchMagic(magic string, data []byte) bool {
+	if len(magic) != len(data) {
+		return false
+	}
+	for i, b := range data {
+		if magic[i] != b && magic[i] != '?' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSupportedImageFormat checks whether ImageEditor can decode images of this
+// format or not.
+func IsSupportedImageFormat(mimeType string) bool {
+	codec, ok := CodecByMIMEType(mimeType)
+	return ok && codec.Decode != nil
+}
+
+// IsEncodableImageFormat checks whether ImageEditor can encode images to this
+// format or not.
+func IsEncodableImageFormat(mimeType string) bool {
+	codec, ok := CodecByMIMEType(mimeType)
+	return ok && codec.Encode != nil
+}
+
+// SupportedMIMETypes returns the sorted mime types of all formats that can be
+// decoded.
+func SupportedMIMETypes() []string {
+	return mimeTypes(func(codec Codec) bool { return codec.Decode != nil })
+}
+
+// EncodableMIMETypes returns the sorted mime types of all formats that can be
+// encoded.
+func EncodableMIMETypes() []string {
+	return mimeTypes(func(codec Codec) bool { return codec.Encode != nil })
+}
+
+// mimeTypes returns the sorted mime types of the codecs that match the filter.
+func mimeTypes(filter func(codec Codec) bool) []string {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	result := make([]string, 0, len(codecs.byMIMEType))
+	for mimeType, codec := range codecs.byMIMEType {
+		if filter(codec) {
+			result = append(result, mimeType)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// MIMETypeByFormat returns the mime type of a format that can be encoded. The
+// format can be given as a name, such as "png", "jpeg" or "jpg", or as a mime
+// type. The second value reports whether the format is supported.
+func MIMETypeByFormat(format string) (string, bool) {
+	mimeType := format
+	if !strings.Contains(format, "/") {
+		codec, ok := codecByName(format)
+		if !ok {
+			return "", false
+		}
+		mimeType = codec.MIMEType
+	}
+
+	if !IsEncodableImageFormat(mimeType) {
+		return "", false
+	}
+	return mimeType, true
+}
@@ -0,0 +1,103 @@
+package imageEditor
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_matchMagic(t *testing.T) {
+	tests := []struct {
+		name  string
+		magic string
+		data  string
+		want  bool
+	}{
+		{"equal", "GIF89a", "GIF89a", true},
+		{"wildcard", "BM??\x00", "BMab\x00", true},
+		{"different byte", "GIF89a", "GIF87a", false},
+		{"different length", "GIF89a", "GIF", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := matchMagic(test.magic, []byte(test.data))
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	const mimeType = "image/x-test"
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	RegisterCodec(Codec{
+		MIMEType: mimeType,
+		Names:    []string{"test"},
+		Magic:    []string{"TEST"},
+		Decode: func(reader io.Reader) (image.Image, error) {
+			data, err := io.ReadAll(reader)
+			if err != nil || string(data) != "TEST" {
+				return nil, errors.New("invalid test image")
+			}
+			return img, nil
+		},
+		Encode: func(writer io.Writer, img image.Image,
+			options *EncodeOptions) error {
+			_, err := io.WriteString(writer, "TEST")
+			return err
+		},
+	})
+	defer func() {
+		codecs.Lock()
+		delete(codecs.byMIMEType, mimeType)
+		codecs.Unlock()
+	}()
+
+	assert.True(t, IsSupportedImageFormat(mimeType))
+	assert.Contains(t, SupportedMIMETypes(), mimeType)
+	got, ok := MIMETypeByFormat("test")
+	assert.True(t, ok)
+	assert.Equal(t, mimeType, got)
+
+	editor, err := NewImageEditor(bytes.NewReader([]byte("TEST")))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, mimeType, editor.Format())
+	assert.Equal(t, img.Bounds(), editor.EditedImage().Bounds())
+
+	buffer, err := editor.BytesBuffer(mimeType)
+	assert.NoError(t, err)
+	assert.Equal(t, "TEST", buffer.String())
+}
+
+func TestEncodableMIMETypes(t *testing.T) {
+	img := newNoiseImage(image.Rect(0, 0, 8, 6))
+	editor := &ImageEditor{
+		source:      img,
+		destination: image.NewRGBA(img.Rect),
+	}
+
+	for _, mimeType := range EncodableMIMETypes() {
+		t.Run(mimeType, func(t *testing.T) {
+			buffer, err := editor.BytesBuffer(mimeType)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			decoded, format, err := decode(buffer)
+			if assert.NoError(t, err) {
+				assert.Equal(t, mimeType, format)
+				assert.Equal(t, img.Bounds(), decoded.Bounds())
+			}
+		})
+	}
+
+	assert.NotContains(t, EncodableMIMETypes(), MIMEWEBP,
+		"WebP can only be decoded")
+	_, err := editor.BytesBuffer(MIMEWEBP)
+	assert.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package imageEditor
+
+import (
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/meta"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+)
+
+// init registers the built-in image formats.
+func init() {
+	RegisterCodec(Codec{
+		MIMEType:      MIMEPNG,
+		Names:         []string{"png"},
+		Magic:         []string{"\x89PNG\r\n\x1a\n"},
+		Decode:        png.Decode,
+		Encode:        encodePNG,
+		WriteMetadata: meta.WritePNG,
+	})
+	RegisterCodec(Codec{
+		MIMEType:      MIMEJPEG,
+		Names:         []string{"jpeg", "jpg"},
+		Magic:         []string{"\xff\xd8"},
+		Decode:        jpeg.Decode,
+		Encode:        encodeJPEG,
+		WriteMetadata: meta.WriteJPEG,
+	})
+	RegisterCodec(Codec{
+		MIMEType: MIMEGIF,
+		Names:    []string{"gif"},
+		Magic:    []string{"GIF87a", "GIF89a"},
+		Decode:   gif.Decode,
+		Encode:   encodeGIF,
+	})
+	RegisterCodec(Codec{
+		MIMEType: MIMEBMP,
+		Names:    []string{"bmp"},
+		Magic:    []string{"BM????\x00\x00\x00\x00"},
+		Decode:   bmp.Decode,
+		Encode:   encodeBMP,
+	})
+	RegisterCodec(Codec{
+		MIMEType: MIMETIFF,
+		Names:    []string{"tiff", "tif"},
+		Magic:    []string{"II\x2a\x00", "MM\x00\x2a"},
+		Decode:   tiff.Decode,
+		Encode:   encodeTIFF,
+	})
+	RegisterCodec(Codec{
+		MIMEType: MIMEWEBP,
+		Names:    []string{"webp"},
+		Magic:    []string{"RIFF????WEBPVP8"},
+		Decode:   webp.Decode,
+	})
+}
+
+// encodePNG encodes the image as PNG with the compression level of the
+// options.
+func encodePNG(writer io.Writer, img image.Image, options *EncodeOptions) error {
+	encoder := png.Encoder{CompressionLevel: options.Compression}
+	return encoder.Encode(writer, img)
+}
+
+// encodeJPEG encodes the image as JPEG with the quality of the options. JPEG
+// has no alpha channel, so transparent pixels are blended with the background.
+func encodeJPEG(writer io.Writer, img image.Image, options *EncodeOptions) error {
+	return jpeg.Encode(writer, flatten(img, options.Background),
+		&jpeg.Options{Quality: options.jpegQuality()})
+}
+
+// encodeGIF encodes the image as a single frame GIF.
+func encodeGIF(writer io.Writer, img image.Image, options *EncodeOptions) error {
+	return gif.Encode(writer, quantize(img), nil)
+}
+
+// encodeBMP encodes the image as BMP.
+func encodeBMP(writer io.Writer, img image.Image, options *EncodeOptions) error {
+	return bmp.Encode(writer, img)
+}
+
+// encodeTIFF encodes the image as TIFF. The image is compressed unless the
+// options disable the compression.
+func encodeTIFF(writer io.Writer, img image.Image, options *EncodeOptions) error {
+	compression := tiff.Deflate
+	if options.Compression == png.NoCompression {
+		compression = tiff.Uncompressed
+	}
+	return tiff.Encode(writer, img, &tiff.Options{Compression: compression})
+}
@@ -0,0 +1,233 @@
+package imageEditor
+
+import "image"
+
+// DEFAULT_HISTORY_BUDGET is the default memory budget of the history in bytes.
+const DEFAULT_HISTORY_BUDGET = 256 << 20
+
+// editorState is a snapshot of the edited image. The images are shared with
+// the editor, which is safe because the operations never change the pixels of
+// an image after it has been set as the destination.
+type editorState struct {
+	source           image.Image
+	destination      *image.RGBA
+	destination64    *image.RGBA64
+	isModifiedPixels bool
+	isCropped        bool
+	// frames are the states of the other frames of an animated image.
+	frames []editorState
+}
+
+// equal reports whether the states have the same images, including the images
+// of the frames.
+func (state *editorState) equal(other *editorState) bool {
+	if state.source != other.source ||
+		state.destination != other.destination ||
+		state.destination64 != other.destination64 ||
+		state.isModifiedPixels != other.isModifiedPixels ||
+		state.isCropped != other.isCropped ||
+		len(state.frames) != len(other.frames) {
+		return false
+	}
+	for i := range state.frames {
+		if !state.frames[i].equal(&other.frames[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// size returns the approximate number of bytes used by the state.
+func (state *editorState) size() int {
+	size := 0
+	if state.destination64 != nil {
+		size = len(state.destination64.Pix)
+	} else {
+		size = len(state.destination.Pix)
+	}
+	for i := range state.frames {
+		size += state.frames[i].size()
+	}
+	return size
+}
+
+// history stores the states of the editor before and after the changes.
+type history struct {
+	// undo are the states that Undo returns to, the last one first.
+	undo []editorState
+	// redo are the states that Redo returns to, the last one first.
+	redo []editorState
+	// budget is the maximum number of bytes used by the saved states.
+	budget int
+	// depth is the number of the changes in progress. Nested changes, like the
+	// steps of a pipeline, are recorded as a single change.
+	depth int
+}
+
+// size returns the number of bytes used by the saved states.
+func (history *history) size() int {
+	size := 0
+	for i := range history.undo {
+		size += history.undo[i].size()
+	}
+	for i := range history.redo {
+		size += history.redo[i].size()
+	}
+	return size
+}
+
+// trim removes the oldest states until the history fits into the budget.
+func (history *history) trim() {
+	size := history.size()
+	for size > history.budget && len(history.undo) > 0 {
+		size -= history.undo[0].size()
+		history.undo = history.undo[1:]
+	}
+	for size > history.budget && len(history.redo) > 0 {
+		size -= history.redo[0].size()
+		history.redo = history.redo[1:]
+	}
+}
+
+// EnableHistory makes the editor record its changes so that they can be undone
+// and redone. The saved states use at most budget bytes; when the budget is
+// exceeded, the oldest states are forgotten. A budget of zero or less disables
+// the history and forgets all saved states.
+func (editor *ImageEditor) EnableHistory(budget int) {
+	if budget <= 0 {
+		editor.history = nil
+		return
+	}
+
+	if editor.history == nil {
+		editor.history = new(history)
+	}
+	editor.history.budget = budget
+	editor.history.trim()
+}
+
+// CanUndo reports whether there is a change to undo.
+func (editor *ImageEditor) CanUndo() bool {
+	return editor.history != nil && len(editor.history.undo) > 0
+}
+
+// CanRedo reports whether there is an undone change to redo.
+func (editor *ImageEditor) CanRedo() bool {
+	return editor.history != nil && len(editor.history.redo) > 0
+}
+
+// Undo reverts the last change. It returns false if there is nothing to undo.
+func (editor *ImageEditor) Undo() bool {
+	if !editor.CanUndo() {
+		return false
+	}
+
+	history := editor.history
+	last := len(history.undo) - 1
+	history.redo = append(history.redo, editor.state())
+	editor.restore(history.undo[last])
+	history.undo = history.undo[:last]
+	return true
+}
+
+// Redo repeats the last undone change. It returns false if there is nothing to
+// redo.
+func (editor *ImageEditor) Redo() bool {
+	if !editor.CanRedo() {
+		return false
+	}
+
+	history := editor.history
+	last := len(history.redo) - 1
+	history.undo = append(history.undo, editor.state())
+	editor.restore(history.redo[last])
+	history.redo = history.redo[:last]
+	return true
+}
+
+// Checkpoint is a saved state of the image and of its history, see
+// ImageEditor.Rollback.
+type Checkpoint struct {
+	state      editorState
+	undo, redo []editorState
+}
+
+// Checkpoint saves the current state of the image and of its history.
+func (editor *ImageEditor) Checkpoint() Checkpoint {
+	checkpoint := Checkpoint{state: editor.state()}
+	if editor.history != nil {
+		checkpoint.undo = append([]editorState(nil), editor.history.undo...)
+		checkpoint.redo = append([]editorState(nil), editor.history.redo...)
+	}
+	return checkpoint
+}
+
+// Rollback returns the image and its history to the checkpoint. Unlike Undo,
+// the changes made after the checkpoint are forgotten and cannot be redone.
+func (editor *ImageEditor) Rollback(checkpoint Checkpoint) {
+	editor.restore(checkpoint.state)
+	if editor.history != nil {
+		editor.history.undo = checkpoint.undo
+		editor.history.redo = checkpoint.redo
+		editor.history.trim()
+	}
+}
+
+// track starts a change of the image and returns the function that finishes
+// it, so it is used as "defer editor.track()()". If the image has changed, its
+// previous state is saved in the history and the undone changes are forgotten.
+func (editor *ImageEditor) track() func() {
+	history := editor.history
+	if history == nil {
+		return func() {}
+	}
+
+	history.depth++
+	if history.depth > 1 {
+		return func() { history.depth-- }
+	}
+
+	before := editor.state()
+	return func() {
+		history.depth--
+		// Any difference of the states is a change, so that no change can keep
+		// the undone changes, which no longer follow the current state.
+		after := editor.state()
+		if after.equal(&before) {
+			return
+		}
+
+		history.undo = append(history.undo, before)
+		history.redo = nil
+		history.trim()
+	}
+}
+
+// state returns the current state of the editor.
+func (editor *ImageEditor) state() editorState {
+	state := editorState{
+		source:           editor.source,
+		destination:      editor.destination,
+		destination64:    editor.destination64,
+		isModifiedPixels: editor.isModifiedPixels,
+		isCropped:        editor.isCropped,
+	}
+	editor.eachFrame(func(frame *ImageEditor) {
+		state.frames = append(state.frames, frame.state())
+	})
+	return state
+}
+
+// restore sets the state of the editor.
+func (editor *ImageEditor) restore(state editorState) {
+	editor.source = state.source
+	editor.destination = state.destination
+	editor.destination64 = state.destination64
+	editor.isModifiedPixels = state.isModifiedPixels
+	editor.isCropped = state.isCropped
+	if editor.animation != nil {
+		for i, frame := range editor.animation.frames {
+			frame.restore(state.frames[i])
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package imageEditor
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"testing"
+
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/geom"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/mods"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/resample"
+	"github.com/stretchr/testify/assert"
+)
+
+// newHistoryEditor creates an editor of a numbered image with the history
+// enabled.
+func newHistoryEditor(budget int) *ImageEditor {
+	bounds := image.Rect(0, 0, 4, 3)
+	editor := &ImageEditor{
+		source:      newNumberedImage(bounds),
+		destination: image.NewRGBA(bounds),
+	}
+	editor.EnableHistory(budget)
+	return editor
+}
+
+// pixels returns the pixels of the edited image.
+func pixels(editor *ImageEditor) []color.RGBA {
+	img := editor.EditedImage()
+	bounds := img.Bounds()
+	result := make([]color.RGBA, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			result = append(result, color.RGBAModel.Convert(
+				img.At(x, y)).(color.RGBA))
+		}
+	}
+	return result
+}
+
+func TestImageEditor_Undo(t *testing.T) {
+	editor := newHistoryEditor(DEFAULT_HISTORY_BUDGET)
+	original := pixels(editor)
+	assert.False(t, editor.CanUndo())
+	assert.False(t, editor.Undo(), "there is nothing to undo")
+
+	editor.ModifyPixels(mods.NewNegative())
+	negative := pixels(editor)
+	editor.CropByRectangle(image.Rect(1, 1, 3, 3))
+	cropped := pixels(editor)
+	editor.Rotate90()
+	rotated := pixels(editor)
+
+	assert.True(t, editor.Undo())
+	assert.Equal(t, cropped, pixels(editor), "rotation must be undone")
+	assert.True(t, editor.Undo())
+	assert.Equal(t, negative, pixels(editor), "crop must be undone")
+	assert.True(t, editor.Undo())
+	assert.Equal(t, original, pixels(editor), "negative must be undone")
+	assert.False(t, editor.Undo())
+
+	assert.True(t, editor.Redo())
+	assert.Equal(t, negative, pixels(editor), "negative must be redone")
+	assert.True(t, editor.Redo())
+	assert.True(t, editor.Redo())
+	assert.Equal(t, rotated, pixels(editor), "rotation must be redone")
+	assert.False(t, editor.Redo(), "there is nothing to redo")
+}
+
+func TestImageEditor_Redo(t *testing.T) {
+	editor := newHistoryEditor(DEFAULT_HISTORY_BUDGET)
+	editor.ModifyPixels(mods.NewNegative())
+	editor.Undo()
+	assert.True(t, editor.CanRedo())
+
+	editor.FlipHorizontal()
+	assert.False(t, editor.CanRedo(), "a new change must forget undone ones")
+	assert.True(t, editor.Undo())
+	assert.False(t, editor.CanUndo())
+}
+
+func TestImageEditor_Redo_afterNewChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(editor *ImageEditor)
+	}{
+		{"modify pixels", func(editor *ImageEditor) {
+			editor.ModifyPixels(mods.NewGrayscale())
+		}},
+		{"crop", func(editor *ImageEditor) {
+			editor.CropByRectangle(image.Rect(0, 0, 2, 2))
+		}},
+		{"resize", func(editor *ImageEditor) {
+			editor.Resize(geom.NewSize(2, 2), resample.Bilinear)
+		}},
+		{"rotate", func(editor *ImageEditor) {
+			editor.Rotate(30, color.Black, true)
+		}},
+		{"pipeline", func(editor *ImageEditor) {
+			Pipeline{NewStep(OP_FLIP, Params{"direction": VERTICAL})}.
+				Apply(editor)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			editor := newHistoryEditor(DEFAULT_HISTORY_BUDGET)
+			editor.ModifyPixels(mods.NewNegative())
+			assert.True(t, editor.Undo())
+
+			test.change(editor)
+			changed := pixels(editor)
+			assert.False(t, editor.Redo(),
+				"the undone change must be forgotten after a new one")
+			assert.Equal(t, changed, pixels(editor))
+		})
+	}
+}
+
+func TestImageEditor_Rollback(t *testing.T) {
+	editor := newHistoryEditor(DEFAULT_HISTORY_BUDGET)
+	editor.ModifyPixels(mods.NewNegative())
+	negative := pixels(editor)
+
+	checkpoint := editor.Checkpoint()
+	editor.Rotate90()
+	editor.Rollback(checkpoint)
+	assert.Equal(t, negative, pixels(editor))
+	assert.True(t, editor.CanUndo())
+	assert.False(t, editor.CanRedo(), "the change must not be redone")
+
+	checkpoint = editor.Checkpoint()
+	editor.Undo()
+	editor.Rollback(checkpoint)
+	assert.Equal(t, negative, pixels(editor))
+	assert.True(t, editor.CanUndo())
+	assert.False(t, editor.CanRedo(), "the undo must be forgotten")
+
+	editor.Undo()
+	assert.NotEqual(t, negative, pixels(editor))
+	assert.False(t, editor.CanUndo())
+}
+
+func TestImageEditor_track(t *testing.T) {
+	t.Run("nested changes are recorded once", func(t *testing.T) {
+		editor := newHistoryEditor(DEFAULT_HISTORY_BUDGET)
+		editor.ResizeToBox(geom.NewSize(2, 2), geom.FIT, geom.DefaultAlignment,
+			resample.Lanczos3, color.Black)
+		pipeline := Pipeline{
+			NewStep(OP_GRAYSCALE, nil),
+			NewStep(OP_FLIP, Params{"direction": VERTICAL}),
+		}
+		assert.NoError(t, pipeline.Apply(editor))
+
+		assert.Len(t, editor.history.undo, 2)
+	})
+
+	t.Run("operations without changes are not recorded", func(t *testing.T) {
+		editor := newHistoryEditor(DEFAULT_HISTORY_BUDGET)
+		editor.CropByRectangle(image.Rect(0, 0, 10, 10))
+		editor.Encode(io.Discard, MIMEPNG)
+
+		assert.False(t, editor.CanUndo())
+	})
+
+	t.Run("disabled history", func(t *testing.T) {
+		editor := newHistoryEditor(0)
+		editor.ModifyPixels(mods.NewNegative())
+
+		assert.False(t, editor.CanUndo())
+		assert.False(t, editor.Undo())
+	})
+}
+
+func TestImageEditor_EnableHistory(t *testing.T) {
+	// Every state of the 4x3 image uses 48 bytes.
+	editor := newHistoryEditor(100)
+	for i := 0; i < 4; i++ {
+		editor.ModifyPixels(mods.NewNegative())
+	}
+	assert.Len(t, editor.history.undo, 2, "the oldest states must be forgotten")
+
+	editor.EnableHistory(50)
+	assert.Len(t, editor.history.undo, 1)
+
+	editor.EnableHistory(0)
+	assert.False(t, editor.CanUndo())
+}
+
+func TestImageEditor_Undo_animation(t *testing.T) {
+	editor, _ := newAnimatedEditor(t)
+	editor.EnableHistory(DEFAULT_HISTORY_BUDGET)
+	frame := editor.animation.frames[0]
+	before := frame.EditedImage().Bounds()
+
+	editor.CropByRectangle(image.Rect(0, 0, 10, 10))
+	assert.Equal(t, image.Rect(0, 0, 10, 10), frame.EditedImage().Bounds())
+
+	editor.Undo()
+	assert.Equal(t, before, frame.EditedImage().Bounds(),
+		"the frames must be restored")
+}
+
+func TestImageEditor_MemorySize(t *testing.T) {
+	// The source and the destination of the 4x3 image use 48 bytes each.
+	editor := newHistoryEditor(DEFAULT_HISTORY_BUDGET)
+	assert.Equal(t, 96, editor.MemorySize())
+
+	editor.ModifyPixels(mods.NewNegative())
+	assert.Equal(t, 144, editor.MemorySize(),
+		"the saved state must be counted")
+}
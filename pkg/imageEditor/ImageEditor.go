@@ -3,63 +3,106 @@
 package imageEditor
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"image"
 	"image/color"
-	"image/jpeg"
-	"image/png"
+	"image/draw"
 	"io"
-	"mime"
-	"runtime"
-	"sync"
 
 	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/geom"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/meta"
 	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/mods"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/resample"
 )
 
-// Supported mime types
+// Mime types of the built-in image formats
 const (
 	MIMEPNG  = "image/png"
 	MIMEJPEG = "image/jpeg"
+	MIMEGIF  = "image/gif"
+	MIMEBMP  = "image/bmp"
+	MIMETIFF = "image/tiff"
+	MIMEWEBP = "image/webp"
 )
 
-// IsSupportedImageFormat checks whether ImageEditor supports this image format
-// or not.
-func IsSupportedImageFormat(mimeType string) bool {
-	switch mimeType {
-	case MIMEPNG, MIMEJPEG:
-		return true
-	default:
-		return false
-	}
-}
-
 // NewImageEditor creates a new ImageEditor instance by decoding the image from
 // the given io.Reader. It returns an error if the decoding fails.
 func NewImageEditor(reader io.Reader) (*ImageEditor, error) {
+	return NewImageEditorWithOptions(reader, nil)
+}
+
+// NewImageEditorWithOptions creates a new ImageEditor instance by decoding the
+// image from the given io.Reader with the given options. A nil options is
+// equivalent to the default options. It returns an error if the decoding fails
+// and ErrTooLarge if an animated image has too many pixels.
+func NewImageEditorWithOptions(reader io.Reader,
+	options *DecodeOptions) (*ImageEditor, error) {
 	if reader == nil {
 		return nil, errors.New("io.Reader is nil")
 	}
+	if options == nil {
+		options = new(DecodeOptions)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
 
-	source, err := decode(reader)
+	source, format, err := decode(bytes.NewReader(data))
 	if err != nil {
 		return nil, err
 	}
 
-	destination := image.NewRGBA(source.Bounds())
-	return &ImageEditor{source, destination, false, false}, nil
+	var animation *animation
+	if format == MIMEGIF {
+		source, animation, err = decodeAnimation(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	editor := &ImageEditor{
+		source:    source,
+		format:    format,
+		metadata:  meta.Read(data),
+		animation: animation,
+	}
+	if isDeep(source) {
+		editor.destination64 = image.NewRGBA64(source.Bounds())
+	} else {
+		editor.destination = image.NewRGBA(source.Bounds())
+	}
+
+	if !options.IgnoreOrientation {
+		orientation := meta.Orientation(editor.metadata.EXIF)
+		if orientation != meta.ORIENTATION_NORMAL {
+			editor.orient(orientation)
+			editor.metadata.EXIF = meta.ResetOrientation(editor.metadata.EXIF)
+		}
+	}
+	return editor, nil
 }
 
-// decode decodes an image from the given io.Reader and returns the image.Image.
-// It returns an error if the decoding fails.
-func decode(reader io.Reader) (image.Image, error) {
-	img, ext, err := image.Decode(reader)
-	if err != nil || !IsSupportedImageFormat(mime.TypeByExtension("."+ext)) {
-		return nil, image.ErrFormat
+// decode decodes an image from the given io.Reader and returns the image.Image
+// and the mime type of its format. The format is detected from the content of
+// the image. It returns an error if the decoding fails.
+func decode(reader io.Reader) (image.Image, string, error) {
+	buffered := bufio.NewReader(reader)
+	codec, ok := sniffCodec(buffered)
+	if !ok {
+		return nil, "", image.ErrFormat
 	}
 
-	return img, nil
+	img, err := codec.Decode(buffered)
+	if err != nil {
+		return nil, "", image.ErrFormat
+	}
+
+	return img, codec.MIMEType, nil
 }
 
 // ImageEditor is an image editor that contains various tools for manipulating
@@ -68,12 +111,35 @@ type ImageEditor struct {
 	// source is original image that is loaded into the editor.
 	source image.Image
 	// destination is the final edited image that is produced from the original.
+	// It is nil while destination64 is used.
 	destination *image.RGBA
+	// destination64 is the edited image of a high bit depth source, which keeps
+	// 16 bits per channel. It is replaced with destination by the changes that
+	// do not support 16 bits.
+	destination64 *image.RGBA64
 	// isModifiedPixels is boolean indicating if the pixels of the image have.
 	// been modified.
 	isModifiedPixels bool
 	// isCropped is boolean indicating if the image has been cropped.
 	isCropped bool
+	// format is the mime type of the original image.
+	format string
+	// metadata is the metadata of the original image.
+	metadata *meta.Metadata
+	// animation stores the other frames of an animated image. It is nil for
+	// still images.
+	animation *animation
+	// history stores the previous states of the image. It is nil if the
+	// history is disabled.
+	history *history
+	// workers is the number of goroutines that modify the pixels, or 0 for
+	// runtime.NumCPU().
+	workers int
+}
+
+// Format returns the mime type of the original image.
+func (editor *ImageEditor) Format() string {
+	return editor.format
 }
 
 // IsModifiedImage checks if the image has been modified.
@@ -93,100 +159,336 @@ func (editor *ImageEditor) EditedImage() image.Image {
 		if !editor.isModifiedPixels {
 			// If the destination field's pixels are uninitialized, they will be
 			// copied from the source field via mods.NewCopy().
-			editor.ModifyPixels(mods.NewCopy())
+			editor.modifyPixels(context.Background(), mods.NewCopy())
 		}
-		editedImage = editor.destination
+		editedImage = editor.editedPixels()
 	}
 
 	return editedImage
 }
 
+// editedPixels returns the destination image that is used.
+func (editor *ImageEditor) editedPixels() image.Image {
+	if editor.destination64 != nil {
+		return editor.destination64
+	}
+	return editor.destination
+}
+
+// bounds returns the bounds of the edited image.
+func (editor *ImageEditor) bounds() image.Rectangle {
+	return editor.editedPixels().Bounds()
+}
+
 func (editor *ImageEditor) Size() geom.Size {
+	bounds := editor.bounds()
 	return geom.NewSize(
-		editor.destination.Rect.Dx(),
-		editor.destination.Rect.Dy(),
+		bounds.Dx(),
+		bounds.Dy(),
 	)
 }
 
+// MemorySize returns the approximate number of bytes used by the images of the
+// editor, including the other frames of an animation and the history.
+func (editor *ImageEditor) MemorySize() int {
+	bounds := editor.source.Bounds()
+	size := bounds.Dx() * bounds.Dy() * 4
+	if isDeep(editor.source) {
+		size *= 2
+	}
+	if editor.destination64 != nil {
+		size += len(editor.destination64.Pix)
+	} else {
+		size += len(editor.destination.Pix)
+	}
+	if editor.history != nil {
+		size += editor.history.size()
+	}
+	editor.eachFrame(func(frame *ImageEditor) {
+		size += frame.MemorySize()
+	})
+	return size
+}
+
 // ModifyPixels gets pixels from the source field, changes pixel values using
 // mods.PixelModifier and sets new values in the destination field.
 func (editor *ImageEditor) ModifyPixels(pixelModifer mods.PixelModifier) {
-	if pixelModifer == nil {
-		return
+	editor.ModifyPixelsContext(context.Background(), pixelModifer)
+}
+
+// ModifyPixelsContext is like ModifyPixels, but it stops when the context is
+// done and returns the error of the context, in which case the image is left
+// unchanged.
+func (editor *ImageEditor) ModifyPixelsContext(ctx context.Context,
+	pixelModifer mods.PixelModifier) error {
+	defer editor.track()()
+
+	before := editor.state()
+	if err := editor.modifyPixels(ctx, pixelModifer); err != nil {
+		editor.restore(before)
+		return err
 	}
+	return nil
+}
+
+// SetWorkers sets the number of goroutines that modify the pixels. If it is not
+// positive, runtime.NumCPU() goroutines are used.
+func (editor *ImageEditor) SetWorkers(workers int) {
+	editor.workers = workers
+	editor.eachFrame(func(frame *ImageEditor) {
+		frame.SetWorkers(workers)
+	})
+}
 
-	bounds := editor.destination.Bounds()
-	width, height := bounds.Dx(), bounds.Dy()
+// modifyPixels modifies the pixels without recording the change in the
+// history.
+func (editor *ImageEditor) modifyPixels(ctx context.Context,
+	pixelModifer mods.PixelModifier) error {
+	if pixelModifer == nil {
+		return nil
+	}
 
 	if editor.isModifiedPixels {
-		// If pixels have been changed, we move the original field and create a
-		// new destination field to modify pixels again.
-		editor.source = editor.destination
-		editor.destination = image.NewRGBA(bounds)
-	}
-
-	var waitGroup sync.WaitGroup
-	groupCount := runtime.NumCPU()
-	waitGroup.Add(groupCount)
-	pixelCount := width * height
-	pixelPerGroup := pixelCount / groupCount
-
-	for currentGroup := 0; currentGroup < groupCount; currentGroup++ {
-		go func(group int) {
-			defer waitGroup.Done()
-
-			var startPixel int = pixelPerGroup * group
-			var endPixel int
-			if group == groupCount-1 {
-				endPixel = pixelCount
-			} else {
-				endPixel = startPixel + pixelPerGroup
-			}
+		// If pixels have been changed, we move the original field to modify
+		// pixels again.
+		editor.source = editor.editedPixels()
+	}
 
-			for currentPixel := startPixel; currentPixel < endPixel; currentPixel++ {
-				x := currentPixel%width + bounds.Min.X
-				y := currentPixel/width + bounds.Min.Y
-				oldColor := color.RGBAModel.Convert(
-					editor.source.At(x, y)).(color.RGBA)
-				newColor := pixelModifer.ModifyPixel(
-					image.Pt(x, y),
-					oldColor,
-					editor.source,
-				)
-				editor.destination.SetRGBA(x, y, newColor)
-			}
-		}(currentGroup)
+	// The pixels are always written to a new destination, so that the images
+	// saved in the history are never changed. Decoded images are converted
+	// once, so that the modifiers read the packed pixels directly.
+	bounds := editor.bounds()
+	modifier64, isModifier64 := pixelModifer.(mods.PixelModifier64)
+	if editor.destination64 != nil && isModifier64 {
+		source := toRGBA64(editor.source)
+		editor.source = source
+		destination, err := mods.ModifyPixels64Context(ctx, modifier64,
+			source, bounds, editor.workers)
+		if err != nil {
+			return err
+		}
+		editor.destination64 = destination
+		editor.isModifiedPixels = true
+	} else {
+		// Modifiers without the 16-bit variant reduce the image to 8 bits.
+		editor.source = toRGBA(editor.source)
+		destination, err := mods.ModifyPixelsContext(ctx, pixelModifer,
+			editor.source, bounds, editor.workers)
+		if err != nil {
+			return err
+		}
+		editor.setDestination(destination)
 	}
 
-	waitGroup.Wait()
-	editor.isModifiedPixels = true
+	var err error
+	editor.eachFrame(func(frame *ImageEditor) {
+		if err == nil {
+			err = frame.modifyPixels(ctx, pixelModifer)
+		}
+	})
+	return err
+}
+
+// ApplyOperation replaces the edited image with the result of the operation,
+// which can also change the size of the image. It returns the error of the
+// operation, in which case the image is left unchanged.
+func (editor *ImageEditor) ApplyOperation(operation mods.ImageOperation) error {
+	return editor.ApplyOperationContext(context.Background(), operation)
+}
+
+// ApplyOperationContext is like ApplyOperation, but it stops when the context is
+// done and returns the error of the context. Operations that implement
+// mods.ContextOperation are also stopped while they are applied.
+func (editor *ImageEditor) ApplyOperationContext(ctx context.Context,
+	operation mods.ImageOperation) error {
+	defer editor.track()()
+	if operation == nil {
+		return nil
+	}
+
+	before := editor.state()
+	if err := editor.applyOperation(ctx, operation); err != nil {
+		editor.restore(before)
+		return err
+	}
+	return nil
+}
+
+// applyOperation applies the operation to the image and the other frames of an
+// animation without recording the change in the history.
+func (editor *ImageEditor) applyOperation(ctx context.Context,
+	operation mods.ImageOperation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	src := toRGBA(editor.EditedImage())
+	var result *image.RGBA
+	var err error
+	if contextOperation, ok := operation.(mods.ContextOperation); ok {
+		result, err = contextOperation.ApplyContext(ctx, src)
+	} else {
+		result, err = operation.Apply(src)
+	}
+	if err != nil {
+		return err
+	}
+	editor.setDestination(result)
+
+	editor.eachFrame(func(frame *ImageEditor) {
+		if err == nil {
+			err = frame.applyOperation(ctx, operation)
+		}
+	})
+	return err
 }
 
 // Encode encodes the image and saves it using the specified writer. It returns
 // an error if there is a problem with encoding the image.
 func (editor *ImageEditor) Encode(writer io.Writer, mimeType string) error {
-	switch mimeType {
-	case MIMEPNG:
-		return png.Encode(writer, editor.EditedImage())
-	case MIMEJPEG:
-		return jpeg.Encode(writer, editor.EditedImage(), nil)
-	default:
+	return editor.EncodeWithOptions(writer, mimeType, nil)
+}
+
+// EncodeWithOptions is like Encode, but it uses the specified options. A nil
+// options is equivalent to the default options.
+func (editor *ImageEditor) EncodeWithOptions(writer io.Writer, mimeType string,
+	options *EncodeOptions) error {
+	if options == nil {
+		options = new(EncodeOptions)
+	}
+
+	if mimeType == MIMEJPEG && options.MaxBytes > 0 {
+		buffer, err := editor.encodeToMaxBytes(options)
+		if err != nil {
+			return err
+		}
+		_, err = buffer.WriteTo(writer)
+		return err
+	}
+
+	return editor.encode(writer, mimeType, options)
+}
+
+// encodeToMaxBytes encodes the image as JPEG with the highest quality that
+// keeps the image within options.MaxBytes.
+func (editor *ImageEditor) encodeToMaxBytes(
+	options *EncodeOptions) (*bytes.Buffer, error) {
+	current := *options
+	var best *bytes.Buffer
+
+	// Binary search of the quality, as the size grows with the quality.
+	low, high := 1, options.jpegQuality()
+	for low <= high {
+		current.Quality = (low + high) / 2
+		buffer := new(bytes.Buffer)
+		err := editor.encode(buffer, MIMEJPEG, &current)
+		if err != nil {
+			return nil, err
+		}
+
+		if buffer.Len() <= options.MaxBytes {
+			best = buffer
+			low = current.Quality + 1
+		} else {
+			if best == nil && current.Quality == 1 {
+				best = buffer
+			}
+			high = current.Quality - 1
+		}
+	}
+
+	return best, nil
+}
+
+// encode encodes the image with the metadata using the specified options.
+func (editor *ImageEditor) encode(writer io.Writer, mimeType string,
+	options *EncodeOptions) error {
+	metadata := editor.outputMetadata(options.Metadata)
+	if metadata.IsEmpty() {
+		return editor.encodeImage(writer, mimeType, options)
+	}
+
+	buffer := new(bytes.Buffer)
+	err := editor.encodeImage(buffer, mimeType, options)
+	if err != nil {
+		return err
+	}
+
+	data := buffer.Bytes()
+	// Formats without WriteMetadata do not support the metadata.
+	if codec, _ := CodecByMIMEType(mimeType); codec.WriteMetadata != nil {
+		data, err = codec.WriteMetadata(data, metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = writer.Write(data)
+	return err
+}
+
+// encodeImage encodes the edited image without metadata.
+func (editor *ImageEditor) encodeImage(writer io.Writer, mimeType string,
+	options *EncodeOptions) error {
+	codec, ok := CodecByMIMEType(mimeType)
+	if !ok || codec.Encode == nil {
 		return image.ErrFormat
 	}
+
+	if mimeType == MIMEGIF && editor.animation != nil {
+		return editor.encodeAnimation(writer)
+	}
+	return codec.Encode(writer, editor.EditedImage(), options)
+}
+
+// outputMetadata returns the metadata of the original image that is kept
+// according to the metadata mode.
+func (editor *ImageEditor) outputMetadata(mode string) *meta.Metadata {
+	if editor.metadata == nil {
+		return new(meta.Metadata)
+	}
+
+	switch mode {
+	case METADATA_KEEP:
+		return editor.metadata
+	case METADATA_KEEP_ICC:
+		return &meta.Metadata{ICC: editor.metadata.ICC}
+	case METADATA_KEEP_COPYRIGHT:
+		return &meta.Metadata{
+			EXIF: meta.KeepTags(editor.metadata.EXIF, meta.TAG_ORIENTATION,
+				meta.TAG_ARTIST, meta.TAG_COPYRIGHT),
+			ICC: editor.metadata.ICC,
+		}
+	default:
+		return new(meta.Metadata)
+	}
 }
 
 // CropByRectangle crops the image to the given rectangle.
 func (editor *ImageEditor) CropByRectangle(bounds image.Rectangle) {
+	defer editor.track()()
 	if bounds.Empty() {
 		return
 	}
 
-	if !bounds.In(editor.destination.Rect) || bounds.Eq(editor.destination.Rect) {
+	if !bounds.In(editor.bounds()) || bounds.Eq(editor.bounds()) {
 		return
 	}
 
-	editor.destination = editor.destination.SubImage(bounds).(*image.RGBA)
+	if editor.destination64 != nil {
+		editor.destination64 =
+			editor.destination64.SubImage(bounds).(*image.RGBA64)
+	} else if editor.destination.Pix == nil {
+		// The destination without pixels only stores the bounds.
+		editor.destination = &image.RGBA{Rect: bounds}
+	} else {
+		editor.destination = editor.destination.SubImage(bounds).(*image.RGBA)
+	}
 	editor.isCropped = true
+
+	editor.eachFrame(func(frame *ImageEditor) {
+		frame.CropByRectangle(bounds)
+	})
 }
 
 // CropBySizeAndAlignment crops the image according to the given geometry.Size
@@ -201,11 +503,79 @@ func (editor *ImageEditor) CropBySizeAndAlignment(size geom.Size,
 	editor.CropByRectangle(rect)
 }
 
+// Resize scales the image to the given size using the given resampling kernel.
+// If one of the dimensions is zero, it is calculated from the other one so that
+// the aspect ratio of the image is preserved.
+func (editor *ImageEditor) Resize(size geom.Size, kernel resample.Kernel) {
+	defer editor.track()()
+	imageSize := editor.Size()
+	if imageSize.IsEmpty() {
+		return
+	}
+
+	width, height := size.Width(), size.Height()
+	switch {
+	case width == 0 && height == 0:
+		return
+	case width == 0:
+		width = int(float64(height)*float64(imageSize.Width())/
+			float64(imageSize.Height()) + 0.5)
+	case height == 0:
+		height = int(float64(width)*float64(imageSize.Height())/
+			float64(imageSize.Width()) + 0.5)
+	}
+
+	if width == imageSize.Width() && height == imageSize.Height() {
+		return
+	}
+
+	if editor.destination64 != nil {
+		editor.setDestination64(resample.Resize64(
+			toRGBA64(editor.EditedImage()), width, height, kernel))
+	} else {
+		editor.setDestination(resample.Resize(
+			toRGBA(editor.EditedImage()), width, height, kernel))
+	}
+
+	editor.eachFrame(func(frame *ImageEditor) {
+		frame.Resize(size, kernel)
+	})
+}
+
+// ResizeToBox scales the image into the box using the given fit mode. The
+// alignment sets the position of the image inside the box for geom.FIT and the
+// part of the image that is kept for geom.COVER. The background fills the empty
+// parts of the box for geom.FIT.
+func (editor *ImageEditor) ResizeToBox(box geom.Size, fit string,
+	alignment geom.Alignment, kernel resample.Kernel,
+	background color.Color) {
+	defer editor.track()()
+	if box.IsEmpty() || editor.Size().IsEmpty() {
+		return
+	}
+
+	editor.Resize(editor.Size().ScaleTo(box, fit), kernel)
+
+	switch fit {
+	case geom.COVER:
+		editor.CropBySizeAndAlignment(box, alignment)
+	case geom.FIT:
+		editor.extend(box, alignment, background)
+	}
+}
+
 // BytesBuffer returns a byte  representation of the Edited Image, encoded using 
 // the specified mimeType. It returns an error if the encoding fails.
 func (editor *ImageEditor) BytesBuffer(mimeType string) (*bytes.Buffer, error) {
+	return editor.BytesBufferWithOptions(mimeType, nil)
+}
+
+// BytesBufferWithOptions is like BytesBuffer, but it encodes the image with the
+// specified options. A nil options is equivalent to the default options.
+func (editor *ImageEditor) BytesBufferWithOptions(mimeType string,
+	options *EncodeOptions) (*bytes.Buffer, error) {
 	buffer := new(bytes.Buffer)
-	err := editor.Encode(buffer, mimeType)
+	err := editor.EncodeWithOptions(buffer, mimeType, options)
 	if err != nil {
 		return nil, err
 	}
@@ -216,10 +586,10 @@ func (editor *ImageEditor) BytesBuffer(mimeType string) (*bytes.Buffer, error) {
 // into an image.Rectangle for the edited image.
 func (editor *ImageEditor) sizeAndAlignmentToRectangle(size geom.Size,
 	alignment geom.Alignment) image.Rectangle {
-	bounds := editor.destination.Bounds()
+	bounds := editor.bounds()
 	var rect image.Rectangle
 
-	if size.Width() >= bounds.Dx() || size.Height() >= bounds.Dy() {
+	if size.Width() > bounds.Dx() || size.Height() > bounds.Dy() {
 		return bounds
 	}
 
@@ -259,3 +629,120 @@ func (editor *ImageEditor) sizeAndAlignmentToRectangle(size geom.Size,
 
 	return rect
 }
+
+// extend places the image into a canvas of the given size according to the
+// alignment. The rest of the canvas is filled with the background.
+func (editor *ImageEditor) extend(size geom.Size, alignment geom.Alignment,
+	background color.Color) {
+	defer editor.track()()
+	imageSize := editor.Size()
+	if size.Width() < imageSize.Width() || size.Height() < imageSize.Height() {
+		return
+	}
+	if size == imageSize {
+		return
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, size.Width(), size.Height()))
+	if background != nil {
+		draw.Draw(canvas, canvas.Rect, image.NewUniform(background),
+			image.Point{}, draw.Src)
+	}
+
+	x := alignmentOffset(size.Width()-imageSize.Width(),
+		alignment.Vertical() == geom.LEFT, alignment.Vertical() == geom.RIGHT)
+	y := alignmentOffset(size.Height()-imageSize.Height(),
+		alignment.Horizontal() == geom.TOP, alignment.Horizontal() == geom.BOTTOM)
+
+	edited := editor.EditedImage()
+	bounds := edited.Bounds()
+	draw.Draw(canvas, bounds.Sub(bounds.Min).Add(image.Pt(x, y)), edited,
+		bounds.Min, draw.Over)
+	editor.setDestination(canvas)
+
+	editor.eachFrame(func(frame *ImageEditor) {
+		frame.extend(size, alignment, background)
+	})
+}
+
+// alignmentOffset returns the offset of an object inside free space of the
+// given length. Centered objects are shifted by one pixel to the end when the
+// free space is odd, just like when cropping.
+func alignmentOffset(free int, isStart, isEnd bool) int {
+	switch {
+	case isStart:
+		return 0
+	case isEnd:
+		return free
+	default:
+		return free - free/2
+	}
+}
+
+// setDestination replaces the edited image with the given one. It is used by
+// operations that change the geometry of the image, which mostly work with 8
+// bits per channel.
+func (editor *ImageEditor) setDestination(destination *image.RGBA) {
+	editor.destination = destination
+	editor.destination64 = nil
+	editor.isModifiedPixels = true
+}
+
+// setDestination64 replaces the edited image with the given 16-bit one. It is
+// used by the operations that keep the depth of a 16-bit image.
+func (editor *ImageEditor) setDestination64(destination *image.RGBA64) {
+	editor.destination = nil
+	editor.destination64 = destination
+	editor.isModifiedPixels = true
+}
+
+// flatten returns the image blended with the opaque background. A nil or
+// translucent background is blended with white. Opaque images are returned as
+// is.
+func flatten(img image.Image, background color.Color) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+
+	bounds := img.Bounds()
+	result := image.NewRGBA(bounds)
+	draw.Draw(result, bounds, image.White, image.Point{}, draw.Src)
+	if background != nil {
+		draw.Draw(result, bounds, image.NewUniform(background), image.Point{},
+			draw.Over)
+	}
+	draw.Draw(result, bounds, img, bounds.Min, draw.Over)
+	return result
+}
+
+// toRGBA returns the image as *image.RGBA, converting it if necessary.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Rect, img, rgba.Rect.Min, draw.Src)
+	return rgba
+}
+
+// toRGBA64 returns the image as *image.RGBA64, converting it if necessary.
+func toRGBA64(img image.Image) *image.RGBA64 {
+	if rgba64, ok := img.(*image.RGBA64); ok {
+		return rgba64
+	}
+
+	rgba64 := image.NewRGBA64(img.Bounds())
+	draw.Draw(rgba64, rgba64.Rect, img, rgba64.Rect.Min, draw.Src)
+	return rgba64
+}
+
+// isDeep reports whether the image has more than 8 bits per channel.
+func isDeep(img image.Image) bool {
+	switch img.ColorModel() {
+	case color.RGBA64Model, color.NRGBA64Model, color.Gray16Model:
+		return true
+	default:
+		return false
+	}
+}
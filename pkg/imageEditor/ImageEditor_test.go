@@ -4,15 +4,22 @@ package imageEditor
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	"image/jpeg"
+	"image/png"
 	"io"
 	"os"
 	"testing"
 
 	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/geom"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/meta"
 	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/mods"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/resample"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -62,8 +69,28 @@ func TestIsSupportedImageFormat(t *testing.T) {
 			want: true,
 		},
 		{
-			name: "Unsupported format: image/gif",
-			args: args{"image/gif"},
+			name: "Correct image format: " + MIMEGIF,
+			args: args{MIMEGIF},
+			want: true,
+		},
+		{
+			name: "Correct image format: " + MIMEBMP,
+			args: args{MIMEBMP},
+			want: true,
+		},
+		{
+			name: "Correct image format: " + MIMETIFF,
+			args: args{MIMETIFF},
+			want: true,
+		},
+		{
+			name: "Correct image format: " + MIMEWEBP,
+			args: args{MIMEWEBP},
+			want: true,
+		},
+		{
+			name: "Unsupported format: image/svg+xml",
+			args: args{"image/svg+xml"},
 			want: false,
 		},
 		{
@@ -80,12 +107,50 @@ func TestIsSupportedImageFormat(t *testing.T) {
 	}
 }
 
-func TestNewImageEditor(t *testing.T) {
+func TestSupportedMIMETypes(t *testing.T) {
+	for _, mimeType := range SupportedMIMETypes() {
+		assert.True(t, IsSupportedImageFormat(mimeType),
+			"%q must be supported", mimeType)
+	}
+}
 
+func TestMIMETypeByFormat(t *testing.T) {
 	tests := []struct {
 		name     string
-		fileName string
-		wantErr  bool
+		format   string
+		want     string
+		wantIsOk bool
+	}{
+		{"png name", "png", MIMEPNG, true},
+		{"jpeg name", "jpeg", MIMEJPEG, true},
+		{"jpg name", "JPG", MIMEJPEG, true},
+		{"png mime type", MIMEPNG, MIMEPNG, true},
+		{"jpeg mime type", MIMEJPEG, MIMEJPEG, true},
+		{"tif name", "tif", MIMETIFF, true},
+		{"bmp name", "BMP", MIMEBMP, true},
+		{"decode only name", "webp", "", false},
+		{"decode only mime type", MIMEWEBP, "", false},
+		{"unsupported name", "txt", "", false},
+		{"unsupported mime type", "text/plain", "", false},
+		{"empty format", "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := MIMETypeByFormat(test.format)
+			assert.Equal(t, test.wantIsOk, ok, "MIMETypeByFormat(%q)", test.format)
+			assert.Equal(t, test.want, got, "MIMETypeByFormat(%q) = %q, want %q",
+				test.format, got, test.want)
+		})
+	}
+}
+
+func TestNewImageEditor(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		fileName       string
+		wantFrameCount int
+		wantErr        bool
 	}{
 		{
 			name:     "jpeg extension",
@@ -103,9 +168,25 @@ func TestNewImageEditor(t *testing.T) {
 			wantErr:  false,
 		},
 		{
-			name:     "gif extension",
-			fileName: "test_image.gif",
-			wantErr:  true,
+			name:           "gif extension",
+			fileName:       "test_image.gif",
+			wantFrameCount: 11,
+			wantErr:        false,
+		},
+		{
+			name:     "bmp extension",
+			fileName: "test_image.bmp",
+			wantErr:  false,
+		},
+		{
+			name:     "tiff extension",
+			fileName: "test_image.tiff",
+			wantErr:  false,
+		},
+		{
+			name:     "webp extension",
+			fileName: "test_image.webp",
+			wantErr:  false,
 		},
 		{
 			name:     "broken jepg file",
@@ -154,7 +235,13 @@ func TestNewImageEditor(t *testing.T) {
 				return
 			}
 
-			if !assert.ObjectsAreEqual(img, editor.source) {
+			if test.wantFrameCount > 1 {
+				// The frames of animated images are composed into full images.
+				assert.Equal(t, test.wantFrameCount, editor.FrameCount(),
+					"incorrect number of frames")
+				assert.Equal(t, img.Bounds(), editor.source.Bounds(),
+					"The source field is incorrectly initialized")
+			} else if !assert.ObjectsAreEqual(img, editor.source) {
 				t.Error("The source field is incorrectly initialized")
 			}
 
@@ -171,31 +258,130 @@ func TestNewImageEditor(t *testing.T) {
 	}
 }
 
+// newJPEGWithOrientation encodes the image as JPEG with an EXIF segment that
+// contains the given orientation.
+func newJPEGWithOrientation(img image.Image, orientation uint16) []byte {
+	buffer := new(bytes.Buffer)
+	jpeg.Encode(buffer, img, nil)
+	data := buffer.Bytes()
+
+	exif := []byte("Exif\x00\x00II*\x00\x08\x00\x00\x00" +
+		"\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x00\x00\x00\x00" +
+		"\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint16(exif[24:], orientation)
+
+	segment := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(exif)+2))
+	segment = append(segment, exif...)
+
+	result := append([]byte{}, data[:2]...)
+	result = append(result, segment...)
+	return append(result, data[2:]...)
+}
+
+func TestNewImageEditorWithOptions(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+
+	tests := []struct {
+		name        string
+		orientation uint16
+		options     *DecodeOptions
+		wantBounds  image.Rectangle
+	}{
+		{
+			name:        "nil options",
+			orientation: 6,
+			options:     nil,
+			wantBounds:  image.Rect(0, 0, 2, 4),
+		},
+		{
+			name:        "normal orientation",
+			orientation: 1,
+			options:     &DecodeOptions{},
+			wantBounds:  image.Rect(0, 0, 4, 2),
+		},
+		{
+			name:        "rotated image",
+			orientation: 8,
+			options:     &DecodeOptions{},
+			wantBounds:  image.Rect(0, 0, 2, 4),
+		},
+		{
+			name:        "ignored orientation",
+			orientation: 6,
+			options:     &DecodeOptions{IgnoreOrientation: true},
+			wantBounds:  image.Rect(0, 0, 4, 2),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := newJPEGWithOrientation(img, test.orientation)
+			editor, err := NewImageEditorWithOptions(bytes.NewReader(data),
+				test.options)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, test.wantBounds, editor.EditedImage().Bounds(),
+				"incorrect bounds of the edited image")
+			wantOrientation := meta.ORIENTATION_NORMAL
+			if test.options != nil && test.options.IgnoreOrientation {
+				wantOrientation = int(test.orientation)
+			}
+			assert.Equal(t, wantOrientation, meta.Orientation(editor.metadata.EXIF),
+				"the orientation must be reset only after orienting the image")
+		})
+	}
+}
+
 func Test_decode(t *testing.T) {
 	tests := []struct {
-		name     string
-		fileName string
-		wantErr  bool
+		name         string
+		fileName     string
+		wantMIMEType string
+		wantErr      bool
 	}{
 		{
-			name:     "decoding test_image.jpeg",
-			fileName: "test_image.jpeg",
-			wantErr:  false,
+			name:         "decoding test_image.jpeg",
+			fileName:     "test_image.jpeg",
+			wantMIMEType: MIMEJPEG,
+			wantErr:      false,
 		},
 		{
-			name:     "decoding test_image.jpg ",
-			fileName: "test_image.jpg",
-			wantErr:  false,
+			name:         "decoding test_image.jpg ",
+			fileName:     "test_image.jpg",
+			wantMIMEType: MIMEJPEG,
+			wantErr:      false,
 		},
 		{
-			name:     "decoding test_image.png",
-			fileName: "test_image.png",
-			wantErr:  false,
+			name:         "decoding test_image.png",
+			fileName:     "test_image.png",
+			wantMIMEType: MIMEPNG,
+			wantErr:      false,
 		},
 		{
-			name:     "decoding test_image.gif",
-			fileName: "test_image.gif",
-			wantErr:  true,
+			name:         "decoding test_image.gif",
+			fileName:     "test_image.gif",
+			wantMIMEType: MIMEGIF,
+			wantErr:      false,
+		},
+		{
+			name:         "decoding test_image.bmp",
+			fileName:     "test_image.bmp",
+			wantMIMEType: MIMEBMP,
+			wantErr:      false,
+		},
+		{
+			name:         "decoding test_image.tiff",
+			fileName:     "test_image.tiff",
+			wantMIMEType: MIMETIFF,
+			wantErr:      false,
+		},
+		{
+			name:         "decoding test_image.webp",
+			fileName:     "test_image.webp",
+			wantMIMEType: MIMEWEBP,
+			wantErr:      false,
 		},
 		{
 			name:     "decoding broken_image.jpeg",
@@ -223,7 +409,7 @@ func Test_decode(t *testing.T) {
 				return
 			}
 			defer file.Close()
-			img, err := decode(file)
+			img, mimeType, err := decode(file)
 
 			if test.wantErr {
 				assert.Error(t, err)
@@ -242,6 +428,8 @@ func Test_decode(t *testing.T) {
 			if !assert.ObjectsAreEqual(wantImg, img) {
 				t.Error("The source field is incorrectly initialized")
 			}
+			assert.Equal(t, test.wantMIMEType, mimeType,
+				"incorrect mime type of the image")
 		})
 	}
 }
@@ -464,6 +652,168 @@ func TestImageEditor_ModifyPixels(t *testing.T) {
 	}
 }
 
+func TestImageEditor_ModifyPixelsContext(t *testing.T) {
+	bounds := image.Rect(0, 0, 4, 3)
+	newEditor := func() *ImageEditor {
+		editor := &ImageEditor{
+			source:      newNumberedImage(bounds),
+			destination: image.NewRGBA(bounds),
+		}
+		editor.EnableHistory(DEFAULT_HISTORY_BUDGET)
+		return editor
+	}
+	want := newEditor()
+	want.ModifyPixels(mods.NewNegative())
+
+	t.Run("workers", func(t *testing.T) {
+		editor := newEditor()
+		editor.SetWorkers(1)
+		err := editor.ModifyPixelsContext(context.Background(),
+			mods.NewNegative())
+		assert.NoError(t, err)
+		assert.Equal(t, pixels(want), pixels(editor))
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		editor := newEditor()
+		original := pixels(editor)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := editor.ModifyPixelsContext(ctx, mods.NewNegative())
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, editor.IsModifiedImage())
+		assert.Equal(t, original, pixels(editor))
+		assert.False(t, editor.CanUndo(),
+			"the cancelled change must not be recorded")
+	})
+}
+
+// newDeepPNG returns an opaque 16-bit PNG image, whose channels cannot be
+// stored in 8 bits.
+func newDeepPNG(bounds image.Rectangle) (*image.RGBA64, []byte) {
+	img := image.NewRGBA64(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			value := uint16(0x1234 + 0x0101*(x+y*bounds.Dx()))
+			img.SetRGBA64(x, y, color.RGBA64{value, value + 1, value + 3,
+				0xffff})
+		}
+	}
+	buffer := new(bytes.Buffer)
+	png.Encode(buffer, img)
+	return img, buffer.Bytes()
+}
+
+func TestImageEditor_ModifyPixels_deep(t *testing.T) {
+	bounds := image.Rect(0, 0, 4, 3)
+	original, data := newDeepPNG(bounds)
+
+	t.Run("16 bits", func(t *testing.T) {
+		editor, err := NewImageEditor(bytes.NewReader(data))
+		assert.NoError(t, err)
+		editor.EnableHistory(DEFAULT_HISTORY_BUDGET)
+		editor.ModifyPixels(mods.NewNegative())
+		assert.NotNil(t, editor.destination64,
+			"the 16-bit image must be kept")
+		assert.Equal(t, color.RGBA64{0xedcb, 0xedca, 0xedc8, 0xffff},
+			editor.destination64.RGBA64At(0, 0))
+		editor.ModifyPixels(mods.NewNegative())
+		editor.CropByRectangle(image.Rect(1, 1, 3, 3))
+
+		buffer, err := editor.BytesBuffer(MIMEPNG)
+		assert.NoError(t, err)
+		got, err := png.Decode(buffer)
+		assert.NoError(t, err)
+		assert.Equal(t, original.RGBA64At(1, 1),
+			got.(*image.RGBA64).RGBA64At(0, 0),
+			"the 16-bit channels must be saved")
+
+		assert.True(t, editor.Undo())
+		assert.Equal(t, bounds, editor.destination64.Rect)
+	})
+
+	t.Run("8-bit modifier", func(t *testing.T) {
+		editor, err := NewImageEditor(bytes.NewReader(data))
+		assert.NoError(t, err)
+		editor.ModifyPixels(pixelModifier{mods.NewNegative()})
+		assert.Nil(t, editor.destination64,
+			"the modifier without the 16-bit variant must reduce the image")
+		assert.Equal(t, color.RGBA{0xed, 0xed, 0xed, 0xff},
+			editor.destination.RGBAAt(0, 0))
+	})
+}
+
+func TestImageEditor_Resize_deep(t *testing.T) {
+	bounds := image.Rect(0, 0, 4, 3)
+	original, data := newDeepPNG(bounds)
+	editor, err := NewImageEditor(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	editor.Resize(geom.NewSize(8, 6), resample.NearestNeighbor)
+	if !assert.NotNil(t, editor.destination64,
+		"the 16-bit image must be kept") {
+		return
+	}
+	assert.Equal(t, image.Rect(0, 0, 8, 6), editor.destination64.Rect)
+	assert.Equal(t, original.RGBA64At(2, 1),
+		editor.destination64.RGBA64At(5, 3))
+
+	editor.Resize(geom.NewSize(4, 3), resample.Bilinear)
+	if !assert.NotNil(t, editor.destination64) {
+		return
+	}
+	// A channel reduced to 8 bits has equal high and low bytes.
+	red := editor.destination64.RGBA64At(1, 1).R
+	assert.NotEqual(t, red>>8, red&0xff,
+		"the low bytes of the channels must be kept")
+}
+
+// pixelModifier hides the ModifyRow method of a modifier, so its pixels are
+// modified one by one.
+type pixelModifier struct {
+	modifier mods.PixelModifier
+}
+
+func (modifier pixelModifier) ModifyPixel(position image.Point,
+	col color.RGBA, src image.Image) color.RGBA {
+	return modifier.modifier.ModifyPixel(position, col, src)
+}
+
+// BenchmarkImageEditor_ModifyPixels compares modifying a large decoded JPEG
+// pixel by pixel with modifying the packed rows of its RGBA copy.
+func BenchmarkImageEditor_ModifyPixels(b *testing.B) {
+	bounds := image.Rect(0, 0, 3000, 2000)
+	src := image.NewYCbCr(bounds, image.YCbCrSubsampleRatio420)
+	for i := range src.Y {
+		src.Y[i] = uint8(i)
+	}
+	for i := range src.Cb {
+		src.Cb[i], src.Cr[i] = uint8(i*3), uint8(i*7)
+	}
+
+	modifiers := map[string]mods.PixelModifier{
+		"negative": mods.NewNegative(),
+		"gamma":    mods.NewGamma(2.2),
+	}
+	for name, modifier := range modifiers {
+		b.Run(name+"/pixels", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				mods.ModifyPixels(pixelModifier{modifier}, src, bounds)
+			}
+		})
+		b.Run(name+"/rows", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				editor := ImageEditor{
+					source:      src,
+					destination: image.NewRGBA(bounds),
+				}
+				editor.ModifyPixels(modifier)
+			}
+		})
+	}
+}
+
 func TestImageEditor_Encode(t *testing.T) {
 	type args struct {
 		mimeType string
@@ -485,8 +835,8 @@ func TestImageEditor_Encode(t *testing.T) {
 		},
 		{
 			name:    "encoding gif type",
-			args:    args{"image/gif"},
-			wantErr: true,
+			args:    args{MIMEGIF},
+			wantErr: false,
 		},
 		{
 			name:    "encoding invalid type",
@@ -521,6 +871,182 @@ func TestImageEditor_Encode(t *testing.T) {
 	}
 }
 
+func TestImageEditor_Encode_metadata(t *testing.T) {
+	// The EXIF data contains the Make and the Copyright tags.
+	metadata := &meta.Metadata{
+		EXIF: []byte("II*\x00\x08\x00\x00\x00\x02\x00" +
+			"\x0f\x01\x02\x00\x04\x00\x00\x00Cam\x00" +
+			"\x98\x82\x02\x00\x04\x00\x00\x00(c)\x00\x00\x00\x00\x00"),
+		ICC:  []byte("icc profile"),
+		XMP:  []byte("<xmp/>"),
+	}
+
+	tests := []struct {
+		name    string
+		options *EncodeOptions
+		want    *meta.Metadata
+	}{
+		{
+			name:    "nil options",
+			options: nil,
+			want:    &meta.Metadata{},
+		},
+		{
+			name:    "strip",
+			options: &EncodeOptions{Metadata: METADATA_STRIP},
+			want:    &meta.Metadata{},
+		},
+		{
+			name:    "keep",
+			options: &EncodeOptions{Metadata: METADATA_KEEP},
+			want:    metadata,
+		},
+		{
+			name:    "keep-icc",
+			options: &EncodeOptions{Metadata: METADATA_KEEP_ICC},
+			want:    &meta.Metadata{ICC: metadata.ICC},
+		},
+		{
+			name:    "keep-copyright",
+			options: &EncodeOptions{Metadata: METADATA_KEEP_COPYRIGHT},
+			want: &meta.Metadata{
+				EXIF: []byte("II*\x00\x08\x00\x00\x00\x01\x00" +
+					"\x98\x82\x02\x00\x04\x00\x00\x00(c)\x00\x00\x00\x00\x00"),
+				ICC: metadata.ICC,
+			},
+		},
+	}
+	for _, mimeType := range []string{MIMEJPEG, MIMEPNG} {
+		for _, test := range tests {
+			t.Run(mimeType+" "+test.name, func(t *testing.T) {
+				bounds := image.Rect(0, 0, 3, 3)
+				editor := &ImageEditor{
+					source:      image.NewRGBA(bounds),
+					destination: image.NewRGBA(bounds),
+					metadata:    metadata,
+				}
+
+				buffer, err := editor.BytesBufferWithOptions(mimeType, test.options)
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.Equal(t, test.want, meta.Read(buffer.Bytes()),
+					"incorrect metadata of the encoded image")
+
+				_, format, err := image.Decode(buffer)
+				assert.NoError(t, err, "the encoded image must be decodable")
+				assert.Equal(t, mimeType, "image/"+format)
+			})
+		}
+	}
+}
+
+// newNoiseImage creates an image filled with pseudo-random colors.
+func newNoiseImage(bounds image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(bounds)
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = uint8(seed >> 24)
+		if i%4 == 3 {
+			img.Pix[i] = 255
+		}
+	}
+	return img
+}
+
+func TestImageEditor_Encode_options(t *testing.T) {
+	source := newNoiseImage(image.Rect(0, 0, 64, 64))
+	editor := &ImageEditor{
+		source:      source,
+		destination: image.NewRGBA(source.Rect),
+	}
+
+	encodedLen := func(mimeType string, options *EncodeOptions) int {
+		buffer, err := editor.BytesBufferWithOptions(mimeType, options)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		_, _, err = image.Decode(bytes.NewReader(buffer.Bytes()))
+		assert.NoError(t, err, "the encoded image must be decodable")
+		return buffer.Len()
+	}
+
+	t.Run("jpeg quality", func(t *testing.T) {
+		low := encodedLen(MIMEJPEG, &EncodeOptions{Quality: 10})
+		high := encodedLen(MIMEJPEG, &EncodeOptions{Quality: 95})
+		assert.Less(t, low, high, "a lower quality must produce a smaller image")
+	})
+
+	t.Run("png compression", func(t *testing.T) {
+		editor.source = image.NewRGBA(source.Rect)
+		defer func() { editor.source = source }()
+
+		none := encodedLen(MIMEPNG, &EncodeOptions{Compression: png.NoCompression})
+		best := encodedLen(MIMEPNG, &EncodeOptions{Compression: png.BestCompression})
+		assert.Less(t, best, none, "a compressed image must be smaller")
+	})
+
+	t.Run("jpeg max bytes", func(t *testing.T) {
+		full := encodedLen(MIMEJPEG, &EncodeOptions{Quality: 90})
+		limit := full * 2 / 3
+		got := encodedLen(MIMEJPEG, &EncodeOptions{Quality: 90, MaxBytes: limit})
+		assert.LessOrEqual(t, got, limit, "the image must fit into MaxBytes")
+		assert.Greater(t, got, encodedLen(MIMEJPEG, &EncodeOptions{Quality: 1}),
+			"the highest fitting quality must be used")
+	})
+
+	t.Run("unreachable max bytes", func(t *testing.T) {
+		got := encodedLen(MIMEJPEG, &EncodeOptions{MaxBytes: 1})
+		want := encodedLen(MIMEJPEG, &EncodeOptions{Quality: 1})
+		assert.Equal(t, want, got, "the lowest quality must be used")
+	})
+}
+
+func TestImageEditor_Encode_flatten(t *testing.T) {
+	source := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	// The left half is transparent, the right half is opaque black.
+	for y := 0; y < 8; y++ {
+		for x := 4; x < 8; x++ {
+			source.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+
+	tests := []struct {
+		name       string
+		background color.Color
+		want       color.RGBA
+	}{
+		{"nil background", nil, color.RGBA{255, 255, 255, 255}},
+		{"red background", color.RGBA{255, 0, 0, 255}, color.RGBA{255, 0, 0, 255}},
+		{"transparent background", color.RGBA{}, color.RGBA{255, 255, 255, 255}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			editor := &ImageEditor{
+				source:      source,
+				destination: image.NewRGBA(source.Rect),
+			}
+			buffer, err := editor.BytesBufferWithOptions(MIMEJPEG,
+				&EncodeOptions{Quality: 100, Background: test.background})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			img, err := jpeg.Decode(buffer)
+			if !assert.NoError(t, err) {
+				return
+			}
+			transparent := color.RGBAModel.Convert(img.At(0, 0)).(color.RGBA)
+			opaque := color.RGBAModel.Convert(img.At(7, 7)).(color.RGBA)
+			assert.InDelta(t, test.want.R, transparent.R, 8, "incorrect background")
+			assert.InDelta(t, test.want.G, transparent.G, 8, "incorrect background")
+			assert.InDelta(t, test.want.B, transparent.B, 8, "incorrect background")
+			assert.InDelta(t, 0, opaque.R, 8, "opaque pixels must be kept")
+		})
+	}
+}
+
 func TestImageEditor_CropByRectangle(t *testing.T) {
 
 	tests := []struct {
@@ -693,6 +1219,163 @@ func TestImageEditor_CropBySizeAndAlignment(t *testing.T) {
 	}
 }
 
+func TestImageEditor_Resize(t *testing.T) {
+	tests := []struct {
+		name          string
+		imageBounds   image.Rectangle
+		size          geom.Size
+		wantBounds    image.Rectangle
+		wantIsResized bool
+	}{
+		{
+			name:          "empty size",
+			imageBounds:   image.Rect(0, 0, 10, 10),
+			size:          geom.NewSize(0, 0),
+			wantBounds:    image.Rect(0, 0, 10, 10),
+			wantIsResized: false,
+		},
+		{
+			name:          "same size",
+			imageBounds:   image.Rect(2, 2, 12, 12),
+			size:          geom.NewSize(10, 10),
+			wantBounds:    image.Rect(2, 2, 12, 12),
+			wantIsResized: false,
+		},
+		{
+			name:          "downscaling",
+			imageBounds:   image.Rect(2, 2, 12, 12),
+			size:          geom.NewSize(5, 3),
+			wantBounds:    image.Rect(0, 0, 5, 3),
+			wantIsResized: true,
+		},
+		{
+			name:          "upscaling",
+			imageBounds:   image.Rect(0, 0, 10, 10),
+			size:          geom.NewSize(20, 30),
+			wantBounds:    image.Rect(0, 0, 20, 30),
+			wantIsResized: true,
+		},
+		{
+			name:          "zero width",
+			imageBounds:   image.Rect(0, 0, 20, 10),
+			size:          geom.NewSize(0, 5),
+			wantBounds:    image.Rect(0, 0, 10, 5),
+			wantIsResized: true,
+		},
+		{
+			name:          "zero height",
+			imageBounds:   image.Rect(0, 0, 20, 10),
+			size:          geom.NewSize(5, 0),
+			wantBounds:    image.Rect(0, 0, 5, 3),
+			wantIsResized: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			editor := &ImageEditor{
+				source:      image.NewRGBA(test.imageBounds),
+				destination: image.NewRGBA(test.imageBounds),
+			}
+			editor.Resize(test.size, resample.Bilinear)
+
+			assert.Equal(t, test.wantBounds, editor.EditedImage().Bounds(),
+				"incorrect bounds of the edited image.\nimageBounds %v, size %v",
+				test.imageBounds, test.size)
+			assert.Equal(t, test.wantIsResized, editor.IsModifiedImage(),
+				"incorrect value of ImageEditor.IsModifiedImage().\nimageBounds %v, size %v",
+				test.imageBounds, test.size)
+		})
+	}
+}
+
+func TestImageEditor_ResizeToBox(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	tests := []struct {
+		name        string
+		imageBounds image.Rectangle
+		box         geom.Size
+		fit         string
+		alignment   geom.Alignment
+		wantBounds  image.Rectangle
+		// wantColors maps points of the edited image to the expected colors.
+		wantColors map[image.Point]color.RGBA
+	}{
+		{
+			name:        "empty box",
+			imageBounds: image.Rect(0, 0, 8, 4),
+			box:         geom.NewSize(0, 3),
+			fit:         geom.FIT,
+			alignment:   geom.DefaultAlignment,
+			wantBounds:  image.Rect(0, 0, 8, 4),
+		},
+		{
+			name:        "fill",
+			imageBounds: image.Rect(0, 0, 8, 4),
+			box:         geom.NewSize(4, 3),
+			fit:         geom.FILL,
+			alignment:   geom.DefaultAlignment,
+			wantBounds:  image.Rect(0, 0, 4, 3),
+			wantColors:  map[image.Point]color.RGBA{{0, 0}: red, {3, 2}: red},
+		},
+		{
+			name:        "fit centered",
+			imageBounds: image.Rect(0, 0, 8, 4),
+			box:         geom.NewSize(4, 4),
+			fit:         geom.FIT,
+			alignment:   geom.DefaultAlignment,
+			wantBounds:  image.Rect(0, 0, 4, 4),
+			wantColors: map[image.Point]color.RGBA{
+				{0, 0}: blue, {0, 1}: red, {3, 2}: red, {3, 3}: blue,
+			},
+		},
+		{
+			name:        "fit to the top",
+			imageBounds: image.Rect(2, 2, 10, 6),
+			box:         geom.NewSize(4, 4),
+			fit:         geom.FIT,
+			alignment:   geom.NewAlignment(geom.CENTER, geom.TOP),
+			wantBounds:  image.Rect(0, 0, 4, 4),
+			wantColors: map[image.Point]color.RGBA{
+				{0, 0}: red, {3, 1}: red, {0, 2}: blue, {3, 3}: blue,
+			},
+		},
+		{
+			name:        "cover",
+			imageBounds: image.Rect(0, 0, 8, 4),
+			box:         geom.NewSize(3, 3),
+			fit:         geom.COVER,
+			alignment:   geom.NewAlignment(geom.LEFT, geom.CENTER),
+			wantBounds:  image.Rect(0, 0, 3, 3),
+			wantColors:  map[image.Point]color.RGBA{{0, 0}: red, {2, 2}: red},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := image.NewRGBA(test.imageBounds)
+			for i := 0; i < len(source.Pix); i += 4 {
+				copy(source.Pix[i:i+4], []uint8{red.R, red.G, red.B, red.A})
+			}
+			editor := &ImageEditor{
+				source:      source,
+				destination: image.NewRGBA(test.imageBounds),
+			}
+			editor.ResizeToBox(test.box, test.fit, test.alignment,
+				resample.NearestNeighbor, blue)
+
+			got := editor.EditedImage()
+			assert.Equal(t, test.wantBounds, got.Bounds(),
+				"incorrect bounds of the edited image.\nimageBounds %v, box %v, fit %q",
+				test.imageBounds, test.box, test.fit)
+			for point, want := range test.wantColors {
+				assert.Equal(t, want, color.RGBAModel.Convert(got.At(point.X, point.Y)),
+					"incorrect color at %v", point)
+			}
+		})
+	}
+}
+
 func TestImageEditor_BytesBuffer(t *testing.T) {
 	type args struct {
 		mimeType string
@@ -714,8 +1397,8 @@ func TestImageEditor_BytesBuffer(t *testing.T) {
 		},
 		{
 			name:    "encoding gif type",
-			args:    args{"image/gif"},
-			wantErr: true,
+			args:    args{MIMEGIF},
+			wantErr: false,
 		},
 		{
 			name:    "encoding invalid type",
@@ -828,3 +1511,73 @@ func TestImageEditor_sizeAndAlignmentToRectangle(t *testing.T) {
 		})
 	}
 }
+
+// operationFunc is an ImageOperation defined by a function.
+type operationFunc func(src *image.RGBA) (*image.RGBA, error)
+
+func (operation operationFunc) Apply(src *image.RGBA) (*image.RGBA, error) {
+	return operation(src)
+}
+
+func TestImageEditor_ApplyOperation(t *testing.T) {
+	bounds := image.Rect(0, 0, 4, 3)
+	editor := &ImageEditor{
+		source:      newNumberedImage(bounds),
+		destination: image.NewRGBA(bounds),
+	}
+	editor.EnableHistory(DEFAULT_HISTORY_BUDGET)
+
+	// The operation gets the cropped image and can change its size.
+	editor.CropByRectangle(image.Rect(1, 1, 3, 3))
+	err := editor.ApplyOperation(operationFunc(
+		func(src *image.RGBA) (*image.RGBA, error) {
+			assert.Equal(t, image.Rect(1, 1, 3, 3), src.Bounds())
+			assert.Equal(t, []uint8{6, 7, 10, 11}, redChannels(src))
+			return image.NewRGBA(image.Rect(0, 0, 5, 1)), nil
+		}))
+	assert.NoError(t, err)
+	assert.Equal(t, geom.NewSize(5, 1), editor.Size())
+	assert.True(t, editor.IsModifiedImage())
+
+	assert.True(t, editor.Undo(), "the operation must be recorded")
+	assert.Equal(t, geom.NewSize(2, 2), editor.Size())
+
+	err = editor.ApplyOperation(operationFunc(
+		func(src *image.RGBA) (*image.RGBA, error) {
+			return nil, errors.New("failed")
+		}))
+	assert.Error(t, err)
+	assert.Equal(t, []uint8{6, 7, 10, 11}, redChannels(editor.EditedImage()),
+		"a failed operation must not change the image")
+
+	assert.NoError(t, editor.ApplyOperation(
+		mods.NewPixelOperation(mods.NewNegative())))
+	assert.Equal(t, []uint8{249, 248, 245, 244},
+		redChannels(editor.EditedImage()))
+}
+
+func TestImageEditor_ApplyOperationContext(t *testing.T) {
+	bounds := image.Rect(0, 0, 4, 3)
+	editor := &ImageEditor{
+		source:      newNumberedImage(bounds),
+		destination: image.NewRGBA(bounds),
+	}
+	editor.EnableHistory(DEFAULT_HISTORY_BUDGET)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := editor.ApplyOperationContext(ctx, operationFunc(
+		func(src *image.RGBA) (*image.RGBA, error) {
+			calls++
+			return src, nil
+		}))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, calls, "the operation must not be applied")
+
+	err = editor.ApplyOperationContext(ctx, mods.NewAutoLevels(0))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, editor.IsModifiedImage())
+	assert.False(t, editor.CanUndo(),
+		"the cancelled change must not be recorded")
+}
@@ -0,0 +1,106 @@
+package imageEditor
+
+import (
+	"image/color"
+	"image/jpeg"
+	"image/png"
+)
+
+// Metadata modes
+const (
+	// METADATA_STRIP removes all metadata from the encoded image.
+	METADATA_STRIP = "strip"
+	// METADATA_KEEP keeps the EXIF, ICC and XMP data of the original image.
+	// The EXIF orientation is reset if the image has been oriented.
+	METADATA_KEEP = "keep"
+	// METADATA_KEEP_ICC keeps only the ICC color profile.
+	METADATA_KEEP_ICC = "keep-icc"
+	// METADATA_KEEP_COPYRIGHT keeps the ICC color profile and the EXIF
+	// Orientation, Artist and Copyright tags. The other data, such as the GPS
+	// position, the camera details and the XMP packet, is removed.
+	METADATA_KEEP_COPYRIGHT = "keep-copyright"
+)
+
+const DEFAULT_METADATA = METADATA_STRIP
+
+// ValidateMetadata checks whether a string value is a valid metadata mode.
+// Valid values are "strip", "keep", "keep-icc" and "keep-copyright".
+func ValidateMetadata(metadata string) bool {
+	switch metadata {
+	case METADATA_STRIP, METADATA_KEEP, METADATA_KEEP_ICC,
+		METADATA_KEEP_COPYRIGHT:
+		return true
+	default:
+		return false
+	}
+}
+
+// PNG compression levels
+const (
+	COMPRESSION_DEFAULT = "default"
+	COMPRESSION_NONE    = "none"
+	COMPRESSION_FAST    = "fast"
+	COMPRESSION_BEST    = "best"
+)
+
+// CompressionLevel returns the PNG compression level with the given name. An
+// empty name returns the default level. The second value reports whether the
+// level exists.
+func CompressionLevel(name string) (png.CompressionLevel, bool) {
+	switch name {
+	case "", COMPRESSION_DEFAULT:
+		return png.DefaultCompression, true
+	case COMPRESSION_NONE:
+		return png.NoCompression, true
+	case COMPRESSION_FAST:
+		return png.BestSpeed, true
+	case COMPRESSION_BEST:
+		return png.BestCompression, true
+	default:
+		return png.DefaultCompression, false
+	}
+}
+
+// DecodeOptions are the parameters of decoding an image.
+type DecodeOptions struct {
+	// IgnoreOrientation disables the correction of the image orientation
+	// according to the EXIF Orientation tag.
+	IgnoreOrientation bool
+}
+
+// EncodeOptions are the parameters of encoding an image. JPEG images are
+// always encoded as baseline, because the standard encoder cannot write
+// progressive JPEG.
+type EncodeOptions struct {
+	// Metadata is the metadata mode. An empty value is treated as
+	// DEFAULT_METADATA.
+	Metadata string
+	// Quality is the JPEG quality from 1 to 100. Zero means the default
+	// quality, other values are clamped to the range.
+	Quality int
+	// Compression is the PNG compression level.
+	Compression png.CompressionLevel
+	// MaxBytes is the maximum size of a JPEG image in bytes. If it is
+	// positive, the highest quality not exceeding Quality that keeps the
+	// image within the size is used. If the image does not fit even with the
+	// lowest quality, it is encoded with the lowest quality.
+	MaxBytes int
+	// Background is the color that replaces transparency in formats without
+	// an alpha channel, such as JPEG. A nil value means white.
+	Background color.Color
+}
+
+// jpegQuality returns the JPEG quality of the options in the range from 1 to
+// 100.
+func (options *EncodeOptions) jpegQuality() int {
+	switch {
+	case options.Quality == 0:
+		return jpeg.DefaultQuality
+	case options.Quality < 1:
+		return 1
+	case options.Quality > 100:
+		return 100
+	default:
+		return options.Quality
+	}
+}
@@ -0,0 +1,78 @@
+package imageEditor
+
+import (
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata string
+		want     bool
+	}{
+		{"correct metadata", METADATA_STRIP, true},
+		{"correct metadata", METADATA_KEEP, true},
+		{"correct metadata", METADATA_KEEP_ICC, true},
+		{"correct metadata", METADATA_KEEP_COPYRIGHT, true},
+		{"incorrect metadata", "beleberda", false},
+		{"empty metadata", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ValidateMetadata(test.metadata)
+			assert.Equal(t, test.want, got, "ValidateMetadata(%q) = %t, want %t",
+				test.metadata, got, test.want)
+		})
+	}
+}
+
+func TestCompressionLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		level    string
+		want     png.CompressionLevel
+		wantIsOk bool
+	}{
+		{"empty level", "", png.DefaultCompression, true},
+		{"default level", COMPRESSION_DEFAULT, png.DefaultCompression, true},
+		{"no compression", COMPRESSION_NONE, png.NoCompression, true},
+		{"fast compression", COMPRESSION_FAST, png.BestSpeed, true},
+		{"best compression", COMPRESSION_BEST, png.BestCompression, true},
+		{"incorrect level", "beleberda", png.DefaultCompression, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := CompressionLevel(test.level)
+			assert.Equal(t, test.wantIsOk, ok, "CompressionLevel(%q)", test.level)
+			assert.Equal(t, test.want, got, "CompressionLevel(%q) = %d, want %d",
+				test.level, got, test.want)
+		})
+	}
+}
+
+func TestEncodeOptions_jpegQuality(t *testing.T) {
+	tests := []struct {
+		name    string
+		quality int
+		want    int
+	}{
+		{"zero quality", 0, jpeg.DefaultQuality},
+		{"normal quality", 42, 42},
+		{"minimum quality", 1, 1},
+		{"maximum quality", 100, 100},
+		{"negative quality", -5, 1},
+		{"too high quality", 500, 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := &EncodeOptions{Quality: test.quality}
+			got := options.jpegQuality()
+			assert.Equal(t, test.want, got, "jpegQuality() with Quality %d = %d, want %d",
+				test.quality, got, test.want)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package imageEditor
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Params stores the parameters of a pipeline step by name. The values are the
+// ones produced by decoding JSON or YAML: numbers, strings and booleans.
+type Params map[string]interface{}
+
+// Float returns the number parameter with the given name, or def if it is not
+// set. It returns an error if the parameter is not a finite number.
+func (params Params) Float(name string, def float64) (float64, error) {
+	value, ok := params[name]
+	if !ok {
+		return def, nil
+	}
+
+	var number float64
+	switch value := value.(type) {
+	case float64:
+		number = value
+	case float32:
+		number = float64(value)
+	case int:
+		number = float64(value)
+	case int64:
+		number = float64(value)
+	case uint64:
+		number = float64(value)
+	default:
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("%s must be a finite number", name)
+	}
+	return number, nil
+}
+
+// Int returns the integer parameter with the given name, or def if it is not
+// set. It returns an error if the parameter is not an integer.
+func (params Params) Int(name string, def int) (int, error) {
+	number, err := params.Float(name, float64(def))
+	if err != nil || number != math.Trunc(number) ||
+		math.Abs(number) > math.MaxInt32 {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return int(number), nil
+}
+
+// String returns the string parameter with the given name, or def if it is not
+// set. It returns an error if the parameter is not a string.
+func (params Params) String(name string, def string) (string, error) {
+	value, ok := params[name]
+	if !ok {
+		return def, nil
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", name)
+	}
+	return str, nil
+}
+
+// Bool returns the boolean parameter with the given name, or def if it is not
+// set. It returns an error if the parameter is not a boolean.
+func (params Params) Bool(name string, def bool) (bool, error) {
+	value, ok := params[name]
+	if !ok {
+		return def, nil
+	}
+
+	boolean, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean", name)
+	}
+	return boolean, nil
+}
+
+// Matrix returns the parameter with the given name that is a list of rows of
+// numbers, or nil if it is not set. It returns an error if the parameter is not
+// a list of lists of finite numbers.
+func (params Params) Matrix(name string) ([][]float64, error) {
+	value, ok := params[name]
+	if !ok {
+		return nil, nil
+	}
+
+	err := fmt.Errorf("%s must be a list of rows of numbers", name)
+	rows, ok := value.([]interface{})
+	if !ok {
+		return nil, err
+	}
+
+	matrix := make([][]float64, len(rows))
+	for i, row := range rows {
+		values, ok := row.([]interface{})
+		if !ok {
+			return nil, err
+		}
+		matrix[i] = make([]float64, len(values))
+		for j, value := range values {
+			number, numberErr := Params{name: value}.Float(name, 0)
+			if numberErr != nil {
+				return nil, err
+			}
+			matrix[i][j] = number
+		}
+	}
+	return matrix, nil
+}
+
+// Numbers returns the parameter with the given name that is a list of numbers,
+// or nil if it is not set. It returns an error if the parameter is not a list
+// of finite numbers.
+func (params Params) Numbers(name string) ([]float64, error) {
+	value, ok := params[name]
+	if !ok {
+		return nil, nil
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of numbers", name)
+	}
+
+	numbers := make([]float64, len(values))
+	for i, value := range values {
+		number, err := Params{name: value}.Float(name, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a list of numbers", name)
+		}
+		numbers[i] = number
+	}
+	return numbers, nil
+}
+
+// Color returns the color parameter with the given name in the format accepted
+// by ParseHexColor. It returns a transparent color if the parameter is not set.
+func (params Params) Color(name string) (color.NRGBA, error) {
+	str, err := params.String(name, "")
+	if err != nil {
+		return color.NRGBA{}, err
+	}
+
+	col, err := ParseHexColor(str)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("%s must be a color: %w", name, err)
+	}
+	return col, nil
+}
+
+// ParseHexColor converts a string in the "#rgb", "#rrggbb" or "#rrggbbaa" format
+// to a color. The leading "#" is optional. If the input string is empty, it
+// returns a transparent color. Returns an error if the string is not a color.
+func ParseHexColor(str string) (color.NRGBA, error) {
+	if str == "" {
+		return color.NRGBA{}, nil
+	}
+
+	hex := strings.TrimPrefix(str, "#")
+	switch len(hex) {
+	case 3:
+		hex = string([]byte{
+			hex[0], hex[0], hex[1], hex[1], hex[2], hex[2], 'f', 'f',
+		})
+	case 6:
+		hex += "ff"
+	case 8:
+	default:
+		return color.NRGBA{}, fmt.Errorf("%q is not a color", str)
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("%q is not a color", str)
+	}
+
+	return color.NRGBA{
+		uint8(value >> 24),
+		uint8(value >> 16),
+		uint8(value >> 8),
+		uint8(value),
+	}, nil
+}
@@ -0,0 +1,186 @@
+package imageEditor
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParams_Float(t *testing.T) {
+	params := Params{
+		"float":    1.5,
+		"int":      3,
+		"string":   "1",
+		"infinity": math.Inf(1),
+	}
+	tests := []struct {
+		name    string
+		param   string
+		want    float64
+		wantErr bool
+	}{
+		{"float value", "float", 1.5, false},
+		{"int value", "int", 3, false},
+		{"missing value", "missing", 7, false},
+		{"string value", "string", 0, true},
+		{"infinite value", "infinity", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := params.Float(test.param, 7)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestParams_Int(t *testing.T) {
+	params := Params{"float": 2.0, "fraction": 2.5, "int": 4}
+	tests := []struct {
+		name    string
+		param   string
+		want    int
+		wantErr bool
+	}{
+		{"integral float value", "float", 2, false},
+		{"int value", "int", 4, false},
+		{"missing value", "missing", 7, false},
+		{"fractional value", "fraction", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := params.Int(test.param, 7)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestParams_String(t *testing.T) {
+	params := Params{"string": "top", "number": 1}
+
+	got, err := params.String("string", "center")
+	assert.NoError(t, err)
+	assert.Equal(t, "top", got)
+
+	got, err = params.String("missing", "center")
+	assert.NoError(t, err)
+	assert.Equal(t, "center", got)
+
+	_, err = params.String("number", "center")
+	assert.Error(t, err)
+}
+
+func TestParams_Bool(t *testing.T) {
+	params := Params{"bool": false, "string": "false"}
+
+	got, err := params.Bool("bool", true)
+	assert.NoError(t, err)
+	assert.False(t, got)
+
+	got, err = params.Bool("missing", true)
+	assert.NoError(t, err)
+	assert.True(t, got)
+
+	_, err = params.Bool("string", true)
+	assert.Error(t, err)
+}
+
+func TestParams_Matrix(t *testing.T) {
+	params := Params{
+		"matrix": []interface{}{
+			[]interface{}{1, 2.5},
+			[]interface{}{-1, 0},
+		},
+		"row":     []interface{}{1, 2},
+		"strings": []interface{}{[]interface{}{"1"}},
+	}
+
+	got, err := params.Matrix("matrix")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{1, 2.5}, {-1, 0}}, got)
+
+	got, err = params.Matrix("missing")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = params.Matrix("row")
+	assert.Error(t, err)
+
+	_, err = params.Matrix("strings")
+	assert.Error(t, err)
+}
+
+func TestParams_Numbers(t *testing.T) {
+	params := Params{
+		"numbers": []interface{}{1, -2.5, 0},
+		"number":  1,
+		"strings": []interface{}{"1"},
+	}
+
+	got, err := params.Numbers("numbers")
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, -2.5, 0}, got)
+
+	got, err = params.Numbers("missing")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = params.Numbers("number")
+	assert.Error(t, err)
+
+	_, err = params.Numbers("strings")
+	assert.Error(t, err)
+}
+
+func TestParams_Color(t *testing.T) {
+	params := Params{"color": "#f00", "invalid": "red"}
+
+	got, err := params.Color("color")
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{255, 0, 0, 255}, got)
+
+	got, err = params.Color("missing")
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{}, got)
+
+	_, err = params.Color("invalid")
+	assert.Error(t, err)
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		str     string
+		want    color.NRGBA
+		wantErr bool
+	}{
+		{"empty string", "", color.NRGBA{}, false},
+		{"short form", "#0f8", color.NRGBA{0, 255, 136, 255}, false},
+		{"long form", "#102030", color.NRGBA{16, 32, 48, 255}, false},
+		{"with alpha", "10203040", color.NRGBA{16, 32, 48, 64}, false},
+		{"wrong length", "#1234", color.NRGBA{}, true},
+		{"not hex", "#xyz", color.NRGBA{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseHexColor(test.str)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -0,0 +1,767 @@
+package imageEditor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/geom"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/mods"
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/resample"
+	"gopkg.in/yaml.v3"
+)
+
+// Operations of the pipeline steps
+const (
+	OP_CROP          = "crop"
+	OP_RESIZE        = "resize"
+	OP_ROTATE        = "rotate"
+	OP_FLIP          = "flip"
+	OP_GRAYSCALE     = "grayscale"
+	OP_NEGATIVE      = "negative"
+	OP_BLUR          = "blur"
+	OP_BRIGHTNESS    = "brightness"
+	OP_CONTRAST      = "contrast"
+	OP_GAMMA         = "gamma"
+	OP_EXPOSURE      = "exposure"
+	OP_SHARPEN       = "sharpen"
+	OP_EMBOSS        = "emboss"
+	OP_EDGE_DETECT   = "edge_detect"
+	OP_BOX_BLUR      = "box_blur"
+	OP_UNSHARP       = "unsharp_mask"
+	OP_CONVOLVE      = "convolve"
+	OP_AUTO_LEVELS   = "auto_levels"
+	OP_CHANNEL_MIXER = "channel_mixer"
+	OP_SEPIA         = "sepia"
+	OP_TINT          = "tint"
+	OP_DUOTONE       = "duotone"
+	OP_COLOR_BALANCE = "color_balance"
+	OP_HSL           = "hsl"
+)
+
+// Step is a single operation of a pipeline with its parameters. In JSON and
+// YAML a step is an object with the "op" field and the parameters as the other
+// fields, e.g. {"op": "blur", "sigma": 3}.
+type Step struct {
+	Op     string
+	Params Params
+}
+
+// NewStep creates a new Step with the given operation and parameters.
+func NewStep(op string, params Params) Step {
+	return Step{Op: op, Params: params}
+}
+
+// MarshalJSON encodes the step as a JSON object.
+func (step Step) MarshalJSON() ([]byte, error) {
+	return json.Marshal(step.fields())
+}
+
+// UnmarshalJSON decodes the step from a JSON object.
+func (step *Step) UnmarshalJSON(data []byte) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	return step.setFields(fields)
+}
+
+// MarshalYAML encodes the step as a YAML mapping.
+func (step Step) MarshalYAML() (interface{}, error) {
+	return step.fields(), nil
+}
+
+// UnmarshalYAML decodes the step from a YAML mapping.
+func (step *Step) UnmarshalYAML(value *yaml.Node) error {
+	var fields map[string]interface{}
+	if err := value.Decode(&fields); err != nil {
+		return err
+	}
+	return step.setFields(fields)
+}
+
+// fields returns the operation and the parameters as a single map.
+func (step Step) fields() map[string]interface{} {
+	fields := make(map[string]interface{}, len(step.Params)+1)
+	for name, value := range step.Params {
+		fields[name] = value
+	}
+	fields["op"] = step.Op
+	return fields
+}
+
+// setFields sets the operation and the parameters from a single map.
+func (step *Step) setFields(fields map[string]interface{}) error {
+	op, ok := fields["op"].(string)
+	if !ok {
+		return errors.New(`step must have a string "op" field`)
+	}
+
+	step.Op = op
+	step.Params = make(Params, len(fields)-1)
+	for name, value := range fields {
+		if name != "op" {
+			step.Params[name] = value
+		}
+	}
+	return nil
+}
+
+// Validate checks that the operation exists and its parameters are correct.
+func (step Step) Validate() error {
+	_, err := step.build()
+	return err
+}
+
+// build returns the function that applies the step to an editor.
+func (step Step) build() (stepFunc, error) {
+	operation, ok := operations[step.Op]
+	if !ok {
+		return nil, fmt.Errorf("unknown operation %q", step.Op)
+	}
+
+	for name := range step.Params {
+		if !operation.hasParam(name) {
+			return nil, fmt.Errorf("%s: unknown parameter %q", step.Op, name)
+		}
+	}
+
+	apply, err := operation.build(step.Params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", step.Op, err)
+	}
+	return apply, nil
+}
+
+// Pipeline is an ordered list of steps that are applied to an image one after
+// another.
+type Pipeline []Step
+
+// ParsePipeline decodes the pipeline from a JSON or YAML list of steps and
+// validates it.
+func ParsePipeline(data []byte) (Pipeline, error) {
+	// JSON is a subset of YAML, so both formats are decoded as YAML.
+	var pipeline Pipeline
+	if err := yaml.Unmarshal(data, &pipeline); err != nil {
+		return nil, err
+	}
+
+	if err := pipeline.Validate(); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// Validate checks all steps of the pipeline.
+func (pipeline Pipeline) Validate() error {
+	_, err := pipeline.build()
+	return err
+}
+
+// Apply applies all steps to the editor in order. The steps are validated
+// before the first one is applied, and the image is restored if a step fails,
+// so an incorrect pipeline leaves the image unchanged. The whole pipeline is a
+// single change in the editor history.
+func (pipeline Pipeline) Apply(editor *ImageEditor) error {
+	return pipeline.ApplyContext(context.Background(), editor)
+}
+
+// ApplyContext is like Apply, but it stops when the context is done and
+// returns the error of the context, in which case the image is also restored.
+func (pipeline Pipeline) ApplyContext(ctx context.Context,
+	editor *ImageEditor) error {
+	steps, err := pipeline.build()
+	if err != nil {
+		return err
+	}
+
+	defer editor.track()()
+	before := editor.state()
+	for i, apply := range steps {
+		err := ctx.Err()
+		if err == nil {
+			err = apply(ctx, editor)
+		}
+		if err != nil {
+			editor.restore(before)
+			return fmt.Errorf("step %d: %s: %w", i+1, pipeline[i].Op, err)
+		}
+	}
+	return nil
+}
+
+// build returns the functions that apply the steps to an editor.
+func (pipeline Pipeline) build() ([]stepFunc, error) {
+	steps := make([]stepFunc, len(pipeline))
+	for i, step := range pipeline {
+		apply, err := step.build()
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		steps[i] = apply
+	}
+	return steps, nil
+}
+
+// stepFunc applies a step to an editor.
+type stepFunc func(ctx context.Context, editor *ImageEditor) error
+
+// operation describes how a step is turned into an editor call.
+type operation struct {
+	// params are the names of the accepted parameters.
+	params []string
+	// build checks the parameters and returns the function that applies the
+	// operation to an editor.
+	build func(params Params) (stepFunc, error)
+}
+
+// hasParam reports whether the operation accepts the parameter.
+func (operation operation) hasParam(name string) bool {
+	for _, param := range operation.params {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
+
+// operations are the operations of the pipeline steps by name.
+var operations = map[string]operation{
+	OP_CROP: {
+		params: []string{"width", "height", "vertical", "horizontal"},
+		build:  buildCrop,
+	},
+	OP_RESIZE: {
+		params: []string{"width", "height", "resample", "fit", "vertical",
+			"horizontal", "background"},
+		build: buildResize,
+	},
+	OP_ROTATE: {
+		params: []string{"degrees", "background", "expand"},
+		build:  buildRotate,
+	},
+	OP_FLIP: {
+		params: []string{"direction"},
+		build:  buildFlip,
+	},
+	OP_GRAYSCALE: {
+		params: []string{"mode"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			mode, err := params.String("mode", mods.DEFAULT_GRAYSCALE)
+			if err != nil {
+				return nil, err
+			}
+			grayscale, err := mods.NewGrayscaleWithMode(mode)
+			if err != nil {
+				return nil, err
+			}
+			return grayscale, nil
+		}),
+	},
+	OP_NEGATIVE: {
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			return mods.NewNegative(), nil
+		}),
+	},
+	OP_BLUR: {
+		params: []string{"sigma", "edge", "linear"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			sigma, err := sigmaParam(params)
+			if err != nil {
+				return nil, err
+			}
+			options, err := filterParams(params)
+			if err != nil {
+				return nil, err
+			}
+			return mods.NewGaussianBlurWithOptions(sigma, options), nil
+		}),
+	},
+	OP_BRIGHTNESS: {
+		params: []string{"amount"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			amount, err := params.Float("amount", 0)
+			if err != nil || amount < -1 || amount > 1 {
+				return nil, errors.New("amount must be a number from -1 to 1")
+			}
+			return mods.NewBrightness(amount), nil
+		}),
+	},
+	OP_CONTRAST: {
+		params: []string{"amount"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			amount, err := params.Float("amount", 0)
+			if err != nil || amount < -1 || amount > 1 {
+				return nil, errors.New("amount must be a number from -1 to 1")
+			}
+			return mods.NewContrast(amount), nil
+		}),
+	},
+	OP_GAMMA: {
+		params: []string{"gamma"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			gamma, err := params.Float("gamma", 1)
+			if err != nil || !(gamma >= mods.MIN_GAMMA &&
+				gamma <= mods.MAX_GAMMA) {
+				return nil, fmt.Errorf("gamma must be a number from %v to %v",
+					mods.MIN_GAMMA, mods.MAX_GAMMA)
+			}
+			return mods.NewGamma(gamma), nil
+		}),
+	},
+	OP_EXPOSURE: {
+		params: []string{"stops"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			stops, err := params.Float("stops", 0)
+			if err != nil || !(stops >= -mods.MAX_EXPOSURE_STOPS &&
+				stops <= mods.MAX_EXPOSURE_STOPS) {
+				return nil, fmt.Errorf("stops must be a number from %v to %v",
+					-mods.MAX_EXPOSURE_STOPS, mods.MAX_EXPOSURE_STOPS)
+			}
+			return mods.NewExposure(stops), nil
+		}),
+	},
+	OP_SHARPEN: {
+		params: []string{"edge", "linear"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			options, err := filterParams(params)
+			if err != nil {
+				return nil, err
+			}
+			return mods.NewSharpen(options), nil
+		}),
+	},
+	OP_EMBOSS: {
+		params: []string{"edge", "linear"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			options, err := filterParams(params)
+			if err != nil {
+				return nil, err
+			}
+			return mods.NewEmboss(options), nil
+		}),
+	},
+	OP_EDGE_DETECT: {
+		params: []string{"edge", "linear"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			options, err := filterParams(params)
+			if err != nil {
+				return nil, err
+			}
+			return mods.NewEdgeDetect(options), nil
+		}),
+	},
+	OP_BOX_BLUR: {
+		params: []string{"radius", "edge", "linear"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			radius, err := params.Int("radius", 1)
+			if err != nil || radius < 1 {
+				return nil, errors.New("radius must be a positive integer")
+			}
+			options, err := filterParams(params)
+			if err != nil {
+				return nil, err
+			}
+			return mods.NewBoxBlur(radius, options), nil
+		}),
+	},
+	OP_UNSHARP: {
+		params: []string{"sigma", "amount", "threshold", "edge",
+			"linear"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			sigma, err := sigmaParam(params)
+			if err != nil {
+				return nil, err
+			}
+			amount, err := params.Float("amount", 1)
+			if err != nil || amount < 0 {
+				return nil, errors.New("amount must be a non-negative number")
+			}
+			threshold, err := params.Float("threshold", 0)
+			if err != nil || threshold < 0 || threshold > 1 {
+				return nil, errors.New("threshold must be a number from 0 to 1")
+			}
+			options, err := filterParams(params)
+			if err != nil {
+				return nil, err
+			}
+			return mods.NewUnsharpMask(sigma, amount, threshold, options), nil
+		}),
+	},
+	OP_CONVOLVE: {
+		params: []string{"kernel", "divisor", "bias", "edge",
+			"preserve_alpha", "linear"},
+		build: modifierBuilder(buildConvolution),
+	},
+	OP_AUTO_LEVELS: {
+		params: []string{"clip"},
+		build: operationBuilder(func(params Params) (mods.ImageOperation, error) {
+			clip, err := params.Float("clip", 0.005)
+			if err != nil || clip < 0 || clip > 0.5 {
+				return nil, errors.New("clip must be a number from 0 to 0.5")
+			}
+			return mods.NewAutoLevels(clip), nil
+		}),
+	},
+	OP_CHANNEL_MIXER: {
+		params: []string{"matrix"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			matrix, err := params.Matrix("matrix")
+			if err != nil {
+				return nil, err
+			}
+			if matrix == nil {
+				return nil, errors.New("matrix is required")
+			}
+			return mods.NewChannelMixer(matrix)
+		}),
+	},
+	OP_SEPIA: {
+		params: []string{"strength"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			strength, err := params.Float("strength", 1)
+			if err != nil || strength < 0 || strength > 1 {
+				return nil, errors.New("strength must be a number from 0 to 1")
+			}
+			return mods.NewSepia(strength), nil
+		}),
+	},
+	OP_TINT: {
+		params: []string{"color", "amount"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			tint, err := requiredColor(params, "color")
+			if err != nil {
+				return nil, err
+			}
+			amount, err := params.Float("amount", 0.5)
+			if err != nil || amount < 0 || amount > 1 {
+				return nil, errors.New("amount must be a number from 0 to 1")
+			}
+			return mods.NewTint(tint, amount), nil
+		}),
+	},
+	OP_DUOTONE: {
+		params: []string{"shadows", "highlights"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			shadows, err := requiredColor(params, "shadows")
+			if err != nil {
+				return nil, err
+			}
+			highlights, err := requiredColor(params, "highlights")
+			if err != nil {
+				return nil, err
+			}
+			return mods.NewDuotone(shadows, highlights), nil
+		}),
+	},
+	OP_COLOR_BALANCE: {
+		params: []string{"shadows", "midtones", "highlights"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			var shifts [3][3]float64
+			for i, name := range []string{"shadows", "midtones", "highlights"} {
+				var err error
+				if shifts[i], err = balanceParam(params, name); err != nil {
+					return nil, err
+				}
+			}
+			return mods.NewColorBalance(shifts[0], shifts[1], shifts[2]), nil
+		}),
+	},
+	OP_HSL: {
+		params: []string{"hue", "saturation", "lightness", "target"},
+		build: modifierBuilder(func(params Params) (mods.PixelModifier, error) {
+			hue, err := params.Float("hue", 0)
+			if err != nil || hue < -180 || hue > 180 {
+				return nil, errors.New("hue must be a number from -180 to 180")
+			}
+			saturation, err := params.Float("saturation", 1)
+			if err != nil || saturation < 0 || saturation > 4 {
+				return nil, errors.New("saturation must be a number from 0 to 4")
+			}
+			lightness, err := params.Float("lightness", 1)
+			if err != nil || lightness < 0 || lightness > 4 {
+				return nil, errors.New("lightness must be a number from 0 to 4")
+			}
+			target, err := params.String("target", "")
+			if err != nil {
+				return nil, err
+			}
+			hues, ok := mods.HueRangeByName(target)
+			if !ok {
+				return nil, fmt.Errorf("unknown hue range %q", target)
+			}
+			return mods.NewHSLAdjust(hue, saturation, lightness, hues), nil
+		}),
+	},
+}
+
+// modifierBuilder returns the build function of an operation that applies a
+// PixelModifier.
+func modifierBuilder(newModifier func(params Params) (mods.PixelModifier,
+	error)) func(params Params) (stepFunc, error) {
+	return func(params Params) (stepFunc, error) {
+		modifier, err := newModifier(params)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, editor *ImageEditor) error {
+			return editor.ModifyPixelsContext(ctx, modifier)
+		}, nil
+	}
+}
+
+// operationBuilder returns the build function of an operation that applies an
+// ImageOperation.
+func operationBuilder(newOperation func(params Params) (mods.ImageOperation,
+	error)) func(params Params) (stepFunc, error) {
+	return func(params Params) (stepFunc, error) {
+		operation, err := newOperation(params)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, editor *ImageEditor) error {
+			return editor.ApplyOperationContext(ctx, operation)
+		}, nil
+	}
+}
+
+// buildConvolution builds the convolution with a custom kernel.
+func buildConvolution(params Params) (mods.PixelModifier, error) {
+	kernel, err := params.Matrix("kernel")
+	if err != nil {
+		return nil, err
+	}
+	if kernel == nil {
+		return nil, errors.New("kernel is required")
+	}
+
+	var options mods.ConvolutionOptions
+	if options.Divisor, err = params.Float("divisor", 0); err != nil {
+		return nil, err
+	}
+	if options.Bias, err = params.Float("bias", 0); err != nil {
+		return nil, err
+	}
+	if options.Edge, err = edgeParam(params); err != nil {
+		return nil, err
+	}
+	if options.PreserveAlpha, err = params.Bool("preserve_alpha",
+		false); err != nil {
+		return nil, err
+	}
+	if options.Linear, err = params.Bool("linear", false); err != nil {
+		return nil, err
+	}
+	return mods.NewConvolution(kernel, options)
+}
+
+// sigmaParam returns the sigma of a gaussian blur from the sigma parameter,
+// which is 2 by default.
+func sigmaParam(params Params) (float64, error) {
+	sigma, err := params.Float("sigma", 2)
+	if err != nil || sigma <= 0 || sigma > mods.MAX_SIGMA {
+		return 0, fmt.Errorf("sigma must be a positive number up to %v",
+			mods.MAX_SIGMA)
+	}
+	return sigma, nil
+}
+
+// edgeParam returns the edge mode from the edge parameter, or an empty string
+// for the default edge mode of the operation if it is not set.
+func edgeParam(params Params) (string, error) {
+	edge, err := params.String("edge", "")
+	if err != nil {
+		return "", err
+	}
+	if edge != "" && !mods.ValidateEdge(edge) {
+		return "", fmt.Errorf("unknown edge mode %q", edge)
+	}
+	return edge, nil
+}
+
+// filterParams returns the options of a neighbourhood filter from the edge and
+// linear parameters.
+func filterParams(params Params) (mods.FilterOptions, error) {
+	edge, err := edgeParam(params)
+	if err != nil {
+		return mods.FilterOptions{}, err
+	}
+	linear, err := params.Bool("linear", false)
+	if err != nil {
+		return mods.FilterOptions{}, err
+	}
+	return mods.FilterOptions{Edge: edge, Linear: linear}, nil
+}
+
+// requiredColor returns the color parameter with the given name, which must be
+// set.
+func requiredColor(params Params, name string) (color.NRGBA, error) {
+	if _, ok := params[name]; !ok {
+		return color.NRGBA{}, fmt.Errorf("%s is required", name)
+	}
+	return params.Color(name)
+}
+
+// balanceParam returns the red, green and blue shifts of a tonal range of the
+// color balance, which are zero if the parameter is not set.
+func balanceParam(params Params, name string) ([3]float64, error) {
+	var shifts [3]float64
+	numbers, err := params.Numbers(name)
+	if err != nil {
+		return shifts, err
+	}
+	if numbers == nil {
+		return shifts, nil
+	}
+
+	if len(numbers) != 3 {
+		return shifts, fmt.Errorf("%s must have 3 numbers", name)
+	}
+	for i, number := range numbers {
+		if number < -1 || number > 1 {
+			return shifts, fmt.Errorf("%s must be numbers from -1 to 1", name)
+		}
+		shifts[i] = number
+	}
+	return shifts, nil
+}
+
+// sizeParams returns the size from the width and height parameters. At least
+// one of them must be set.
+func sizeParams(params Params) (geom.Size, error) {
+	width, err := params.Int("width", 0)
+	if err != nil || width < 0 {
+		return geom.Size{}, errors.New("width must be a positive integer")
+	}
+	height, err := params.Int("height", 0)
+	if err != nil || height < 0 {
+		return geom.Size{}, errors.New("height must be a positive integer")
+	}
+
+	if width == 0 && height == 0 {
+		return geom.Size{}, errors.New("width or height is required")
+	}
+	return geom.NewSize(width, height), nil
+}
+
+// alignmentParams returns the alignment from the vertical and horizontal
+// parameters.
+func alignmentParams(params Params) (geom.Alignment, error) {
+	vertical, err := params.String("vertical", geom.DEFAULT_VERTICAL)
+	if err != nil || !geom.ValidateVertical(vertical) {
+		return geom.Alignment{}, errors.New("incorrect vertical value")
+	}
+	horizontal, err := params.String("horizontal", geom.DEFAULT_HORIZONTL)
+	if err != nil || !geom.ValidateHorizontal(horizontal) {
+		return geom.Alignment{}, errors.New("incorrect horizontal value")
+	}
+	return geom.NewAlignment(vertical, horizontal), nil
+}
+
+// buildCrop builds the crop operation.
+func buildCrop(params Params) (stepFunc, error) {
+	size, err := sizeParams(params)
+	if err != nil {
+		return nil, err
+	}
+	if size.IsEmpty() {
+		return nil, errors.New("width and height are required")
+	}
+	alignment, err := alignmentParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, editor *ImageEditor) error {
+		editor.CropBySizeAndAlignment(size, alignment)
+		return nil
+	}, nil
+}
+
+// buildResize builds the resize operation. Without the fit parameter the image
+// is resized to the size, otherwise it is scaled into the box.
+func buildResize(params Params) (stepFunc, error) {
+	size, err := sizeParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := params.String("resample", "")
+	if err != nil {
+		return nil, err
+	}
+	kernel, ok := resample.KernelByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown resample kernel %q", name)
+	}
+
+	fit, err := params.String("fit", "")
+	if err != nil {
+		return nil, err
+	}
+	if fit == "" {
+		return func(ctx context.Context, editor *ImageEditor) error {
+			editor.Resize(size, kernel)
+			return nil
+		}, nil
+	}
+
+	if !geom.ValidateFit(fit) {
+		return nil, fmt.Errorf("unknown fit %q", fit)
+	}
+	if size.IsEmpty() {
+		return nil, errors.New("width and height are required to fit the image")
+	}
+	alignment, err := alignmentParams(params)
+	if err != nil {
+		return nil, err
+	}
+	background, err := params.Color("background")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, editor *ImageEditor) error {
+		editor.ResizeToBox(size, fit, alignment, kernel, background)
+		return nil
+	}, nil
+}
+
+// buildRotate builds the rotate operation.
+func buildRotate(params Params) (stepFunc, error) {
+	degrees, err := params.Float("degrees", 0)
+	if err != nil {
+		return nil, err
+	}
+	background, err := params.Color("background")
+	if err != nil {
+		return nil, err
+	}
+	expand, err := params.Bool("expand", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, editor *ImageEditor) error {
+		editor.Rotate(degrees, background, expand)
+		return nil
+	}, nil
+}
+
+// buildFlip builds the flip operation.
+func buildFlip(params Params) (stepFunc, error) {
+	direction, err := params.String("direction", "")
+	if err != nil {
+		return nil, err
+	}
+	if direction != HORIZONTAL && direction != VERTICAL {
+		return nil, errors.New("direction must be horizontal or vertical")
+	}
+
+	return func(ctx context.Context, editor *ImageEditor) error {
+		editor.Flip(direction)
+		return nil
+	}, nil
+}
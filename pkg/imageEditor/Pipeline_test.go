@@ -0,0 +1,454 @@
+package imageEditor
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestStep_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Step
+		wantErr bool
+	}{
+		{
+			name: "step with parameters",
+			data: `{"op": "blur", "sigma": 3}`,
+			want: NewStep(OP_BLUR, Params{"sigma": 3.0}),
+		},
+		{
+			name: "step without parameters",
+			data: `{"op": "grayscale"}`,
+			want: NewStep(OP_GRAYSCALE, Params{}),
+		},
+		{
+			name:    "missing operation",
+			data:    `{"sigma": 3}`,
+			wantErr: true,
+		},
+		{
+			name:    "not an object",
+			data:    `"blur"`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got Step
+			err := json.Unmarshal([]byte(test.data), &got)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestStep_MarshalJSON(t *testing.T) {
+	step := NewStep(OP_RESIZE, Params{"width": 100, "fit": "cover"})
+	data, err := json.Marshal(step)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.JSONEq(t, `{"op": "resize", "width": 100, "fit": "cover"}`,
+		string(data))
+}
+
+func TestStep_MarshalYAML(t *testing.T) {
+	step := NewStep(OP_RESIZE, Params{"width": 100, "fit": "cover"})
+	data, err := yaml.Marshal(step)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var got Step
+	if assert.NoError(t, yaml.Unmarshal(data, &got)) {
+		assert.Equal(t, step, got)
+	}
+}
+
+func TestParsePipeline(t *testing.T) {
+	want := Pipeline{
+		NewStep(OP_CROP, Params{"width": 400, "height": 300}),
+		NewStep(OP_GRAYSCALE, Params{}),
+	}
+	tests := []struct {
+		name    string
+		data    string
+		want    Pipeline
+		wantErr bool
+	}{
+		{
+			name: "json",
+			data: `[{"op": "crop", "width": 400, "height": 300},
+				{"op": "grayscale"}]`,
+			want: want,
+		},
+		{
+			name: "yaml",
+			data: "- op: crop\n  width: 400\n  height: 300\n- op: grayscale\n",
+			want: want,
+		},
+		{
+			name:    "not a list",
+			data:    "op: crop",
+			wantErr: true,
+		},
+		{
+			name:    "invalid step",
+			data:    "- op: blur\n  sigma: -1\n",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParsePipeline([]byte(test.data))
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.want, got)
+			}
+		})
+	}
+}
+
+func TestPipeline_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline Pipeline
+		wantErr  bool
+	}{
+		{
+			name: "correct steps",
+			pipeline: Pipeline{
+				NewStep(OP_CROP, Params{"width": 10, "height": 10,
+					"vertical": "left"}),
+				NewStep(OP_RESIZE, Params{"width": 5, "height": 5,
+					"fit": "fit", "background": "#fff"}),
+				NewStep(OP_ROTATE, Params{"degrees": 45, "expand": false}),
+				NewStep(OP_FLIP, Params{"direction": HORIZONTAL}),
+				NewStep(OP_GRAYSCALE, nil),
+				NewStep(OP_GRAYSCALE, Params{"mode": "rec709"}),
+				NewStep(OP_NEGATIVE, nil),
+				NewStep(OP_BLUR, Params{"sigma": 1.5, "edge": "clamp"}),
+				NewStep(OP_BRIGHTNESS, Params{"amount": 0.5}),
+				NewStep(OP_CONTRAST, Params{"amount": -0.5}),
+				NewStep(OP_GAMMA, Params{"gamma": 2.2}),
+				NewStep(OP_EXPOSURE, Params{"stops": -1}),
+				NewStep(OP_SHARPEN, Params{"edge": "renormalize"}),
+				NewStep(OP_EMBOSS, nil),
+				NewStep(OP_EDGE_DETECT, nil),
+				NewStep(OP_BOX_BLUR, Params{"radius": 2, "edge": "wrap"}),
+				NewStep(OP_BOX_BLUR, Params{"linear": false}),
+				NewStep(OP_UNSHARP, Params{"sigma": 1, "amount": 0.5,
+					"threshold": 0.1, "linear": true}),
+				NewStep(OP_CONVOLVE, Params{
+					"kernel": []interface{}{
+						[]interface{}{0, 1, 0},
+						[]interface{}{1, -4.0, 1},
+						[]interface{}{0, 1, 0},
+					},
+					"bias": 0.5, "edge": "mirror", "preserve_alpha": true,
+					"linear": true,
+				}),
+				NewStep(OP_AUTO_LEVELS, Params{"clip": 0.01}),
+				NewStep(OP_CHANNEL_MIXER, Params{
+					"matrix": []interface{}{
+						[]interface{}{0, 0, 1, 0},
+						[]interface{}{0, 1, 0, 0},
+						[]interface{}{1, 0, 0, 0.1},
+					},
+				}),
+				NewStep(OP_SEPIA, Params{"strength": 0.8}),
+				NewStep(OP_TINT, Params{"color": "#ff8000", "amount": 0.2}),
+				NewStep(OP_DUOTONE, Params{
+					"shadows":    "#000080",
+					"highlights": "#ffff00",
+				}),
+				NewStep(OP_COLOR_BALANCE, Params{
+					"shadows":    []interface{}{0.2, 0, -0.1},
+					"highlights": []interface{}{-0.3, 0, 0.2},
+				}),
+				NewStep(OP_HSL, Params{
+					"hue":        -30,
+					"saturation": 1.2,
+					"lightness":  0.9,
+					"target":     "reds",
+				}),
+			},
+		},
+		{
+			name:     "empty pipeline",
+			pipeline: Pipeline{},
+		},
+		{
+			name:     "unknown operation",
+			pipeline: Pipeline{NewStep("posterize", nil)},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown parameter",
+			pipeline: Pipeline{NewStep(OP_NEGATIVE, Params{"amount": 1})},
+			wantErr:  true,
+		},
+		{
+			name:     "empty crop size",
+			pipeline: Pipeline{NewStep(OP_CROP, nil)},
+			wantErr:  true,
+		},
+		{
+			name:     "crop without height",
+			pipeline: Pipeline{NewStep(OP_CROP, Params{"width": 10})},
+			wantErr:  true,
+		},
+		{
+			name: "incorrect alignment",
+			pipeline: Pipeline{
+				NewStep(OP_CROP, Params{"width": 10, "height": 10,
+					"vertical": "top"}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "fit without height",
+			pipeline: Pipeline{
+				NewStep(OP_RESIZE, Params{"width": 10, "fit": "cover"}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown kernel",
+			pipeline: Pipeline{
+				NewStep(OP_RESIZE, Params{"width": 10, "resample": "box"}),
+			},
+			wantErr: true,
+		},
+		{
+			name:     "incorrect flip direction",
+			pipeline: Pipeline{NewStep(OP_FLIP, nil)},
+			wantErr:  true,
+		},
+		{
+			name:     "negative sigma",
+			pipeline: Pipeline{NewStep(OP_BLUR, Params{"sigma": -1})},
+			wantErr:  true,
+		},
+		{
+			name:     "too large sigma",
+			pipeline: Pipeline{NewStep(OP_UNSHARP, Params{"sigma": 1e300})},
+			wantErr:  true,
+		},
+		{
+			name:     "brightness out of range",
+			pipeline: Pipeline{NewStep(OP_BRIGHTNESS, Params{"amount": 2})},
+			wantErr:  true,
+		},
+		{
+			name:     "zero gamma",
+			pipeline: Pipeline{NewStep(OP_GAMMA, Params{"gamma": 0})},
+			wantErr:  true,
+		},
+		{
+			name:     "too large gamma",
+			pipeline: Pipeline{NewStep(OP_GAMMA, Params{"gamma": 11})},
+			wantErr:  true,
+		},
+		{
+			name:     "exposure out of range",
+			pipeline: Pipeline{NewStep(OP_EXPOSURE, Params{"stops": -20})},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown blur edge mode",
+			pipeline: Pipeline{NewStep(OP_BLUR, Params{"edge": "repeat"})},
+			wantErr:  true,
+		},
+		{
+			name:     "linear is not a boolean",
+			pipeline: Pipeline{NewStep(OP_BLUR, Params{"linear": "yes"})},
+			wantErr:  true,
+		},
+		{
+			name:     "auto levels clip out of range",
+			pipeline: Pipeline{NewStep(OP_AUTO_LEVELS, Params{"clip": 0.6})},
+			wantErr:  true,
+		},
+		{
+			name:     "zero box blur radius",
+			pipeline: Pipeline{NewStep(OP_BOX_BLUR, Params{"radius": 0})},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown grayscale mode",
+			pipeline: Pipeline{NewStep(OP_GRAYSCALE, Params{"mode": "luma"})},
+			wantErr:  true,
+		},
+		{
+			name:     "channel mixer without matrix",
+			pipeline: Pipeline{NewStep(OP_CHANNEL_MIXER, nil)},
+			wantErr:  true,
+		},
+		{
+			name: "channel mixer with 3 columns",
+			pipeline: Pipeline{
+				NewStep(OP_CHANNEL_MIXER, Params{"matrix": []interface{}{
+					[]interface{}{1, 0, 0},
+					[]interface{}{0, 1, 0},
+					[]interface{}{0, 0, 1},
+				}}),
+			},
+			wantErr: true,
+		},
+		{
+			name:     "sepia with too large strength",
+			pipeline: Pipeline{NewStep(OP_SEPIA, Params{"strength": 2})},
+			wantErr:  true,
+		},
+		{
+			name:     "tint without color",
+			pipeline: Pipeline{NewStep(OP_TINT, Params{"amount": 0.5})},
+			wantErr:  true,
+		},
+		{
+			name: "duotone with incorrect color",
+			pipeline: Pipeline{NewStep(OP_DUOTONE, Params{
+				"shadows":    "black",
+				"highlights": "#fff",
+			})},
+			wantErr: true,
+		},
+		{
+			name: "color balance with 2 shifts",
+			pipeline: Pipeline{NewStep(OP_COLOR_BALANCE, Params{
+				"midtones": []interface{}{0.1, 0.2},
+			})},
+			wantErr: true,
+		},
+		{
+			name: "color balance with too large shift",
+			pipeline: Pipeline{NewStep(OP_COLOR_BALANCE, Params{
+				"midtones": []interface{}{0.1, 0.2, 1.5},
+			})},
+			wantErr: true,
+		},
+		{
+			name:     "hsl with negative saturation",
+			pipeline: Pipeline{NewStep(OP_HSL, Params{"saturation": -1})},
+			wantErr:  true,
+		},
+		{
+			name:     "hsl with unknown target",
+			pipeline: Pipeline{NewStep(OP_HSL, Params{"target": "oranges"})},
+			wantErr:  true,
+		},
+		{
+			name:     "convolution without kernel",
+			pipeline: Pipeline{NewStep(OP_CONVOLVE, nil)},
+			wantErr:  true,
+		},
+		{
+			name: "even convolution kernel",
+			pipeline: Pipeline{
+				NewStep(OP_CONVOLVE, Params{"kernel": []interface{}{
+					[]interface{}{1, 1},
+					[]interface{}{1, 1},
+				}}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown edge mode",
+			pipeline: Pipeline{
+				NewStep(OP_CONVOLVE, Params{
+					"kernel": []interface{}{[]interface{}{1}},
+					"edge":   "repeat",
+				}),
+			},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.pipeline.Validate()
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPipeline_Apply(t *testing.T) {
+	newEditor := func() *ImageEditor {
+		img := image.NewRGBA(image.Rect(0, 0, 8, 6))
+		for i := range img.Pix {
+			img.Pix[i] = 255
+		}
+		return &ImageEditor{
+			source:      img,
+			destination: image.NewRGBA(img.Rect),
+		}
+	}
+
+	t.Run("steps are applied in order", func(t *testing.T) {
+		editor := newEditor()
+		var pipeline Pipeline
+		err := json.Unmarshal([]byte(`[
+			{"op": "crop", "width": 4, "height": 4},
+			{"op": "resize", "width": 2},
+			{"op": "rotate", "degrees": 90},
+			{"op": "negative"}
+		]`), &pipeline)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		if !assert.NoError(t, pipeline.Apply(editor)) {
+			return
+		}
+		got := editor.EditedImage()
+		assert.Equal(t, 2, got.Bounds().Dx())
+		assert.Equal(t, 2, got.Bounds().Dy())
+		assert.Equal(t, color.RGBA{0, 0, 0, 255},
+			color.RGBAModel.Convert(got.At(got.Bounds().Min.X,
+				got.Bounds().Min.Y)))
+	})
+
+	t.Run("invalid pipeline leaves the image unchanged", func(t *testing.T) {
+		editor := newEditor()
+		pipeline := Pipeline{
+			NewStep(OP_NEGATIVE, nil),
+			NewStep(OP_BLUR, Params{"sigma": "big"}),
+		}
+
+		assert.Error(t, pipeline.Apply(editor))
+		assert.False(t, editor.IsModifiedImage())
+	})
+	t.Run("cancelled pipeline leaves the image unchanged", func(t *testing.T) {
+		editor := newEditor()
+		editor.EnableHistory(DEFAULT_HISTORY_BUDGET)
+		pipeline := Pipeline{
+			NewStep(OP_CROP, Params{"width": 4, "height": 4}),
+			NewStep(OP_NEGATIVE, nil),
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := pipeline.ApplyContext(ctx, editor)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, editor.IsModifiedImage())
+		assert.False(t, editor.CanUndo())
+	})
+}
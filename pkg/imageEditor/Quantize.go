@@ -0,0 +1,244 @@
+package imageEditor
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+// maxPaletteSize is the maximum number of colors in a palette of a GIF image.
+const maxPaletteSize = 256
+
+// alphaThreshold is the alpha value below which a pixel becomes transparent
+// in a paletted image.
+const alphaThreshold = 128
+
+// quantize converts the image into a paletted image with at most 256 colors.
+// The bounds of the result start at the origin. Pixels with alpha below
+// alphaThreshold become transparent. If the image has more colors than fit
+// into the palette, they are reduced using the median cut algorithm.
+func quantize(img image.Image) *image.Paletted {
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Rect, img, bounds.Min, draw.Src)
+
+	histogram := make(map[uint32]int)
+	hasTransparency := false
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		if nrgba.Pix[i+3] < alphaThreshold {
+			hasTransparency = true
+			continue
+		}
+		histogram[packColor(nrgba.Pix[i:i+3])]++
+	}
+
+	paletteSize := maxPaletteSize
+	if hasTransparency {
+		paletteSize--
+	}
+
+	var palette color.Palette
+	if len(histogram) <= paletteSize {
+		palette = make(color.Palette, 0, len(histogram)+1)
+		for packed := range histogram {
+			palette = append(palette, unpackColor(packed))
+		}
+		// The map order is random, so the palette is sorted to make the
+		// result reproducible.
+		sort.Slice(palette, func(i, j int) bool {
+			return packRGBA(palette[i].(color.RGBA)) <
+				packRGBA(palette[j].(color.RGBA))
+		})
+	} else {
+		palette = medianCut(histogram, paletteSize)
+	}
+
+	opaquePalette := palette
+	transparentIndex := -1
+	if hasTransparency {
+		transparentIndex = len(palette)
+		palette = append(palette, color.RGBA{})
+	}
+	if len(palette) == 0 {
+		palette = append(palette, color.RGBA{0, 0, 0, 255})
+	}
+
+	result := image.NewPaletted(nrgba.Rect, palette)
+	indexes := make(map[uint32]uint8)
+	for i, j := 0, 0; i < len(nrgba.Pix); i, j = i+4, j+1 {
+		if nrgba.Pix[i+3] < alphaThreshold {
+			result.Pix[j] = uint8(transparentIndex)
+			continue
+		}
+
+		packed := packColor(nrgba.Pix[i : i+3])
+		index, ok := indexes[packed]
+		if !ok {
+			index = uint8(nearestColor(opaquePalette, unpackColor(packed)))
+			indexes[packed] = index
+		}
+		result.Pix[j] = index
+	}
+
+	return result
+}
+
+// colorBox is a box of colors used by the median cut algorithm.
+type colorBox struct {
+	colors []uint32
+	counts []int
+}
+
+// medianCut reduces the colors of the histogram to the given number of colors.
+func medianCut(histogram map[uint32]int, size int) color.Palette {
+	box := colorBox{}
+	for packed, count := range histogram {
+		box.colors = append(box.colors, packed)
+		box.counts = append(box.counts, count)
+	}
+	sort.Sort(&box)
+
+	boxes := []colorBox{box}
+	for len(boxes) < size {
+		// The box with the widest channel range is split at its median.
+		widest, widestRange, widestChannel := -1, 0, 0
+		for i, box := range boxes {
+			if len(box.colors) < 2 {
+				continue
+			}
+			channel, channelRange := box.widestChannel()
+			if channelRange > widestRange {
+				widest, widestRange, widestChannel = i, channelRange, channel
+			}
+		}
+		if widest < 0 {
+			break
+		}
+
+		first, second := boxes[widest].split(widestChannel)
+		boxes[widest] = first
+		boxes = append(boxes, second)
+	}
+
+	palette := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		palette[i] = box.average()
+	}
+	return palette
+}
+
+// widestChannel returns the channel with the widest range of values and the
+// range.
+func (box *colorBox) widestChannel() (int, int) {
+	widest, widestRange := 0, -1
+	for channel := 0; channel < 3; channel++ {
+		low, high := 255, 0
+		for _, packed := range box.colors {
+			value := channelValue(packed, channel)
+			if value < low {
+				low = value
+			}
+			if value > high {
+				high = value
+			}
+		}
+		if high-low > widestRange {
+			widest, widestRange = channel, high-low
+		}
+	}
+	return widest, widestRange
+}
+
+// split sorts the colors by the channel and splits the box into two boxes
+// with about the same number of pixels.
+func (box *colorBox) split(channel int) (colorBox, colorBox) {
+	sort.Sort(&channelSorter{box, channel})
+
+	total := 0
+	for _, count := range box.counts {
+		total += count
+	}
+
+	median, sum := 1, box.counts[0]
+	for median < len(box.colors)-1 && sum+box.counts[median] <= total/2 {
+		sum += box.counts[median]
+		median++
+	}
+
+	return colorBox{box.colors[:median], box.counts[:median]},
+		colorBox{box.colors[median:], box.counts[median:]}
+}
+
+// average returns the average color of the box weighted by the pixel counts.
+func (box *colorBox) average() color.Color {
+	var r, g, b, total int
+	for i, packed := range box.colors {
+		count := box.counts[i]
+		r += channelValue(packed, 0) * count
+		g += channelValue(packed, 1) * count
+		b += channelValue(packed, 2) * count
+		total += count
+	}
+	return color.RGBA{
+		uint8((r + total/2) / total),
+		uint8((g + total/2) / total),
+		uint8((b + total/2) / total),
+		255,
+	}
+}
+
+func (box *colorBox) Len() int           { return len(box.colors) }
+func (box *colorBox) Less(i, j int) bool { return box.colors[i] < box.colors[j] }
+func (box *colorBox) Swap(i, j int) {
+	box.colors[i], box.colors[j] = box.colors[j], box.colors[i]
+	box.counts[i], box.counts[j] = box.counts[j], box.counts[i]
+}
+
+// channelSorter sorts the colors of a box by the value of a channel.
+type channelSorter struct {
+	*colorBox
+	channel int
+}
+
+func (sorter *channelSorter) Less(i, j int) bool {
+	return channelValue(sorter.colors[i], sorter.channel) <
+		channelValue(sorter.colors[j], sorter.channel)
+}
+
+// nearestColor returns the index of the palette color closest to the color.
+func nearestColor(palette color.Palette, c color.RGBA) int {
+	best, bestDistance := 0, -1
+	for i, paletteColor := range palette {
+		p := paletteColor.(color.RGBA)
+		dr := int(p.R) - int(c.R)
+		dg := int(p.G) - int(c.G)
+		db := int(p.B) - int(c.B)
+		distance := dr*dr + dg*dg + db*db
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
+
+// packColor packs the red, green and blue channels into a single value.
+func packColor(rgb []uint8) uint32 {
+	return uint32(rgb[0])<<16 | uint32(rgb[1])<<8 | uint32(rgb[2])
+}
+
+// packRGBA packs the color channels into a single value.
+func packRGBA(c color.RGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
+
+// unpackColor converts a packed value into an opaque color.
+func unpackColor(packed uint32) color.RGBA {
+	return color.RGBA{uint8(packed >> 16), uint8(packed >> 8), uint8(packed), 255}
+}
+
+// channelValue returns the value of the channel of a packed color. Channels are
+// numbered from 0 (red) to 2 (blue).
+func channelValue(packed uint32, channel int) int {
+	return int(packed>>(16-8*channel)) & 0xff
+}
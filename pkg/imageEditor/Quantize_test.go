@@ -0,0 +1,62 @@
+package imageEditor
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_quantize(t *testing.T) {
+	t.Run("exact palette", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(2, 2, 4, 4))
+		img.Set(2, 2, color.RGBA{255, 0, 0, 255})
+		img.Set(3, 2, color.RGBA{0, 255, 0, 255})
+		img.Set(2, 3, color.RGBA{0, 0, 255, 255})
+		img.Set(3, 3, color.RGBA{255, 0, 0, 255})
+
+		paletted := quantize(img)
+		assert.Equal(t, image.Rect(0, 0, 2, 2), paletted.Bounds())
+		assert.Len(t, paletted.Palette, 3)
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 2; x++ {
+				assert.Equal(t,
+					color.RGBAModel.Convert(img.At(x+2, y+2)),
+					color.RGBAModel.Convert(paletted.At(x, y)),
+					"pixel (%d, %d)", x, y)
+			}
+		}
+	})
+
+	t.Run("transparency", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+		img.Set(0, 0, color.RGBA{255, 255, 255, 255})
+
+		paletted := quantize(img)
+		assert.Len(t, paletted.Palette, 2)
+		_, _, _, alpha := paletted.At(1, 0).RGBA()
+		assert.Zero(t, alpha, "transparent pixel must stay transparent")
+		_, _, _, alpha = paletted.At(0, 0).RGBA()
+		assert.Equal(t, uint32(0xffff), alpha, "opaque pixel must stay opaque")
+	})
+
+	t.Run("median cut", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), 128, 255})
+			}
+		}
+
+		paletted := quantize(img)
+		assert.LessOrEqual(t, len(paletted.Palette), maxPaletteSize)
+		for _, point := range []image.Point{{0, 0}, {63, 63}, {31, 17}} {
+			want := img.RGBAAt(point.X, point.Y)
+			got := color.RGBAModel.Convert(paletted.At(point.X, point.Y)).(color.RGBA)
+			assert.InDelta(t, want.R, got.R, 16, "red of %v", point)
+			assert.InDelta(t, want.G, got.G, 16, "green of %v", point)
+			assert.InDelta(t, want.B, got.B, 16, "blue of %v", point)
+		}
+	})
+}
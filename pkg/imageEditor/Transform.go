@@ -0,0 +1,292 @@
+package imageEditor
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/NooFreeNames/ImageEditor/pkg/imageEditor/meta"
+)
+
+// Flip directions
+const (
+	HORIZONTAL = "horizontal"
+	VERTICAL   = "vertical"
+)
+
+// Rotate90 rotates the image 90 degrees clockwise.
+func (editor *ImageEditor) Rotate90() {
+	size := editor.Size()
+	editor.transform(size.Height(), size.Width(), func(x, y int) (int, int) {
+		return y, size.Height() - 1 - x
+	})
+}
+
+// Rotate180 rotates the image 180 degrees.
+func (editor *ImageEditor) Rotate180() {
+	size := editor.Size()
+	editor.transform(size.Width(), size.Height(), func(x, y int) (int, int) {
+		return size.Width() - 1 - x, size.Height() - 1 - y
+	})
+}
+
+// Rotate270 rotates the image 270 degrees clockwise.
+func (editor *ImageEditor) Rotate270() {
+	size := editor.Size()
+	editor.transform(size.Height(), size.Width(), func(x, y int) (int, int) {
+		return size.Width() - 1 - y, x
+	})
+}
+
+// FlipHorizontal mirrors the image from left to right.
+func (editor *ImageEditor) FlipHorizontal() {
+	size := editor.Size()
+	editor.transform(size.Width(), size.Height(), func(x, y int) (int, int) {
+		return size.Width() - 1 - x, y
+	})
+}
+
+// FlipVertical mirrors the image from top to bottom.
+func (editor *ImageEditor) FlipVertical() {
+	size := editor.Size()
+	editor.transform(size.Width(), size.Height(), func(x, y int) (int, int) {
+		return x, size.Height() - 1 - y
+	})
+}
+
+// Flip mirrors the image in the given direction. Valid directions are
+// "horizontal" and "vertical". Other values are ignored.
+func (editor *ImageEditor) Flip(direction string) {
+	switch direction {
+	case HORIZONTAL:
+		editor.FlipHorizontal()
+	case VERTICAL:
+		editor.FlipVertical()
+	}
+}
+
+// Rotate rotates the image clockwise by the given angle in degrees around its
+// center. Uncovered areas are filled with the background. If expand is true,
+// the image is enlarged to hold the whole rotated image, otherwise it keeps its
+// size and the corners are cut off.
+func (editor *ImageEditor) Rotate(degrees float64, background color.Color,
+	expand bool) {
+	defer editor.track()()
+	if math.IsNaN(degrees) || math.IsInf(degrees, 0) {
+		return
+	}
+
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+
+	size := editor.Size()
+	switch {
+	case degrees == 0:
+		return
+	case degrees == 180:
+		editor.Rotate180()
+		return
+	case degrees == 90 && (expand || size.Width() == size.Height()):
+		editor.Rotate90()
+		return
+	case degrees == 270 && (expand || size.Width() == size.Height()):
+		editor.Rotate270()
+		return
+	}
+
+	if size.IsEmpty() {
+		return
+	}
+
+	radians := degrees * math.Pi / 180
+	sin, cos := math.Sincos(radians)
+	srcWidth, srcHeight := float64(size.Width()), float64(size.Height())
+	width, height := size.Width(), size.Height()
+	if expand {
+		// Small errors of the floating point calculations must not add an
+		// extra row or column.
+		width = int(math.Ceil(
+			math.Abs(srcWidth*cos) + math.Abs(srcHeight*sin) - 1e-6))
+		height = int(math.Ceil(
+			math.Abs(srcWidth*sin) + math.Abs(srcHeight*cos) - 1e-6))
+	}
+
+	dstCenterX, dstCenterY := float64(width)/2, float64(height)/2
+	srcCenterX, srcCenterY := srcWidth/2, srcHeight/2
+	// sourcePoint uses the inverse rotation to find the source point of the
+	// pixel (x, y).
+	sourcePoint := func(x, y int) (float64, float64) {
+		dx := float64(x) + 0.5 - dstCenterX
+		dy := float64(y) + 0.5 - dstCenterY
+		return dx*cos + dy*sin + srcCenterX - 0.5,
+			-dx*sin + dy*cos + srcCenterY - 0.5
+	}
+
+	bounds := image.Rect(0, 0, width, height)
+	if editor.destination64 != nil {
+		var bg color.RGBA64
+		if background != nil {
+			bg = color.RGBA64Model.Convert(background).(color.RGBA64)
+		}
+		src := toRGBA64(editor.EditedImage())
+		dst := image.NewRGBA64(bounds)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				sx, sy := sourcePoint(x, y)
+				dst.SetRGBA64(x, y, bilinearAt64(src, sx, sy, bg))
+			}
+		}
+		editor.setDestination64(dst)
+	} else {
+		var bg color.RGBA
+		if background != nil {
+			bg = color.RGBAModel.Convert(background).(color.RGBA)
+		}
+		src := toRGBA(editor.EditedImage())
+		dst := image.NewRGBA(bounds)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				sx, sy := sourcePoint(x, y)
+				dst.SetRGBA(x, y, bilinearAt(src, sx, sy, bg))
+			}
+		}
+		editor.setDestination(dst)
+	}
+
+	editor.eachFrame(func(frame *ImageEditor) {
+		frame.Rotate(degrees, background, expand)
+	})
+}
+
+// orient transforms the image so that it is displayed correctly according to
+// the value of the EXIF Orientation tag.
+func (editor *ImageEditor) orient(orientation int) {
+	switch orientation {
+	case meta.ORIENTATION_FLIP_H:
+		editor.FlipHorizontal()
+	case meta.ORIENTATION_ROTATE_180:
+		editor.Rotate180()
+	case meta.ORIENTATION_FLIP_V:
+		editor.FlipVertical()
+	case meta.ORIENTATION_TRANSPOSE:
+		editor.Rotate90()
+		editor.FlipHorizontal()
+	case meta.ORIENTATION_ROTATE_90:
+		editor.Rotate90()
+	case meta.ORIENTATION_TRANSVERSE:
+		editor.Rotate270()
+		editor.FlipHorizontal()
+	case meta.ORIENTATION_ROTATE_270:
+		editor.Rotate270()
+	}
+}
+
+// transform creates an image of the given size whose pixel (x, y) is the
+// pixel of the edited image at the point returned by sourcePoint. The points
+// are relative to the top left corner of the image. The pixels are only moved,
+// so a 16-bit image keeps its depth.
+func (editor *ImageEditor) transform(width, height int,
+	sourcePoint func(x, y int) (int, int)) {
+	defer editor.track()()
+	bounds := image.Rect(0, 0, width, height)
+	if editor.destination64 != nil {
+		src := toRGBA64(editor.EditedImage())
+		dst := image.NewRGBA64(bounds)
+		movePixels(dst.Pix, dst.Stride, src.Pix, src.Stride, 8, width, height,
+			sourcePoint)
+		editor.setDestination64(dst)
+	} else {
+		src := toRGBA(editor.EditedImage())
+		dst := image.NewRGBA(bounds)
+		movePixels(dst.Pix, dst.Stride, src.Pix, src.Stride, 4, width, height,
+			sourcePoint)
+		editor.setDestination(dst)
+	}
+
+	editor.eachFrame(func(frame *ImageEditor) {
+		frame.transform(width, height, sourcePoint)
+	})
+}
+
+// movePixels copies the pixels of pixelSize bytes from src to the width x
+// height pixels of dst. The pixel (x, y) of dst is copied from the point of src
+// returned by sourcePoint.
+func movePixels(dst []uint8, dstStride int, src []uint8, srcStride int,
+	pixelSize, width, height int, sourcePoint func(x, y int) (int, int)) {
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx, sy := sourcePoint(x, y)
+			srcOffset := sy*srcStride + sx*pixelSize
+			dstOffset := y*dstStride + x*pixelSize
+			copy(dst[dstOffset:dstOffset+pixelSize],
+				src[srcOffset:srcOffset+pixelSize])
+		}
+	}
+}
+
+// bilinearAt interpolates the color of the image at the point (x, y) relative
+// to its top left corner. Points outside the image have the background color.
+func bilinearAt(img *image.RGBA, x, y float64, background color.RGBA) color.RGBA {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+
+	at := func(x, y int) color.RGBA {
+		point := image.Pt(img.Rect.Min.X+x, img.Rect.Min.Y+y)
+		if !point.In(img.Rect) {
+			return background
+		}
+		return img.RGBAAt(point.X, point.Y)
+	}
+
+	c00, c10 := at(ix, iy), at(ix+1, iy)
+	c01, c11 := at(ix, iy+1), at(ix+1, iy+1)
+
+	mix := func(v00, v10, v01, v11 uint8) uint8 {
+		top := float64(v00)*(1-fx) + float64(v10)*fx
+		bottom := float64(v01)*(1-fx) + float64(v11)*fx
+		return uint8(top*(1-fy) + bottom*fy + 0.5)
+	}
+
+	return color.RGBA{
+		mix(c00.R, c10.R, c01.R, c11.R),
+		mix(c00.G, c10.G, c01.G, c11.G),
+		mix(c00.B, c10.B, c01.B, c11.B),
+		mix(c00.A, c10.A, c01.A, c11.A),
+	}
+}
+
+// bilinearAt64 is like bilinearAt, but it interpolates the color of a 16-bit
+// image.
+func bilinearAt64(img *image.RGBA64, x, y float64,
+	background color.RGBA64) color.RGBA64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+
+	at := func(x, y int) color.RGBA64 {
+		point := image.Pt(img.Rect.Min.X+x, img.Rect.Min.Y+y)
+		if !point.In(img.Rect) {
+			return background
+		}
+		return img.RGBA64At(point.X, point.Y)
+	}
+
+	c00, c10 := at(ix, iy), at(ix+1, iy)
+	c01, c11 := at(ix, iy+1), at(ix+1, iy+1)
+
+	mix := func(v00, v10, v01, v11 uint16) uint16 {
+		top := float64(v00)*(1-fx) + float64(v10)*fx
+		bottom := float64(v01)*(1-fx) + float64(v11)*fx
+		return uint16(top*(1-fy) + bottom*fy + 0.5)
+	}
+
+	return color.RGBA64{
+		mix(c00.R, c10.R, c01.R, c11.R),
+		mix(c00.G, c10.G, c01.G, c11.G),
+		mix(c00.B, c10.B, c01.B, c11.B),
+		mix(c00.A, c10.A, c01.A, c11.A),
+	}
+}
@@ -0,0 +1,281 @@
+package imageEditor
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newNumberedImage creates an image whose pixels have red channels equal to
+// their numbers counting from the top left corner.
+func newNumberedImage(bounds image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(bounds)
+	for i := 0; i < len(img.Pix)/4; i++ {
+		img.Pix[i*4] = uint8(i + 1)
+		img.Pix[i*4+3] = 255
+	}
+	return img
+}
+
+// redChannels returns the red channels of the image pixels row by row.
+func redChannels(img image.Image) []uint8 {
+	rgba := toRGBA(img)
+	bounds := rgba.Bounds()
+	result := make([]uint8, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			result = append(result, rgba.RGBAAt(x, y).R)
+		}
+	}
+	return result
+}
+
+func TestImageEditor_transforms(t *testing.T) {
+	// Source image:
+	// 1 2 3
+	// 4 5 6
+	tests := []struct {
+		name       string
+		transform  func(editor *ImageEditor)
+		wantBounds image.Rectangle
+		wantRed    []uint8
+	}{
+		{
+			name:       "Rotate90",
+			transform:  (*ImageEditor).Rotate90,
+			wantBounds: image.Rect(0, 0, 2, 3),
+			wantRed:    []uint8{4, 1, 5, 2, 6, 3},
+		},
+		{
+			name:       "Rotate180",
+			transform:  (*ImageEditor).Rotate180,
+			wantBounds: image.Rect(0, 0, 3, 2),
+			wantRed:    []uint8{6, 5, 4, 3, 2, 1},
+		},
+		{
+			name:       "Rotate270",
+			transform:  (*ImageEditor).Rotate270,
+			wantBounds: image.Rect(0, 0, 2, 3),
+			wantRed:    []uint8{3, 6, 2, 5, 1, 4},
+		},
+		{
+			name:       "FlipHorizontal",
+			transform:  (*ImageEditor).FlipHorizontal,
+			wantBounds: image.Rect(0, 0, 3, 2),
+			wantRed:    []uint8{3, 2, 1, 6, 5, 4},
+		},
+		{
+			name:       "FlipVertical",
+			transform:  (*ImageEditor).FlipVertical,
+			wantBounds: image.Rect(0, 0, 3, 2),
+			wantRed:    []uint8{4, 5, 6, 1, 2, 3},
+		},
+		{
+			name: "Rotate by 90 with expand",
+			transform: func(editor *ImageEditor) {
+				editor.Rotate(90, nil, true)
+			},
+			wantBounds: image.Rect(0, 0, 2, 3),
+			wantRed:    []uint8{4, 1, 5, 2, 6, 3},
+		},
+		{
+			name: "Rotate by -90 with expand",
+			transform: func(editor *ImageEditor) {
+				editor.Rotate(-90, nil, true)
+			},
+			wantBounds: image.Rect(0, 0, 2, 3),
+			wantRed:    []uint8{3, 6, 2, 5, 1, 4},
+		},
+		{
+			name: "Rotate by 540",
+			transform: func(editor *ImageEditor) {
+				editor.Rotate(540, nil, false)
+			},
+			wantBounds: image.Rect(0, 0, 3, 2),
+			wantRed:    []uint8{6, 5, 4, 3, 2, 1},
+		},
+		{
+			name: "Rotate by 360",
+			transform: func(editor *ImageEditor) {
+				editor.Rotate(360, nil, false)
+			},
+			wantBounds: image.Rect(1, 1, 4, 3),
+			wantRed:    []uint8{1, 2, 3, 4, 5, 6},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bounds := image.Rect(1, 1, 4, 3)
+			editor := &ImageEditor{
+				source:      newNumberedImage(bounds),
+				destination: image.NewRGBA(bounds),
+			}
+			test.transform(editor)
+
+			got := editor.EditedImage()
+			assert.Equal(t, test.wantBounds, got.Bounds(),
+				"incorrect bounds of the edited image")
+			assert.Equal(t, test.wantRed, redChannels(got),
+				"incorrect pixels of the edited image")
+		})
+	}
+}
+
+func TestImageEditor_orient(t *testing.T) {
+	// Source image:
+	// 1 2 3
+	// 4 5 6
+	tests := []struct {
+		orientation int
+		wantBounds  image.Rectangle
+		wantRed     []uint8
+	}{
+		{0, image.Rect(1, 1, 4, 3), []uint8{1, 2, 3, 4, 5, 6}},
+		{1, image.Rect(1, 1, 4, 3), []uint8{1, 2, 3, 4, 5, 6}},
+		{2, image.Rect(0, 0, 3, 2), []uint8{3, 2, 1, 6, 5, 4}},
+		{3, image.Rect(0, 0, 3, 2), []uint8{6, 5, 4, 3, 2, 1}},
+		{4, image.Rect(0, 0, 3, 2), []uint8{4, 5, 6, 1, 2, 3}},
+		{5, image.Rect(0, 0, 2, 3), []uint8{1, 4, 2, 5, 3, 6}},
+		{6, image.Rect(0, 0, 2, 3), []uint8{4, 1, 5, 2, 6, 3}},
+		{7, image.Rect(0, 0, 2, 3), []uint8{6, 3, 5, 2, 4, 1}},
+		{8, image.Rect(0, 0, 2, 3), []uint8{3, 6, 2, 5, 1, 4}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("orientation %d", test.orientation), func(t *testing.T) {
+			bounds := image.Rect(1, 1, 4, 3)
+			editor := &ImageEditor{
+				source:      newNumberedImage(bounds),
+				destination: image.NewRGBA(bounds),
+			}
+			editor.orient(test.orientation)
+
+			got := editor.EditedImage()
+			assert.Equal(t, test.wantBounds, got.Bounds(),
+				"incorrect bounds of the edited image")
+			assert.Equal(t, test.wantRed, redChannels(got),
+				"incorrect pixels of the edited image")
+		})
+	}
+}
+
+func TestImageEditor_orient_deep(t *testing.T) {
+	// Source image:
+	// 1 2 3
+	// 4 5 6
+	bounds := image.Rect(1, 1, 4, 3)
+	src := image.NewRGBA64(bounds)
+	for i := 0; i < 6; i++ {
+		// The low byte of the red channel is lost in 8 bits.
+		src.SetRGBA64(bounds.Min.X+i%3, bounds.Min.Y+i/3,
+			color.RGBA64{uint16(i+1)<<8 | 0x80, 0, 0, 0xffff})
+	}
+	editor := &ImageEditor{
+		source:        src,
+		destination64: image.NewRGBA64(bounds),
+	}
+	editor.orient(5)
+
+	if !assert.NotNil(t, editor.destination64,
+		"the 16-bit image must be kept") {
+		return
+	}
+	got := editor.destination64
+	assert.Equal(t, image.Rect(0, 0, 2, 3), got.Bounds())
+	var red []uint16
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			red = append(red, got.RGBA64At(x, y).R)
+		}
+	}
+	assert.Equal(t, []uint16{0x180, 0x480, 0x280, 0x580, 0x380, 0x680}, red)
+}
+
+func TestImageEditor_Rotate(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	black := color.RGBA{0, 0, 0, 255}
+
+	tests := []struct {
+		name       string
+		degrees    float64
+		expand     bool
+		wantBounds image.Rectangle
+		wantColors map[image.Point]color.RGBA
+	}{
+		{
+			name:       "45 degrees with expand",
+			degrees:    45,
+			expand:     true,
+			wantBounds: image.Rect(0, 0, 15, 15),
+			wantColors: map[image.Point]color.RGBA{
+				{0, 0}: black, {14, 14}: black, {7, 7}: white, {7, 1}: white,
+			},
+		},
+		{
+			name:       "45 degrees without expand",
+			degrees:    45,
+			expand:     false,
+			wantBounds: image.Rect(0, 0, 10, 10),
+			wantColors: map[image.Point]color.RGBA{
+				{0, 0}: black, {9, 9}: black, {5, 5}: white, {5, 0}: white,
+			},
+		},
+		{
+			name:       "90 degrees without expand",
+			degrees:    90,
+			expand:     false,
+			wantBounds: image.Rect(0, 0, 10, 10),
+			wantColors: map[image.Point]color.RGBA{
+				{0, 0}: white, {9, 9}: white, {5, 5}: white,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := image.NewRGBA(image.Rect(0, 0, 10, 10))
+			for i := range source.Pix {
+				source.Pix[i] = 255
+			}
+			editor := &ImageEditor{
+				source:      source,
+				destination: image.NewRGBA(source.Rect),
+			}
+			editor.Rotate(test.degrees, black, test.expand)
+
+			got := editor.EditedImage()
+			assert.Equal(t, test.wantBounds, got.Bounds(),
+				"incorrect bounds of the edited image")
+			for point, want := range test.wantColors {
+				assert.Equal(t, want, color.RGBAModel.Convert(got.At(point.X, point.Y)),
+					"incorrect color at %v", point)
+			}
+		})
+	}
+}
+
+func TestImageEditor_Rotate_deep(t *testing.T) {
+	// The low byte of the channels is lost in 8 bits.
+	solid := color.RGBA64{0x1234, 0x2345, 0x3456, 0xffff}
+	bounds := image.Rect(0, 0, 4, 4)
+	src := image.NewRGBA64(bounds)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			src.SetRGBA64(x, y, solid)
+		}
+	}
+	editor := &ImageEditor{source: src, destination64: src}
+	background := color.RGBA64{0x0101, 0x0202, 0x0303, 0xffff}
+
+	editor.Rotate(45, background, true)
+	if !assert.NotNil(t, editor.destination64,
+		"the 16-bit image must be kept") {
+		return
+	}
+	got := editor.destination64
+	assert.Equal(t, image.Rect(0, 0, 6, 6), got.Rect)
+	assert.Equal(t, solid, got.RGBA64At(2, 2))
+	assert.Equal(t, solid, got.RGBA64At(3, 3))
+	assert.Equal(t, background, got.RGBA64At(0, 0))
+}
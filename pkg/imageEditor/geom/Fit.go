@@ -0,0 +1,27 @@
+package geom
+
+// Fit modes describe how an object is scaled into a box. They mirror the
+// values of the CSS object-fit property.
+const (
+	// FIT scales the object to fit inside the box preserving its aspect
+	// ratio. The rest of the box is filled with the background (letterbox).
+	FIT = "fit"
+	// FILL stretches the object to the size of the box.
+	FILL = "fill"
+	// COVER scales the object to cover the whole box preserving its aspect
+	// ratio and crops the parts that do not fit.
+	COVER = "cover"
+)
+
+const DEFAULT_FIT = FILL
+
+// ValidateFit checks whether a string value is a valid fit mode. Valid values
+// are "fit", "fill", and "cover".
+func ValidateFit(fit string) bool {
+	switch fit {
+	case FIT, FILL, COVER:
+		return true
+	default:
+		return false
+	}
+}
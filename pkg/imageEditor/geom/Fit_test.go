@@ -0,0 +1,50 @@
+package geom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFit(t *testing.T) {
+	tests := []struct {
+		name string
+		fit  string
+		want bool
+	}{
+		{
+			name: "correct fit",
+			fit:  FIT,
+			want: true,
+		},
+		{
+			name: "correct fit",
+			fit:  FILL,
+			want: true,
+		},
+		{
+			name: "correct fit",
+			fit:  COVER,
+			want: true,
+		},
+		{
+			name: "incorrect fit",
+			fit:  "contain",
+			want: false,
+		},
+		{
+			name: "empty fit",
+			fit:  "",
+			want: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ValidateFit(test.fit)
+			assert.Equal(t, test.want, got,
+				"ValidateFit(%q) = %t, want %t",
+				test.fit, got, test.want,
+			)
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package geom
 
+import "math"
+
 // NewSize creates a new Size object based on the width and height parameters.
 func NewSize(width, height int) Size {
 	size := Size{}
@@ -46,3 +48,68 @@ func (size *Size) SetHeight(height int) {
 func (size Size) IsEmpty() bool {
 	return size.width <= 0 || size.height <= 0
 }
+
+// ScaleToFit returns the largest size with the aspect ratio of the size that
+// fits inside the box. An empty size is returned if either size is empty.
+func (size Size) ScaleToFit(box Size) Size {
+	if size.IsEmpty() || box.IsEmpty() {
+		return Size{}
+	}
+
+	ratio := math.Min(
+		float64(box.width)/float64(size.width),
+		float64(box.height)/float64(size.height),
+	)
+	return size.scale(ratio)
+}
+
+// ScaleToCover returns the smallest size with the aspect ratio of the size
+// that covers the whole box. An empty size is returned if either size is empty.
+func (size Size) ScaleToCover(box Size) Size {
+	if size.IsEmpty() || box.IsEmpty() {
+		return Size{}
+	}
+
+	ratio := math.Max(
+		float64(box.width)/float64(size.width),
+		float64(box.height)/float64(size.height),
+	)
+	result := size.scale(ratio)
+	// Rounding must not leave uncovered pixels.
+	if result.width < box.width {
+		result.width = box.width
+	}
+	if result.height < box.height {
+		result.height = box.height
+	}
+	return result
+}
+
+// ScaleTo returns the size the object takes after it is scaled into the box
+// using the given fit mode. An invalid mode is treated as DEFAULT_FIT.
+func (size Size) ScaleTo(box Size, fit string) Size {
+	switch fit {
+	case FIT:
+		return size.ScaleToFit(box)
+	case COVER:
+		return size.ScaleToCover(box)
+	default:
+		return box
+	}
+}
+
+// scale multiplies the width and height by the ratio. Both dimensions are at
+// least 1.
+func (size Size) scale(ratio float64) Size {
+	result := Size{
+		int(math.Round(float64(size.width) * ratio)),
+		int(math.Round(float64(size.height) * ratio)),
+	}
+	if result.width < 1 {
+		result.width = 1
+	}
+	if result.height < 1 {
+		result.height = 1
+	}
+	return result
+}
@@ -211,3 +211,91 @@ func TestSize_IsEmpty(t *testing.T) {
 		})
 	}
 }
+
+func TestSize_ScaleTo(t *testing.T) {
+	tests := []struct {
+		name string
+		size Size
+		box  Size
+		fit  string
+		want Size
+	}{
+		{
+			name: "fit landscape into box",
+			size: Size{800, 400},
+			box:  Size{400, 300},
+			fit:  FIT,
+			want: Size{400, 200},
+		},
+		{
+			name: "fit portrait into box",
+			size: Size{300, 600},
+			box:  Size{400, 300},
+			fit:  FIT,
+			want: Size{150, 300},
+		},
+		{
+			name: "fit small object into box",
+			size: Size{40, 30},
+			box:  Size{400, 300},
+			fit:  FIT,
+			want: Size{400, 300},
+		},
+		{
+			name: "cover landscape box",
+			size: Size{800, 400},
+			box:  Size{400, 300},
+			fit:  COVER,
+			want: Size{600, 300},
+		},
+		{
+			name: "cover with rounding",
+			size: Size{3, 7},
+			box:  Size{10, 10},
+			fit:  COVER,
+			want: Size{10, 23},
+		},
+		{
+			name: "fill box",
+			size: Size{800, 400},
+			box:  Size{400, 300},
+			fit:  FILL,
+			want: Size{400, 300},
+		},
+		{
+			name: "invalid fit",
+			size: Size{800, 400},
+			box:  Size{400, 300},
+			fit:  "beleberda",
+			want: Size{400, 300},
+		},
+		{
+			name: "empty size",
+			size: Size{0, 400},
+			box:  Size{400, 300},
+			fit:  FIT,
+			want: Size{0, 0},
+		},
+		{
+			name: "empty box",
+			size: Size{800, 400},
+			box:  Size{400, 0},
+			fit:  COVER,
+			want: Size{0, 0},
+		},
+		{
+			name: "very thin object",
+			size: Size{1000, 1},
+			box:  Size{10, 10},
+			fit:  FIT,
+			want: Size{10, 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.size.ScaleTo(test.box, test.fit)
+			assert.Equal(t, test.want, got, "%#v.ScaleTo(%#v, %q) = %#v, want %#v",
+				test.size, test.box, test.fit, got, test.want)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package meta
+
+import (
+	"encoding/binary"
+)
+
+// EXIF tags
+const (
+	TAG_ORIENTATION = 0x0112
+	TAG_ARTIST      = 0x013b
+	TAG_COPYRIGHT   = 0x8298
+)
+
+// entrySize is the size of an IFD entry: the tag, the type, the count and the
+// value or its offset.
+const entrySize = 12
+
+// typeSizes are the sizes of the values of the TIFF types.
+var typeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// Orientation values of the EXIF Orientation tag. They describe how the stored
+// image must be transformed to be displayed correctly.
+const (
+	ORIENTATION_NORMAL     = 1
+	ORIENTATION_FLIP_H     = 2
+	ORIENTATION_ROTATE_180 = 3
+	ORIENTATION_FLIP_V     = 4
+	ORIENTATION_TRANSPOSE  = 5
+	ORIENTATION_ROTATE_90  = 6
+	ORIENTATION_TRANSVERSE = 7
+	ORIENTATION_ROTATE_270 = 8
+)
+
+// Orientation returns the value of the Orientation tag of the EXIF data that
+// starts with the TIFF header. It returns ORIENTATION_NORMAL if the tag is
+// missing or invalid.
+func Orientation(exif []byte) int {
+	offset, order, ok := findTag(exif, TAG_ORIENTATION)
+	if !ok {
+		return ORIENTATION_NORMAL
+	}
+
+	orientation := int(order.Uint16(exif[offset:]))
+	if orientation < ORIENTATION_NORMAL || orientation > ORIENTATION_ROTATE_270 {
+		return ORIENTATION_NORMAL
+	}
+	return orientation
+}
+
+// ResetOrientation returns a copy of the EXIF data with the Orientation tag set
+// to ORIENTATION_NORMAL. It is used after the image has been oriented.
+func ResetOrientation(exif []byte) []byte {
+	offset, order, ok := findTag(exif, TAG_ORIENTATION)
+	if !ok {
+		return exif
+	}
+
+	result := append([]byte{}, exif...)
+	order.PutUint16(result[offset:], ORIENTATION_NORMAL)
+	return result
+}
+
+// KeepTags returns EXIF data that contains only the given tags of the first IFD
+// of the EXIF data, so that other data, such as the GPS position or the camera
+// details, is removed. It returns nil if the data is invalid or contains none
+// of the tags.
+func KeepTags(exif []byte, tags ...uint16) []byte {
+	ifdOffset, order, ok := readHeader(exif)
+	if !ok {
+		return nil
+	}
+
+	var entries [][]byte
+	var values [][]byte
+	entryCount := int(order.Uint16(exif[ifdOffset:]))
+	for i := 0; i < entryCount; i++ {
+		start := ifdOffset + 2 + i*entrySize
+		if start+entrySize > len(exif) {
+			break
+		}
+		entry := exif[start : start+entrySize]
+		if !containsTag(tags, order.Uint16(entry)) {
+			continue
+		}
+
+		typeSize, ok := typeSizes[order.Uint16(entry[2:])]
+		if !ok {
+			continue
+		}
+		size := typeSize * int(order.Uint32(entry[4:]))
+		var value []byte
+		if size > 4 {
+			// Larger values are stored at the offset.
+			offset := int(order.Uint32(entry[8:]))
+			if size > len(exif) || offset < 0 || offset > len(exif)-size {
+				continue
+			}
+			value = exif[offset : offset+size]
+		}
+		entries = append(entries, entry)
+		values = append(values, value)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// The values follow the IFD, which ends with the zero offset of the next
+	// IFD, and start at even offsets.
+	ifdEnd := 8 + 2 + len(entries)*entrySize + 4
+	size := ifdEnd
+	for _, value := range values {
+		size += len(value) + len(value)%2
+	}
+
+	result := make([]byte, size)
+	copy(result, exif[:4])
+	order.PutUint32(result[4:], 8)
+	order.PutUint16(result[8:], uint16(len(entries)))
+	valueOffset := ifdEnd
+	for i, entry := range entries {
+		position := 8 + 2 + i*entrySize
+		copy(result[position:], entry)
+		if values[i] != nil {
+			order.PutUint32(result[position+8:], uint32(valueOffset))
+			copy(result[valueOffset:], values[i])
+			valueOffset += len(values[i]) + len(values[i])%2
+		}
+	}
+	return result
+}
+
+// containsTag reports whether the tags contain the tag.
+func containsTag(tags []uint16, tag uint16) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// readHeader reads the TIFF header of the EXIF data. It returns the offset of
+// the first IFD and the byte order of the data.
+func readHeader(exif []byte) (int, binary.ByteOrder, bool) {
+	if len(exif) < 8 {
+		return 0, nil, false
+	}
+
+	var order binary.ByteOrder
+	switch string(exif[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0, nil, false
+	}
+
+	ifdOffset := int(order.Uint32(exif[4:]))
+	if ifdOffset < 8 || ifdOffset+2 > len(exif) {
+		return 0, nil, false
+	}
+	return ifdOffset, order, true
+}
+
+// findTag finds the tag with a SHORT value in the first IFD of the EXIF data.
+// It returns the offset of the value and the byte order of the data.
+func findTag(exif []byte, tag uint16) (int, binary.ByteOrder, bool) {
+	ifdOffset, order, ok := readHeader(exif)
+	if !ok {
+		return 0, nil, false
+	}
+
+	const typeShort = 3
+	entryCount := int(order.Uint16(exif[ifdOffset:]))
+	for i := 0; i < entryCount; i++ {
+		entry := ifdOffset + 2 + i*entrySize
+		if entry+entrySize > len(exif) {
+			break
+		}
+		if order.Uint16(exif[entry:]) != tag {
+			continue
+		}
+		if order.Uint16(exif[entry+2:]) != typeShort {
+			return 0, nil, false
+		}
+		// Values of four bytes or less are stored in the entry itself.
+		return entry + 8, order, true
+	}
+
+	return 0, nil, false
+}
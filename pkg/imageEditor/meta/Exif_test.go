@@ -0,0 +1,172 @@
+package meta
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newExif creates EXIF data with a single IFD that contains the given tag with
+// a SHORT value.
+func newExif(order binary.ByteOrder, tag, value uint16) []byte {
+	exif := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(exif, "II*\x00")
+	} else {
+		copy(exif, "MM\x00*")
+	}
+	order.PutUint32(exif[4:], 8)
+	order.PutUint16(exif[8:], 1)
+	order.PutUint16(exif[10:], tag)
+	order.PutUint16(exif[12:], 3)
+	order.PutUint32(exif[14:], 1)
+	order.PutUint16(exif[18:], value)
+	return exif
+}
+
+func TestResetOrientation(t *testing.T) {
+	exif := newExif(binary.BigEndian, TAG_ORIENTATION, 6)
+
+	got := ResetOrientation(exif)
+	assert.Equal(t, ORIENTATION_NORMAL, Orientation(got),
+		"the orientation must be reset")
+	assert.Equal(t, ORIENTATION_ROTATE_90, Orientation(exif),
+		"the original data must not be changed")
+
+	invalid := []byte("beleberda")
+	assert.Equal(t, invalid, ResetOrientation(invalid),
+		"invalid data must be returned unchanged")
+}
+
+func TestOrientation(t *testing.T) {
+	tests := []struct {
+		name string
+		exif []byte
+		want int
+	}{
+		{
+			name: "little endian",
+			exif: newExif(binary.LittleEndian, TAG_ORIENTATION, 6),
+			want: ORIENTATION_ROTATE_90,
+		},
+		{
+			name: "big endian",
+			exif: newExif(binary.BigEndian, TAG_ORIENTATION, 3),
+			want: ORIENTATION_ROTATE_180,
+		},
+		{
+			name: "invalid value",
+			exif: newExif(binary.BigEndian, TAG_ORIENTATION, 9),
+			want: ORIENTATION_NORMAL,
+		},
+		{
+			name: "missing tag",
+			exif: newExif(binary.LittleEndian, 0x0110, 8),
+			want: ORIENTATION_NORMAL,
+		},
+		{
+			name: "invalid header",
+			exif: []byte("beleberda"),
+			want: ORIENTATION_NORMAL,
+		},
+		{
+			name: "truncated data",
+			exif: newExif(binary.LittleEndian, TAG_ORIENTATION, 6)[:12],
+			want: ORIENTATION_NORMAL,
+		},
+		{
+			name: "nil data",
+			exif: nil,
+			want: ORIENTATION_NORMAL,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Orientation(test.exif)
+			assert.Equal(t, test.want, got, "Orientation(% x) = %d, want %d",
+				test.exif, got, test.want)
+		})
+	}
+}
+
+// exifTag is an entry of the EXIF data created by newExifTags.
+type exifTag struct {
+	tag, typ uint16
+	value    []byte
+}
+
+// newExifTags creates little endian EXIF data with a single IFD that contains
+// the tags. Values longer than four bytes are stored after the IFD.
+func newExifTags(tags ...exifTag) []byte {
+	order := binary.LittleEndian
+	exif := []byte("II*\x00\x08\x00\x00\x00")
+	exif = order.AppendUint16(exif, uint16(len(tags)))
+	valueOffset := len(exif) + len(tags)*12 + 4
+	var values []byte
+	for _, tag := range tags {
+		exif = order.AppendUint16(exif, tag.tag)
+		exif = order.AppendUint16(exif, tag.typ)
+		count := len(tag.value) / typeSizes[tag.typ]
+		exif = order.AppendUint32(exif, uint32(count))
+		if len(tag.value) <= 4 {
+			exif = append(exif, tag.value...)
+			exif = append(exif, make([]byte, 4-len(tag.value))...)
+			continue
+		}
+		exif = order.AppendUint32(exif, uint32(valueOffset+len(values)))
+		values = append(values, tag.value...)
+	}
+	exif = append(exif, 0, 0, 0, 0)
+	return append(exif, values...)
+}
+
+// tagValue returns the value of the tag in the first IFD of the EXIF data
+// created by newExifTags or KeepTags, or nil if the tag is missing.
+func tagValue(exif []byte, tag uint16) []byte {
+	order := binary.LittleEndian
+	count := int(order.Uint16(exif[8:]))
+	for i := 0; i < count; i++ {
+		entry := exif[10+i*12:]
+		if order.Uint16(entry) != tag {
+			continue
+		}
+		size := typeSizes[order.Uint16(entry[2:])] *
+			int(order.Uint32(entry[4:]))
+		if size <= 4 {
+			return entry[8 : 8+size]
+		}
+		offset := int(order.Uint32(entry[8:]))
+		return exif[offset : offset+size]
+	}
+	return nil
+}
+
+func TestKeepTags(t *testing.T) {
+	copyright := []byte("(c) Example\x00")
+	artist := []byte("Artist\x00")
+	exif := newExifTags(
+		exifTag{0x010f, 2, []byte("Camera maker\x00")},
+		exifTag{TAG_ORIENTATION, 3, []byte{6, 0}},
+		exifTag{TAG_ARTIST, 2, artist},
+		exifTag{TAG_COPYRIGHT, 2, copyright},
+		exifTag{0x8825, 4, []byte{0x40, 0, 0, 0}},
+	)
+
+	got := KeepTags(exif, TAG_ORIENTATION, TAG_COPYRIGHT, TAG_ARTIST)
+	assert.Equal(t, ORIENTATION_ROTATE_90, Orientation(got))
+	assert.Equal(t, copyright, tagValue(got, TAG_COPYRIGHT))
+	assert.Equal(t, artist, tagValue(got, TAG_ARTIST))
+	assert.Nil(t, tagValue(got, 0x010f), "the other tags must be removed")
+	assert.Nil(t, tagValue(got, 0x8825), "the GPS IFD must be removed")
+	assert.Equal(t, 0, len(got)%2, "the values must start at even offsets")
+
+	got = KeepTags(exif, TAG_COPYRIGHT)
+	assert.Equal(t, uint16(1), binary.LittleEndian.Uint16(got[8:]))
+	assert.Equal(t, copyright, tagValue(got, TAG_COPYRIGHT))
+
+	assert.Nil(t, KeepTags(exif, 0x0110), "no tags must give no data")
+	assert.Nil(t, KeepTags([]byte("beleberda"), TAG_COPYRIGHT))
+	assert.Nil(t, KeepTags(exif[:20], TAG_COPYRIGHT),
+		"truncated data must not panic")
+}
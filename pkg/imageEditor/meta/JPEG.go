@@ -0,0 +1,202 @@
+// Package meta provides functions for reading and writing metadata of images,
+// such as EXIF, ICC profiles and XMP, without decoding their pixels.
+package meta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// JPEG markers
+const (
+	MARKER_SOI  = 0xd8
+	MARKER_EOI  = 0xd9
+	MARKER_SOS  = 0xda
+	MARKER_APP0 = 0xe0
+	MARKER_APP1 = 0xe1
+	MARKER_APP2 = 0xe2
+	MARKER_COM  = 0xfe
+)
+
+// Identifiers at the start of the segments with metadata.
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iccHeader  = []byte("ICC_PROFILE\x00")
+)
+
+// maxSegmentLength is the maximum length of the segment data.
+const maxSegmentLength = 0xffff - 2
+
+// ErrNotJPEG is returned when the data is not a valid JPEG file.
+var ErrNotJPEG = errors.New("meta: not a JPEG file")
+
+// Segment is a marker segment of a JPEG file.
+type Segment struct {
+	// Marker is the second byte of the segment marker, e.g. 0xe1 for APP1.
+	Marker byte
+	// Data is the content of the segment without the marker and the length.
+	Data []byte
+}
+
+// ReadJPEGSegments returns the marker segments of a JPEG file that precede the
+// image data. The returned data shares memory with the given slice.
+func ReadJPEGSegments(data []byte) ([]Segment, error) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != MARKER_SOI {
+		return nil, ErrNotJPEG
+	}
+
+	var segments []Segment
+	position := 2
+	for position < len(data) {
+		if data[position] != 0xff {
+			return nil, ErrNotJPEG
+		}
+		// Markers can be preceded by any number of fill bytes.
+		for position < len(data) && data[position] == 0xff {
+			position++
+		}
+		if position >= len(data) {
+			break
+		}
+
+		marker := data[position]
+		position++
+		switch {
+		case marker == MARKER_SOS || marker == MARKER_EOI:
+			return segments, nil
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			// Standalone markers have no length.
+			continue
+		}
+
+		if position+2 > len(data) {
+			return nil, ErrNotJPEG
+		}
+		length := int(binary.BigEndian.Uint16(data[position:]))
+		if length < 2 || position+length > len(data) {
+			return nil, ErrNotJPEG
+		}
+		segments = append(segments, Segment{
+			marker,
+			data[position+2 : position+length],
+		})
+		position += length
+	}
+
+	return segments, nil
+}
+
+// ExifFromJPEG returns the EXIF data of a JPEG file starting with the TIFF
+// header. It returns nil if the file has no EXIF data.
+func ExifFromJPEG(data []byte) []byte {
+	segments, err := ReadJPEGSegments(data)
+	if err != nil {
+		return nil
+	}
+
+	for _, segment := range segments {
+		if segment.Marker == MARKER_APP1 &&
+			bytes.HasPrefix(segment.Data, exifHeader) {
+			return segment.Data[len(exifHeader):]
+		}
+	}
+	return nil
+}
+
+// ReadJPEG returns the metadata of a JPEG file. The returned data shares memory
+// with the given slice.
+func ReadJPEG(data []byte) *Metadata {
+	metadata := new(Metadata)
+	segments, err := ReadJPEGSegments(data)
+	if err != nil {
+		return metadata
+	}
+
+	var iccChunks []Segment
+	for _, segment := range segments {
+		switch {
+		case segment.Marker == MARKER_APP1 &&
+			bytes.HasPrefix(segment.Data, exifHeader):
+			if metadata.EXIF == nil {
+				metadata.EXIF = segment.Data[len(exifHeader):]
+			}
+		case segment.Marker == MARKER_APP1 &&
+			bytes.HasPrefix(segment.Data, xmpHeader):
+			if metadata.XMP == nil {
+				metadata.XMP = segment.Data[len(xmpHeader):]
+			}
+		case segment.Marker == MARKER_APP2 &&
+			bytes.HasPrefix(segment.Data, iccHeader) &&
+			len(segment.Data) > len(iccHeader)+2:
+			iccChunks = append(iccChunks, segment)
+		}
+	}
+
+	// A profile can be split into several chunks with sequence numbers.
+	sort.SliceStable(iccChunks, func(i, j int) bool {
+		return iccChunks[i].Data[len(iccHeader)] <
+			iccChunks[j].Data[len(iccHeader)]
+	})
+	for _, chunk := range iccChunks {
+		metadata.ICC = append(metadata.ICC, chunk.Data[len(iccHeader)+2:]...)
+	}
+
+	return metadata
+}
+
+// WriteJPEG returns a copy of the JPEG file with the metadata inserted after
+// the JFIF segment. Metadata that does not fit into a segment is skipped.
+func WriteJPEG(data []byte, metadata *Metadata) ([]byte, error) {
+	segments, err := ReadJPEGSegments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	position := 2
+	if len(segments) > 0 && segments[0].Marker == MARKER_APP0 {
+		position += 4 + len(segments[0].Data)
+	}
+
+	result := make([]byte, 0, len(data)+len(metadata.EXIF)+
+		len(metadata.ICC)+len(metadata.XMP)+1024)
+	result = append(result, data[:position]...)
+
+	if len(metadata.EXIF) > 0 &&
+		len(exifHeader)+len(metadata.EXIF) <= maxSegmentLength {
+		result = appendSegment(result, MARKER_APP1, exifHeader, metadata.EXIF)
+	}
+	if len(metadata.XMP) > 0 &&
+		len(xmpHeader)+len(metadata.XMP) <= maxSegmentLength {
+		result = appendSegment(result, MARKER_APP1, xmpHeader, metadata.XMP)
+	}
+	if len(metadata.ICC) > 0 {
+		chunkLength := maxSegmentLength - len(iccHeader) - 2
+		count := (len(metadata.ICC) + chunkLength - 1) / chunkLength
+		if count <= 255 {
+			for i := 0; i < count; i++ {
+				end := (i + 1) * chunkLength
+				if end > len(metadata.ICC) {
+					end = len(metadata.ICC)
+				}
+				header := append(append([]byte{}, iccHeader...),
+					byte(i+1), byte(count))
+				result = appendSegment(result, MARKER_APP2, header,
+					metadata.ICC[i*chunkLength:end])
+			}
+		}
+	}
+
+	return append(result, data[position:]...), nil
+}
+
+// appendSegment appends a marker segment with the data consisting of the
+// header and the content.
+func appendSegment(data []byte, marker byte, header, content []byte) []byte {
+	length := 2 + len(header) + len(content)
+	data = append(data, 0xff, marker, byte(length>>8), byte(length))
+	data = append(data, header...)
+	return append(data, content...)
+}
@@ -0,0 +1,168 @@
+package meta
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newJPEG creates the beginning of a JPEG file with the given segments.
+func newJPEG(segments ...Segment) []byte {
+	data := []byte{0xff, MARKER_SOI}
+	for _, segment := range segments {
+		data = append(data, 0xff, segment.Marker, 0, 0)
+		binary.BigEndian.PutUint16(data[len(data)-2:], uint16(len(segment.Data)+2))
+		data = append(data, segment.Data...)
+	}
+	return append(data, 0xff, MARKER_SOS, 0, 2, 1, 2, 3)
+}
+
+func TestReadJPEGSegments(t *testing.T) {
+	segments := []Segment{
+		{MARKER_APP0, []byte("JFIF\x00")},
+		{MARKER_APP1, []byte("Exif\x00\x00")},
+		{MARKER_COM, []byte("comment")},
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []Segment
+		wantErr bool
+	}{
+		{
+			name: "segments",
+			data: newJPEG(segments...),
+			want: segments,
+		},
+		{
+			name: "no segments",
+			data: newJPEG(),
+			want: nil,
+		},
+		{
+			name:    "not a JPEG",
+			data:    []byte("beleberda"),
+			wantErr: true,
+		},
+		{
+			name:    "truncated segment",
+			data:    newJPEG(segments...)[:10],
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ReadJPEGSegments(test.data)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestExifFromJPEG(t *testing.T) {
+	exif := newExif(binary.LittleEndian, TAG_ORIENTATION, 6)
+
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{
+			name: "EXIF segment",
+			data: newJPEG(
+				Segment{MARKER_APP0, []byte("JFIF\x00")},
+				Segment{MARKER_APP1, append([]byte("Exif\x00\x00"), exif...)},
+			),
+			want: exif,
+		},
+		{
+			name: "XMP segment",
+			data: newJPEG(Segment{MARKER_APP1, []byte("http://ns.adobe.com/xap/1.0/\x00")}),
+			want: nil,
+		},
+		{
+			name: "not a JPEG",
+			data: []byte("beleberda"),
+			want: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ExifFromJPEG(test.data)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestReadJPEG(t *testing.T) {
+	exif := newExif(binary.LittleEndian, TAG_ORIENTATION, 6)
+	data := newJPEG(
+		Segment{MARKER_APP0, []byte("JFIF\x00")},
+		Segment{MARKER_APP1, append([]byte("Exif\x00\x00"), exif...)},
+		Segment{MARKER_APP2, []byte("ICC_PROFILE\x00\x02\x02world")},
+		Segment{MARKER_APP1, []byte("http://ns.adobe.com/xap/1.0/\x00<xmp/>")},
+		Segment{MARKER_APP2, []byte("ICC_PROFILE\x00\x01\x02hello ")},
+	)
+
+	got := ReadJPEG(data)
+	want := &Metadata{exif, []byte("hello world"), []byte("<xmp/>")}
+	assert.Equal(t, want, got)
+
+	assert.True(t, ReadJPEG([]byte("beleberda")).IsEmpty(),
+		"metadata of an invalid file must be empty")
+}
+
+func TestWriteJPEG(t *testing.T) {
+	largeICC := make([]byte, 70000)
+	for i := range largeICC {
+		largeICC[i] = byte(i)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		metadata *Metadata
+	}{
+		{
+			name:     "all metadata",
+			data:     newJPEG(Segment{MARKER_APP0, []byte("JFIF\x00")}),
+			metadata: &Metadata{[]byte("II*\x00"), []byte("icc"), []byte("<xmp/>")},
+		},
+		{
+			name:     "large ICC profile",
+			data:     newJPEG(),
+			metadata: &Metadata{ICC: largeICC},
+		},
+		{
+			name:     "empty metadata",
+			data:     newJPEG(Segment{MARKER_COM, []byte("comment")}),
+			metadata: &Metadata{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := WriteJPEG(test.data, test.metadata)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, test.metadata, ReadJPEG(got),
+				"the written metadata must be read back")
+
+			segments, _ := ReadJPEGSegments(got)
+			original, _ := ReadJPEGSegments(test.data)
+			if len(original) > 0 && original[0].Marker == MARKER_APP0 {
+				assert.Equal(t, original[0], segments[0],
+					"the JFIF segment must stay first")
+			}
+		})
+	}
+
+	_, err := WriteJPEG([]byte("beleberda"), &Metadata{})
+	assert.Error(t, err)
+}
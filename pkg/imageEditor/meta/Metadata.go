@@ -0,0 +1,32 @@
+package meta
+
+import "bytes"
+
+// Metadata is the metadata of an image that is not related to its pixels.
+type Metadata struct {
+	// EXIF is the EXIF data starting with the TIFF header.
+	EXIF []byte
+	// ICC is the ICC color profile.
+	ICC []byte
+	// XMP is the XMP packet.
+	XMP []byte
+}
+
+// IsEmpty returns true if the metadata contains no data.
+func (metadata *Metadata) IsEmpty() bool {
+	return metadata == nil ||
+		len(metadata.EXIF) == 0 && len(metadata.ICC) == 0 && len(metadata.XMP) == 0
+}
+
+// Read returns the metadata of a JPEG or PNG file. It returns empty metadata
+// for other formats.
+func Read(data []byte) *Metadata {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, MARKER_SOI}):
+		return ReadJPEG(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return ReadPNG(data)
+	default:
+		return new(Metadata)
+	}
+}
@@ -0,0 +1,38 @@
+package meta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadata_IsEmpty(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata *Metadata
+		want     bool
+	}{
+		{"nil metadata", nil, true},
+		{"no data", &Metadata{}, true},
+		{"empty slices", &Metadata{[]byte{}, []byte{}, []byte{}}, true},
+		{"EXIF", &Metadata{EXIF: []byte{1}}, false},
+		{"ICC", &Metadata{ICC: []byte{1}}, false},
+		{"XMP", &Metadata{XMP: []byte{1}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.metadata.IsEmpty()
+			assert.Equal(t, test.want, got, "%#v.IsEmpty() = %t, want %t",
+				test.metadata, got, test.want)
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	jpeg := newJPEG(Segment{MARKER_APP1, []byte("http://ns.adobe.com/xap/1.0/\x00<xmp/>")})
+	png, _ := WritePNG(newPNG(), &Metadata{ICC: []byte("icc")})
+
+	assert.Equal(t, &Metadata{XMP: []byte("<xmp/>")}, Read(jpeg))
+	assert.Equal(t, &Metadata{ICC: []byte("icc")}, Read(png))
+	assert.Equal(t, &Metadata{}, Read([]byte("GIF89a")))
+}
@@ -0,0 +1,186 @@
+package meta
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// pngSignature is the signature at the start of every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Keywords of the PNG chunks with metadata.
+const (
+	iccProfileName = "ICC Profile"
+	xmpKeyword     = "XML:com.adobe.xmp"
+)
+
+// maxICCLength limits the size of a decompressed ICC profile.
+const maxICCLength = 16 << 20
+
+// ErrNotPNG is returned when the data is not a valid PNG file.
+var ErrNotPNG = errors.New("meta: not a PNG file")
+
+// Chunk is a chunk of a PNG file.
+type Chunk struct {
+	// Type is the four letter type of the chunk, e.g. "IHDR".
+	Type string
+	// Data is the content of the chunk.
+	Data []byte
+}
+
+// ReadPNGChunks returns the chunks of a PNG file. The returned data shares
+// memory with the given slice.
+func ReadPNGChunks(data []byte) ([]Chunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrNotPNG
+	}
+
+	var chunks []Chunk
+	position := len(pngSignature)
+	for position < len(data) {
+		if position+8 > len(data) {
+			return nil, ErrNotPNG
+		}
+		length := int(binary.BigEndian.Uint32(data[position:]))
+		end := position + 8 + length + 4
+		if length < 0 || end > len(data) || end < position {
+			return nil, ErrNotPNG
+		}
+		chunk := Chunk{
+			string(data[position+4 : position+8]),
+			data[position+8 : position+8+length],
+		}
+		chunks = append(chunks, chunk)
+		position = end
+
+		if chunk.Type == "IEND" {
+			break
+		}
+	}
+
+	return chunks, nil
+}
+
+// ReadPNG returns the metadata of a PNG file.
+func ReadPNG(data []byte) *Metadata {
+	metadata := new(Metadata)
+	chunks, err := ReadPNGChunks(data)
+	if err != nil {
+		return metadata
+	}
+
+	for _, chunk := range chunks {
+		switch chunk.Type {
+		case "eXIf":
+			metadata.EXIF = chunk.Data
+		case "iCCP":
+			metadata.ICC = readICCP(chunk.Data)
+		case "iTXt":
+			if xmp := readXMP(chunk.Data); xmp != nil {
+				metadata.XMP = xmp
+			}
+		}
+	}
+
+	return metadata
+}
+
+// WritePNG returns a copy of the PNG file with the metadata inserted after the
+// IHDR chunk.
+func WritePNG(data []byte, metadata *Metadata) ([]byte, error) {
+	chunks, err := ReadPNGChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 || chunks[0].Type != "IHDR" {
+		return nil, ErrNotPNG
+	}
+
+	position := len(pngSignature) + 12 + len(chunks[0].Data)
+	result := make([]byte, 0, len(data)+len(metadata.EXIF)+
+		len(metadata.ICC)+len(metadata.XMP)+1024)
+	result = append(result, data[:position]...)
+
+	if len(metadata.ICC) > 0 {
+		compressed := new(bytes.Buffer)
+		writer := zlib.NewWriter(compressed)
+		writer.Write(metadata.ICC)
+		writer.Close()
+
+		content := append([]byte(iccProfileName), 0, 0)
+		result = appendChunk(result, "iCCP",
+			append(content, compressed.Bytes()...))
+	}
+	if len(metadata.EXIF) > 0 {
+		result = appendChunk(result, "eXIf", metadata.EXIF)
+	}
+	if len(metadata.XMP) > 0 {
+		// The keyword is followed by the compression flag, the compression
+		// method, the empty language tag and the empty translated keyword.
+		content := append([]byte(xmpKeyword), 0, 0, 0, 0, 0)
+		result = appendChunk(result, "iTXt", append(content, metadata.XMP...))
+	}
+
+	return append(result, data[position:]...), nil
+}
+
+// readICCP returns the decompressed profile of an iCCP chunk.
+func readICCP(data []byte) []byte {
+	separator := bytes.IndexByte(data, 0)
+	// The profile name is followed by the compression method.
+	if separator < 0 || separator+2 > len(data) || data[separator+1] != 0 {
+		return nil
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(data[separator+2:]))
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+
+	profile, err := io.ReadAll(io.LimitReader(reader, maxICCLength))
+	if err != nil {
+		return nil
+	}
+	return profile
+}
+
+// readXMP returns the XMP packet of an uncompressed iTXt chunk or nil if the
+// chunk has no XMP.
+func readXMP(data []byte) []byte {
+	prefix := append([]byte(xmpKeyword), 0, 0)
+	if !bytes.HasPrefix(data, prefix) || len(data) == len(prefix) {
+		return nil
+	}
+
+	rest := data[len(prefix)+1:]
+	// Skip the language tag and the translated keyword.
+	for i := 0; i < 2; i++ {
+		separator := bytes.IndexByte(rest, 0)
+		if separator < 0 {
+			return nil
+		}
+		rest = rest[separator+1:]
+	}
+	return rest
+}
+
+// appendChunk appends a chunk with its length and checksum.
+func appendChunk(data []byte, chunkType string, content []byte) []byte {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(content)))
+	copy(header[4:], chunkType)
+	data = append(data, header[:]...)
+	data = append(data, content...)
+
+	checksum := crc32.NewIEEE()
+	checksum.Write(header[4:])
+	checksum.Write(content)
+	var crc [4]byte
+	binary.BigEndian.PutUint32(crc[:], checksum.Sum32())
+	return append(data, crc[:]...)
+}
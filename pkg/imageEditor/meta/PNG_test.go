@@ -0,0 +1,67 @@
+package meta
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newPNG encodes a small image as PNG.
+func newPNG() []byte {
+	buffer := new(bytes.Buffer)
+	png.Encode(buffer, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	return buffer.Bytes()
+}
+
+func TestReadPNGChunks(t *testing.T) {
+	got, err := ReadPNGChunks(newPNG())
+	if assert.NoError(t, err) && assert.NotEmpty(t, got) {
+		assert.Equal(t, "IHDR", got[0].Type)
+		assert.Equal(t, "IEND", got[len(got)-1].Type)
+	}
+
+	_, err = ReadPNGChunks([]byte("beleberda"))
+	assert.Error(t, err)
+
+	_, err = ReadPNGChunks(newPNG()[:20])
+	assert.Error(t, err)
+}
+
+func TestWritePNG(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata *Metadata
+	}{
+		{
+			name:     "all metadata",
+			metadata: &Metadata{[]byte("II*\x00"), []byte("icc"), []byte("<xmp/>")},
+		},
+		{
+			name:     "ICC profile",
+			metadata: &Metadata{ICC: []byte("icc")},
+		},
+		{
+			name:     "empty metadata",
+			metadata: &Metadata{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := WritePNG(newPNG(), test.metadata)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, test.metadata, ReadPNG(got),
+				"the written metadata must be read back")
+
+			_, err = png.Decode(bytes.NewReader(got))
+			assert.NoError(t, err, "the result must be a valid PNG file")
+		})
+	}
+
+	_, err := WritePNG([]byte("beleberda"), &Metadata{})
+	assert.Error(t, err)
+}
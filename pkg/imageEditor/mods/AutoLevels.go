@@ -0,0 +1,108 @@
+package mods
+
+import (
+	"context"
+	"image"
+	"math"
+)
+
+// NewAutoLevels creates a new AutoLevels object. clip is the fraction of the
+// darkest and of the brightest pixels of each channel, from 0 to 0.5, that are
+// ignored when the range of the channel is found, so that a few outliers do not
+// prevent the stretching.
+func NewAutoLevels(clip float64) *AutoLevels {
+	return &AutoLevels{math.Max(0, math.Min(0.5, clip))}
+}
+
+// AutoLevels is a type representing an operation that stretches the range of
+// each color channel of an image to the full range. It fixes the contrast and
+// the color cast of faded images.
+type AutoLevels struct {
+	clip float64
+}
+
+// Apply returns the image with the stretched channels.
+func (levels *AutoLevels) Apply(src *image.RGBA) (*image.RGBA, error) {
+	return levels.ApplyContext(context.Background(), src)
+}
+
+// ApplyContext is like Apply, but it stops when the context is done and
+// returns the error of the context.
+func (levels *AutoLevels) ApplyContext(ctx context.Context,
+	src *image.RGBA) (*image.RGBA, error) {
+	bounds := src.Bounds()
+
+	// The histograms are built from the unpremultiplied colors of the visible
+	// pixels.
+	var histograms [3][256]int
+	count := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			col := src.RGBAAt(x, y)
+			if col.A == 0 {
+				continue
+			}
+			alpha := uint32(col.A)
+			histograms[0][unpremultiply(col.R, alpha)]++
+			histograms[1][unpremultiply(col.G, alpha)]++
+			histograms[2][unpremultiply(col.B, alpha)]++
+			count++
+		}
+	}
+
+	var tables [3]lookupTable
+	for i := range tables {
+		low, high := histogramRange(&histograms[i], count, levels.clip)
+		tables[i] = newLookupTable(func(value float64) float64 {
+			if high <= low {
+				return value
+			}
+			return (value*255 - float64(low)) / float64(high-low)
+		})
+	}
+
+	dst := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			col := src.RGBAAt(x, y)
+			if col.A != 0 {
+				alpha := uint32(col.A)
+				col.R = premultiply(tables[0][unpremultiply(col.R, alpha)], alpha)
+				col.G = premultiply(tables[1][unpremultiply(col.G, alpha)], alpha)
+				col.B = premultiply(tables[2][unpremultiply(col.B, alpha)], alpha)
+			}
+			dst.SetRGBA(x, y, col)
+		}
+	}
+	return dst, nil
+}
+
+// histogramRange returns the lowest and the highest channel values after the
+// clip fraction of the count values is ignored on each side.
+func histogramRange(histogram *[256]int, count int,
+	clip float64) (low, high int) {
+	limit := int(float64(count) * clip)
+
+	sum := 0
+	for low = 0; low < 255; low++ {
+		sum += histogram[low]
+		if sum > limit {
+			break
+		}
+	}
+
+	sum = 0
+	for high = 255; high > 0; high-- {
+		sum += histogram[high]
+		if sum > limit {
+			break
+		}
+	}
+	return low, high
+}
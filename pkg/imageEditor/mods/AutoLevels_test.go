@@ -0,0 +1,80 @@
+package mods
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAutoLevels(t *testing.T) {
+	assert.Equal(t, &AutoLevels{0.01}, NewAutoLevels(0.01))
+	assert.Equal(t, &AutoLevels{0}, NewAutoLevels(-1))
+	assert.Equal(t, &AutoLevels{0.5}, NewAutoLevels(2))
+}
+
+func TestAutoLevels_Apply(t *testing.T) {
+	tests := []struct {
+		name   string
+		clip   float64
+		values [][]uint8
+		want   [][]uint8
+	}{
+		{
+			name:   "stretched range",
+			values: [][]uint8{{50, 100, 150, 200}},
+			want:   [][]uint8{{0, 85, 170, 255}},
+		},
+		{
+			name:   "flat image",
+			values: [][]uint8{{100, 100}},
+			want:   [][]uint8{{100, 100}},
+		},
+		{
+			name: "clipped outliers",
+			clip: 0.1,
+			values: [][]uint8{
+				{0, 50, 50, 50, 50},
+				{100, 100, 100, 100, 255},
+			},
+			want: [][]uint8{
+				{0, 0, 0, 0, 0},
+				{255, 255, 255, 255, 255},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewAutoLevels(test.clip).Apply(
+				newGrayImage(test.values))
+			assert.NoError(t, err)
+			assert.Equal(t, newGrayImage(test.want), got)
+		})
+	}
+}
+
+func TestAutoLevels_Apply_channels(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	src.SetRGBA(0, 0, color.RGBA{40, 0, 10, 255})
+	src.SetRGBA(1, 0, color.RGBA{80, 20, 30, 255})
+	// The transparent pixel does not count and stays transparent.
+	src.SetRGBA(2, 0, color.RGBA{0, 0, 0, 0})
+
+	got, err := NewAutoLevels(0).Apply(src)
+	assert.NoError(t, err)
+	assert.Equal(t, color.RGBA{0, 0, 0, 255}, got.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, got.RGBAAt(1, 0))
+	assert.Equal(t, color.RGBA{}, got.RGBAAt(2, 0))
+}
+
+func TestAutoLevels_ApplyContext(t *testing.T) {
+	src := newGrayImage([][]uint8{{50, 100, 150, 200}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got, err := NewAutoLevels(0).ApplyContext(ctx, src)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, got)
+}
@@ -0,0 +1,43 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+)
+
+// NewBrightness creates a new Brightness object. amount is added to every
+// color channel as a fraction of the full range, so -1 makes the image black,
+// 0 keeps it unchanged and 1 makes it white.
+func NewBrightness(amount float64) *Brightness {
+	curve := func(value float64) float64 {
+		return value + amount
+	}
+	return &Brightness{newLookupTable(curve), newDeepLookupTable(curve)}
+}
+
+// Brightness is a type representing a modifier that changes the brightness of
+// an image.
+type Brightness struct {
+	// values stores the new values for each channel value.
+	values lookupTable
+	// deepValues stores the new values for each 16-bit channel value.
+	deepValues *deepLookupTable
+}
+
+// ModifyPixel changes the brightness of the image pixel.
+func (brightness *Brightness) ModifyPixel(_ image.Point, col color.RGBA,
+	_ image.Image) color.RGBA {
+	return brightness.values.apply(col)
+}
+
+// ModifyRow changes the brightness of a row of the image.
+func (brightness *Brightness) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	brightness.values.applyRow(dst, sourceRow(start, dst, src))
+}
+
+// ModifyPixel64 changes the brightness of the 16-bit image pixel.
+func (brightness *Brightness) ModifyPixel64(_ image.Point, col color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	return brightness.deepValues.apply64(col)
+}
@@ -0,0 +1,52 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrightness_ModifyPixel(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount float64
+		color  color.RGBA
+		want   color.RGBA
+	}{
+		{
+			name:   "unchanged brightness",
+			amount: 0,
+			color:  color.RGBA{1, 128, 255, 255},
+			want:   color.RGBA{1, 128, 255, 255},
+		},
+		{
+			name:   "brighter",
+			amount: 0.2,
+			color:  color.RGBA{0, 100, 250, 255},
+			want:   color.RGBA{51, 151, 255, 255},
+		},
+		{
+			name:   "darker",
+			amount: -0.2,
+			color:  color.RGBA{0, 100, 250, 255},
+			want:   color.RGBA{0, 49, 199, 255},
+		},
+		{
+			name:   "white",
+			amount: 1,
+			color:  color.RGBA{0, 100, 250, 255},
+			want:   color.RGBA{255, 255, 255, 255},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NewBrightness(test.amount).ModifyPixel(image.Point{},
+				test.color, nil)
+			assert.Equal(t, test.want, got,
+				"Brightness.ModifyPixel(image.Point{}, %#v, nil) = %#v, want %#v",
+				test.color, got, test.want)
+		})
+	}
+}
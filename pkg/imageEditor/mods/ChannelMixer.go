@@ -0,0 +1,123 @@
+package mods
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+// NewChannelMixer creates a new ChannelMixer object with a 3x4 matrix. The
+// rows give the red, green and blue channels of the result. The first three
+// columns are the weights of the red, green and blue channels of the pixel, and
+// the last one is added as a fraction of the full range. For example, the row
+// {0, 0, 1, 0} takes the channel from blue and {0, 0, 0, 0.5} sets it to half.
+// Returns an error if the matrix is incorrect.
+func NewChannelMixer(matrix [][]float64) (*ChannelMixer, error) {
+	if len(matrix) != 3 {
+		return nil, errors.New("the channel matrix must have 3 rows")
+	}
+
+	var weights [3][4]float64
+	for i, row := range matrix {
+		if len(row) != 4 {
+			return nil, errors.New("the channel matrix rows must have 4 columns")
+		}
+		for j, weight := range row {
+			if math.IsNaN(weight) || math.IsInf(weight, 0) ||
+				math.Abs(weight) > maxMixerWeight {
+				return nil, errors.New(
+					"the channel matrix weights must be numbers from -256 to 256")
+			}
+			weights[i][j] = weight
+		}
+	}
+	return newChannelMixer(weights), nil
+}
+
+// newChannelMixer creates a new ChannelMixer object with a correct matrix.
+func newChannelMixer(matrix [3][4]float64) *ChannelMixer {
+	mixer := new(ChannelMixer)
+	for i, row := range matrix {
+		// The premultiplied channels are mixed, so the constant is the weight
+		// of the alpha.
+		for j, weight := range row {
+			mixer.weights[i][j] = int64(math.Round(weight * (1 << 16)))
+		}
+	}
+	return mixer
+}
+
+// maxMixerWeight is the largest absolute weight of the channel matrix.
+const maxMixerWeight = 256
+
+// ChannelMixer is a type representing a modifier that replaces every color
+// channel with a weighted sum of the channels. The sum is calculated on the
+// premultiplied channels, which is the same as mixing the unpremultiplied
+// channels.
+type ChannelMixer struct {
+	// weights stores the rows of the matrix out of 1 << 16.
+	weights [3][4]int64
+}
+
+// ModifyPixel mixes the channels of the image pixel.
+func (mixer *ChannelMixer) ModifyPixel(_ image.Point, col color.RGBA,
+	_ image.Image) color.RGBA {
+	col.R, col.G, col.B = mixer.mix(col.R, col.G, col.B, col.A)
+	return col
+}
+
+// ModifyRow mixes the channels of a row of the image.
+func (mixer *ChannelMixer) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	row := sourceRow(start, dst, src)
+	for i := 0; i < len(dst); i += 4 {
+		dst[i], dst[i+1], dst[i+2] = mixer.mix(row[i], row[i+1], row[i+2],
+			row[i+3])
+		dst[i+3] = row[i+3]
+	}
+}
+
+// ModifyPixel64 mixes the channels of the 16-bit image pixel.
+func (mixer *ChannelMixer) ModifyPixel64(_ image.Point, col color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	col.R, col.G, col.B = mixer.mix64(col.R, col.G, col.B, col.A)
+	return col
+}
+
+// mix returns the mixed premultiplied channels, which are limited to the
+// alpha.
+func (mixer *ChannelMixer) mix(r, g, b, a uint8) (uint8, uint8, uint8) {
+	var result [3]uint8
+	for i, weights := range mixer.weights {
+		sum := weights[0]*int64(r) + weights[1]*int64(g) +
+			weights[2]*int64(b) + weights[3]*int64(a)
+		value := (sum + 1<<15) >> 16
+		switch {
+		case value < 0:
+			value = 0
+		case value > int64(a):
+			value = int64(a)
+		}
+		result[i] = uint8(value)
+	}
+	return result[0], result[1], result[2]
+}
+
+// mix64 returns the mixed premultiplied 16-bit channels, like mix.
+func (mixer *ChannelMixer) mix64(r, g, b, a uint16) (uint16, uint16, uint16) {
+	var result [3]uint16
+	for i, weights := range mixer.weights {
+		sum := weights[0]*int64(r) + weights[1]*int64(g) +
+			weights[2]*int64(b) + weights[3]*int64(a)
+		value := (sum + 1<<15) >> 16
+		switch {
+		case value < 0:
+			value = 0
+		case value > int64(a):
+			value = int64(a)
+		}
+		result[i] = uint16(value)
+	}
+	return result[0], result[1], result[2]
+}
@@ -0,0 +1,89 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mustChannelMixer creates the channel mixer and panics if the matrix is
+// incorrect.
+func mustChannelMixer(matrix [][]float64) *ChannelMixer {
+	mixer, err := NewChannelMixer(matrix)
+	if err != nil {
+		panic(err)
+	}
+	return mixer
+}
+
+func TestNewChannelMixer(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix [][]float64
+	}{
+		{"no rows", nil},
+		{"two rows", [][]float64{{1, 0, 0, 0}, {0, 1, 0, 0}}},
+		{"short row", [][]float64{{1, 0, 0, 0}, {0, 1, 0}, {0, 0, 1, 0}}},
+		{"not a number", [][]float64{
+			{1, 0, 0, 0}, {0, math.NaN(), 0, 0}, {0, 0, 1, 0}}},
+		{"too large", [][]float64{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 300}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewChannelMixer(test.matrix)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestChannelMixer_ModifyPixel(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix [][]float64
+		color  color.RGBA
+		want   color.RGBA
+	}{
+		{
+			name:   "identity",
+			matrix: [][]float64{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}},
+			color:  color.RGBA{10, 20, 30, 40},
+			want:   color.RGBA{10, 20, 30, 40},
+		},
+		{
+			name:   "swapped channels",
+			matrix: [][]float64{{0, 0, 1, 0}, {0, 1, 0, 0}, {1, 0, 0, 0}},
+			color:  color.RGBA{200, 100, 50, 255},
+			want:   color.RGBA{50, 100, 200, 255},
+		},
+		{
+			name: "weighted sum",
+			matrix: [][]float64{
+				{0.5, 0.5, 0, 0}, {0, 0.25, 0.75, 0}, {0.2, 0.2, 0.2, 0}},
+			color: color.RGBA{200, 100, 50, 255},
+			want:  color.RGBA{150, 63, 70, 255},
+		},
+		{
+			name:   "constant of a half transparent pixel",
+			matrix: [][]float64{{0, 0, 0, 0.5}, {1, 0, 0, 0.25}, {0, 0, 1, 0}},
+			color:  color.RGBA{40, 20, 60, 128},
+			want:   color.RGBA{64, 72, 60, 128},
+		},
+		{
+			name:   "clamped to alpha",
+			matrix: [][]float64{{2, 0, 0, 0}, {-1, 0, 0, 0}, {0, 0, 0, 1}},
+			color:  color.RGBA{100, 0, 0, 150},
+			want:   color.RGBA{150, 0, 150, 150},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := mustChannelMixer(test.matrix).ModifyPixel(image.Point{},
+				test.color, nil)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
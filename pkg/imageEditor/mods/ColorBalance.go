@@ -0,0 +1,121 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Tonal ranges of the color balance, which are overlapping ranges of the
+// channel values.
+const (
+	// balanceWidth is the width of the transition between the ranges.
+	balanceWidth = 0.25
+	// balanceBorder is the channel value between the shadows and the midtones.
+	// The midtones and the highlights meet at 1 - balanceBorder.
+	balanceBorder = 0.333
+	// balanceScale is the largest shift of a channel value.
+	balanceScale = 0.7
+)
+
+// NewColorBalance creates a new ColorBalance object. The shadows, midtones and
+// highlights shift the red, green and blue channels of the dark, middle and
+// light parts of an image. The shifts are in the range [-1, 1]: negative values
+// move the colors to cyan, magenta and yellow, positive values to red, green and
+// blue. Values outside the range are clamped.
+func NewColorBalance(shadows, midtones, highlights [3]float64) *ColorBalance {
+	balance := new(ColorBalance)
+	for i := range balance.values {
+		shifts := [3]float64{
+			clampShift(shadows[i]),
+			clampShift(midtones[i]),
+			clampShift(highlights[i]),
+		}
+		curve := func(value float64) float64 {
+			weights := balanceWeights(value)
+			return value + shifts[0]*weights[0] + shifts[1]*weights[1] +
+				shifts[2]*weights[2]
+		}
+		balance.values[i] = newLookupTable(curve)
+		balance.deepValues[i] = newDeepLookupTable(curve)
+	}
+	return balance
+}
+
+// clampShift limits the shift of the color balance to the range [-1, 1].
+func clampShift(shift float64) float64 {
+	return math.Max(-1, math.Min(1, shift))
+}
+
+// balanceWeights returns how much the shadows, midtones and highlights shifts
+// change the channel value in the range [0, 1].
+func balanceWeights(value float64) [3]float64 {
+	clamp := func(weight float64) float64 {
+		return math.Max(0, math.Min(1, weight))
+	}
+	return [3]float64{
+		clamp((value-balanceBorder)/-balanceWidth+0.5) * balanceScale,
+		clamp((value-balanceBorder)/balanceWidth+0.5) *
+			clamp((value+balanceBorder-1)/-balanceWidth+0.5) * balanceScale,
+		clamp((value+balanceBorder-1)/balanceWidth+0.5) * balanceScale,
+	}
+}
+
+// ColorBalance is a type representing a modifier that changes the colors of the
+// shadows, midtones and highlights of an image separately.
+type ColorBalance struct {
+	// values stores the new values of the red, green and blue channels for
+	// each channel value.
+	values [3]lookupTable
+	// deepValues stores the new values of the red, green and blue channels for
+	// each 16-bit channel value.
+	deepValues [3]*deepLookupTable
+}
+
+// ModifyPixel changes the color balance of the image pixel.
+func (balance *ColorBalance) ModifyPixel(_ image.Point, col color.RGBA,
+	_ image.Image) color.RGBA {
+	col.R, col.G, col.B = balance.balance(col.R, col.G, col.B, col.A)
+	return col
+}
+
+// ModifyRow changes the color balance of a row of the image.
+func (balance *ColorBalance) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	row := sourceRow(start, dst, src)
+	for i := 0; i < len(dst); i += 4 {
+		dst[i], dst[i+1], dst[i+2] = balance.balance(row[i], row[i+1],
+			row[i+2], row[i+3])
+		dst[i+3] = row[i+3]
+	}
+}
+
+// ModifyPixel64 changes the color balance of the 16-bit image pixel.
+func (balance *ColorBalance) ModifyPixel64(_ image.Point, col color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	if col.A == 0 {
+		return col
+	}
+
+	alpha := uint32(col.A)
+	channels := [3]*uint16{&col.R, &col.G, &col.B}
+	for i, channel := range channels {
+		values := balance.deepValues[i].lookup()
+		*channel = premultiply64(values[unpremultiply64(*channel, alpha)],
+			alpha)
+	}
+	return col
+}
+
+// balance returns the premultiplied channels with the values from the tables.
+func (balance *ColorBalance) balance(r, g, b, a uint8) (uint8, uint8, uint8) {
+	if a == 0 {
+		return r, g, b
+	}
+
+	alpha := uint32(a)
+	tables := &balance.values
+	return premultiply(tables[0][unpremultiply(r, alpha)], alpha),
+		premultiply(tables[1][unpremultiply(g, alpha)], alpha),
+		premultiply(tables[2][unpremultiply(b, alpha)], alpha)
+}
@@ -0,0 +1,72 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColorBalance_ModifyPixel(t *testing.T) {
+	tests := []struct {
+		name       string
+		shadows    [3]float64
+		midtones   [3]float64
+		highlights [3]float64
+		color      color.RGBA
+		want       color.RGBA
+	}{
+		{
+			name:  "no shifts",
+			color: color.RGBA{10, 128, 240, 255},
+			want:  color.RGBA{10, 128, 240, 255},
+		},
+		{
+			name:    "red shadows",
+			shadows: [3]float64{1, 0, 0},
+			color:   color.RGBA{0, 0, 0, 255},
+			want:    color.RGBA{179, 0, 0, 255},
+		},
+		{
+			name:     "green midtones",
+			midtones: [3]float64{0, 0.5, 0},
+			color:    color.RGBA{128, 128, 128, 255},
+			want:     color.RGBA{128, 217, 128, 255},
+		},
+		{
+			name:       "yellow highlights",
+			highlights: [3]float64{0, 0, -1},
+			color:      color.RGBA{255, 255, 255, 255},
+			want:       color.RGBA{255, 255, 77, 255},
+		},
+		{
+			name:       "other ranges",
+			shadows:    [3]float64{0, 0, -1},
+			highlights: [3]float64{-1, 0, 0},
+			color:      color.RGBA{0, 0, 255, 255},
+			want:       color.RGBA{0, 0, 255, 255},
+		},
+		{
+			name:    "clamped shifts",
+			shadows: [3]float64{5, 0, 0},
+			color:   color.RGBA{0, 0, 0, 255},
+			want:    color.RGBA{179, 0, 0, 255},
+		},
+		{
+			name:    "semi-transparent color",
+			shadows: [3]float64{1, 0, 0},
+			color:   color.RGBA{0, 0, 0, 128},
+			want:    color.RGBA{90, 0, 0, 128},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			balance := NewColorBalance(test.shadows, test.midtones,
+				test.highlights)
+			got := balance.ModifyPixel(image.Point{}, test.color, nil)
+			assert.Equal(t, test.want, got,
+				"ModifyPixel(%#v) = %#v, want %#v", test.color, got, test.want)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package mods
+
+import "math"
+
+// rgbToHSL converts the red, green and blue channels in the range [0, 1] to
+// the hue in degrees in the range [0, 360), and the saturation and the
+// lightness in the range [0, 1]. The hue of gray colors is 0.
+func rgbToHSL(r, g, b float64) (h, s, l float64) {
+	high := math.Max(r, math.Max(g, b))
+	low := math.Min(r, math.Min(g, b))
+	l = (high + low) / 2
+	chroma := high - low
+	if chroma == 0 {
+		return 0, 0, l
+	}
+
+	s = chroma / (1 - math.Abs(2*l-1))
+	switch high {
+	case r:
+		h = (g - b) / chroma
+	case g:
+		h = (b-r)/chroma + 2
+	default:
+		h = (r-g)/chroma + 4
+	}
+	return normalizeHue(h * 60), math.Min(1, s), l
+}
+
+// hslToRGB converts the hue in degrees, and the saturation and the lightness
+// in the range [0, 1] to the red, green and blue channels in the same range.
+func hslToRGB(h, s, l float64) (r, g, b float64) {
+	chroma := (1 - math.Abs(2*l-1)) * s
+	sector := normalizeHue(h) / 60
+	x := chroma * (1 - math.Abs(math.Mod(sector, 2)-1))
+	switch int(sector) {
+	case 0:
+		r, g, b = chroma, x, 0
+	case 1:
+		r, g, b = x, chroma, 0
+	case 2:
+		r, g, b = 0, chroma, x
+	case 3:
+		r, g, b = 0, x, chroma
+	case 4:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+	m := l - chroma/2
+	return r + m, g + m, b + m
+}
+
+// normalizeHue returns the hue in degrees in the range [0, 360).
+func normalizeHue(h float64) float64 {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	if h >= 360 {
+		// A tiny negative hue is rounded up to 360 by the addition.
+		h = 0
+	}
+	return h
+}
+
+// hueDistance returns the angle between two hues in degrees in the range
+// [0, 180].
+func hueDistance(a, b float64) float64 {
+	distance := math.Abs(normalizeHue(a - b))
+	return math.Min(distance, 360-distance)
+}
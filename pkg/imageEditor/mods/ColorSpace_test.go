@@ -0,0 +1,42 @@
+package mods
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_rgbToHSL(t *testing.T) {
+	tests := []struct {
+		name    string
+		rgb     [3]float64
+		wantHSL [3]float64
+	}{
+		{"black", [3]float64{0, 0, 0}, [3]float64{0, 0, 0}},
+		{"white", [3]float64{1, 1, 1}, [3]float64{0, 0, 1}},
+		{"gray", [3]float64{0.5, 0.5, 0.5}, [3]float64{0, 0, 0.5}},
+		{"red", [3]float64{1, 0, 0}, [3]float64{0, 1, 0.5}},
+		{"light green", [3]float64{0.5, 1, 0.5}, [3]float64{120, 1, 0.75}},
+		{"dark blue", [3]float64{0, 0, 0.5}, [3]float64{240, 1, 0.25}},
+		{"magenta", [3]float64{1, 0, 1}, [3]float64{300, 1, 0.5}},
+		{"pale orange", [3]float64{0.8, 0.6, 0.4}, [3]float64{30, 0.5, 0.6}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, s, l := rgbToHSL(test.rgb[0], test.rgb[1], test.rgb[2])
+			assert.InDeltaSlice(t, test.wantHSL[:], []float64{h, s, l}, 1e-9,
+				"rgbToHSL(%v)", test.rgb)
+
+			r, g, b := hslToRGB(h, s, l)
+			assert.InDeltaSlice(t, test.rgb[:], []float64{r, g, b}, 1e-9,
+				"hslToRGB must restore the color")
+		})
+	}
+}
+
+func Test_hueDistance(t *testing.T) {
+	assert.InDelta(t, 30, hueDistance(10, 340), 1e-9)
+	assert.InDelta(t, 30, hueDistance(340, 10), 1e-9)
+	assert.InDelta(t, 180, hueDistance(0, 180), 1e-9)
+	assert.InDelta(t, 0, hueDistance(-90, 270), 1e-9)
+}
@@ -0,0 +1,47 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+)
+
+// NewContrast creates a new Contrast object. amount is in the range [-1, 1]:
+// -1 makes the image uniformly gray, 0 keeps it unchanged and 1 turns every
+// channel into either black or white.
+func NewContrast(amount float64) *Contrast {
+	factor := (1 + amount) / (1 - amount)
+	curve := func(value float64) float64 {
+		// The midpoint lies between two channel values, so that the maximum
+		// contrast never leaves a channel in the middle.
+		const midpoint = 127.5 / 255
+		return (value-midpoint)*factor + midpoint
+	}
+	return &Contrast{newLookupTable(curve), newDeepLookupTable(curve)}
+}
+
+// Contrast is a type representing a modifier that changes the contrast of an
+// image.
+type Contrast struct {
+	// values stores the new values for each channel value.
+	values lookupTable
+	// deepValues stores the new values for each 16-bit channel value.
+	deepValues *deepLookupTable
+}
+
+// ModifyPixel changes the contrast of the image pixel.
+func (contrast *Contrast) ModifyPixel(_ image.Point, col color.RGBA,
+	_ image.Image) color.RGBA {
+	return contrast.values.apply(col)
+}
+
+// ModifyRow changes the contrast of a row of the image.
+func (contrast *Contrast) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	contrast.values.applyRow(dst, sourceRow(start, dst, src))
+}
+
+// ModifyPixel64 changes the contrast of the 16-bit image pixel.
+func (contrast *Contrast) ModifyPixel64(_ image.Point, col color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	return contrast.deepValues.apply64(col)
+}
@@ -0,0 +1,58 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContrast_ModifyPixel(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount float64
+		color  color.RGBA
+		want   color.RGBA
+	}{
+		{
+			name:   "unchanged contrast",
+			amount: 0,
+			color:  color.RGBA{1, 128, 255, 255},
+			want:   color.RGBA{1, 128, 255, 255},
+		},
+		{
+			name:   "higher contrast",
+			amount: 1.0 / 3,
+			color:  color.RGBA{64, 128, 192, 255},
+			want:   color.RGBA{1, 129, 255, 255},
+		},
+		{
+			name:   "lower contrast",
+			amount: -1.0 / 3,
+			color:  color.RGBA{64, 128, 192, 255},
+			want:   color.RGBA{96, 128, 160, 255},
+		},
+		{
+			name:   "maximum contrast",
+			amount: 1,
+			color:  color.RGBA{127, 128, 10, 255},
+			want:   color.RGBA{0, 255, 0, 255},
+		},
+		{
+			name:   "minimum contrast",
+			amount: -1,
+			color:  color.RGBA{0, 100, 255, 255},
+			want:   color.RGBA{128, 128, 128, 255},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NewContrast(test.amount).ModifyPixel(image.Point{},
+				test.color, nil)
+			assert.Equal(t, test.want, got,
+				"Contrast.ModifyPixel(image.Point{}, %#v, nil) = %#v, want %#v",
+				test.color, got, test.want)
+		})
+	}
+}
@@ -0,0 +1,391 @@
+package mods
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+// ConvolutionOptions are the optional settings of a Convolution.
+type ConvolutionOptions struct {
+	// Divisor divides the weighted sum of the pixels. If it is 0, the sum of
+	// the kernel weights is used, or 1 if the weights sum up to 0.
+	Divisor float64
+	// Bias is added to every channel after the division as a fraction of the
+	// full range, e.g. 0.5 for the emboss filter.
+	Bias float64
+	// Edge is the edge mode of the pixels outside the image. DEFAULT_EDGE is
+	// used if it is empty.
+	Edge string
+	// PreserveAlpha keeps the alpha of the pixel and convolves only the colors.
+	PreserveAlpha bool
+	// Linear convolves the pixels in linear light, see FilterOptions.
+	Linear bool
+}
+
+// NewConvolution creates a new Convolution object with the kernel given as rows
+// of weights. The kernel must be rectangular with an odd number of rows and
+// columns; its center is applied to the modified pixel. Kernels that are the
+// product of a column and a row are detected and applied in two passes.
+// Returns an error if the kernel or the options are incorrect.
+func NewConvolution(kernel [][]float64,
+	options ConvolutionOptions) (*Convolution, error) {
+	if len(kernel) == 0 || len(kernel[0]) == 0 {
+		return nil, errors.New("the kernel is empty")
+	}
+
+	height, width := len(kernel), len(kernel[0])
+	if width%2 == 0 || height%2 == 0 {
+		return nil, errors.New("the kernel must have an odd number of rows and columns")
+	}
+
+	weights := make([]float64, 0, width*height)
+	for _, row := range kernel {
+		if len(row) != width {
+			return nil, errors.New("the kernel rows must have the same length")
+		}
+		weights = append(weights, row...)
+	}
+
+	total, err := weightSum(weights)
+	if err != nil {
+		return nil, err
+	}
+	convolution, err := newConvolution(total, width, height, options)
+	if err != nil {
+		return nil, err
+	}
+	convolution.kernel = weights
+	convolution.setFactors(separate(kernel))
+	return convolution, nil
+}
+
+// NewSeparableConvolution creates a new Convolution object with the kernel that
+// is the product of the vertical column and the horizontal row. It is applied
+// in two passes, which is much faster for large kernels. Both slices must have
+// an odd length. Returns an error if the kernel or the options are incorrect.
+func NewSeparableConvolution(horizontal, vertical []float64,
+	options ConvolutionOptions) (*Convolution, error) {
+	if len(horizontal)%2 == 0 || len(vertical)%2 == 0 {
+		return nil, errors.New("the kernel must have an odd number of rows and columns")
+	}
+
+	// The kernel itself is never used, so only the factors are stored.
+	horizontalTotal, err := weightSum(horizontal)
+	if err != nil {
+		return nil, err
+	}
+	verticalTotal, err := weightSum(vertical)
+	if err != nil {
+		return nil, err
+	}
+
+	convolution, err := newConvolution(horizontalTotal*verticalTotal,
+		len(horizontal), len(vertical), options)
+	if err != nil {
+		return nil, err
+	}
+	convolution.setFactors(append([]float64(nil), horizontal...),
+		append([]float64(nil), vertical...))
+	return convolution, nil
+}
+
+// setFactors sets the factors of a separable kernel, or nil if the kernel is not
+// separable.
+func (convolution *Convolution) setFactors(horizontal, vertical []float64) {
+	convolution.horizontal, convolution.vertical = horizontal, vertical
+	convolution.horizontalTotal, convolution.verticalTotal = 0, 0
+	for _, weight := range horizontal {
+		convolution.horizontalTotal += weight
+	}
+	for _, weight := range vertical {
+		convolution.verticalTotal += weight
+	}
+}
+
+// weightSum returns the sum of the weights. Returns an error if a weight is not
+// a finite number.
+func weightSum(weights []float64) (float64, error) {
+	var sum float64
+	for _, weight := range weights {
+		if math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return 0, errors.New("the kernel weights must be finite numbers")
+		}
+		sum += weight
+	}
+	return sum, nil
+}
+
+// newConvolution checks the options and creates the Convolution of the kernel
+// of the given size whose weights sum up to sum, without the weights.
+func newConvolution(sum float64, width, height int,
+	options ConvolutionOptions) (*Convolution, error) {
+	divisor := options.Divisor
+	if math.IsNaN(divisor) || math.IsInf(divisor, 0) {
+		return nil, errors.New("the divisor must be a finite number")
+	}
+	if divisor == 0 {
+		divisor = sum
+	}
+	if math.Abs(divisor) < 1e-9 {
+		divisor = 1
+	}
+
+	if math.IsNaN(options.Bias) || math.IsInf(options.Bias, 0) {
+		return nil, errors.New("the bias must be a finite number")
+	}
+
+	edge := options.Edge
+	if edge == "" {
+		edge = DEFAULT_EDGE
+	}
+	if !ValidateEdge(edge) {
+		return nil, errors.New("unknown edge mode " + edge)
+	}
+
+	return &Convolution{
+		total:         sum,
+		width:         width,
+		height:        height,
+		divisor:       divisor,
+		bias:          options.Bias * 255,
+		edge:          edge,
+		preserveAlpha: options.PreserveAlpha,
+		linear:        options.Linear,
+	}, nil
+}
+
+// separate splits the kernel into a column and a row whose product is the
+// kernel. It returns nil slices if the kernel is not separable.
+func separate(kernel [][]float64) (horizontal, vertical []float64) {
+	// The row and the column of the largest weight are used as the factors.
+	var pivotRow, pivotColumn int
+	var largest float64
+	for i, row := range kernel {
+		for j, weight := range row {
+			if math.Abs(weight) > largest {
+				pivotRow, pivotColumn, largest = i, j, math.Abs(weight)
+			}
+		}
+	}
+	if largest == 0 {
+		return nil, nil
+	}
+
+	pivot := kernel[pivotRow][pivotColumn]
+	horizontal = append([]float64(nil), kernel[pivotRow]...)
+	vertical = make([]float64, len(kernel))
+	for i, row := range kernel {
+		vertical[i] = row[pivotColumn] / pivot
+	}
+
+	for i, row := range kernel {
+		for j, weight := range row {
+			if math.Abs(vertical[i]*horizontal[j]-weight) > 1e-9*largest {
+				return nil, nil
+			}
+		}
+	}
+	return horizontal, vertical
+}
+
+// Convolution is a type representing a modifier that replaces every pixel with
+// the weighted sum of its neighbourhood. The sum is calculated on the
+// premultiplied channels.
+type Convolution struct {
+	// kernel stores the weights row by row. It is nil if the convolution was
+	// created from the separable factors.
+	kernel []float64
+	// total is the sum of the kernel weights.
+	total         float64
+	width, height int
+	// horizontal and vertical are the factors of a separable kernel, or nil.
+	horizontal, vertical []float64
+	// horizontalTotal and verticalTotal are the sums of the factors.
+	horizontalTotal, verticalTotal float64
+	divisor                        float64
+	// bias is added to the channels in the range from 0 to 255.
+	bias          float64
+	edge          string
+	preserveAlpha bool
+	// linear is true if the channels are convolved in linear light.
+	linear bool
+	// pass stores the horizontal pass of a separable kernel.
+	pass sourceCache[[]float32]
+}
+
+// ModifyPixel applies the kernel to the image pixel.
+func (convolution *Convolution) ModifyPixel(position image.Point,
+	col color.RGBA, src image.Image) color.RGBA {
+	var sum [4]float64
+	if convolution.horizontal != nil {
+		sum = convolution.separableSum(position, src)
+	} else {
+		sum = convolution.sum(position, src)
+	}
+
+	for c := range sum {
+		sum[c] = sum[c]/convolution.divisor + convolution.bias
+	}
+	alpha := float64(col.A)
+	if !convolution.preserveAlpha {
+		alpha = clampChannel(sum[3], 255)
+	}
+	if convolution.linear {
+		return fromLinear(sum[0], sum[1], sum[2], alpha)
+	}
+	return color.RGBA{
+		uint8(clampChannel(sum[0], alpha)),
+		uint8(clampChannel(sum[1], alpha)),
+		uint8(clampChannel(sum[2], alpha)),
+		uint8(alpha),
+	}
+}
+
+// channels returns the channels of the color that are convolved.
+func (convolution *Convolution) channels(col color.RGBA) [4]float64 {
+	if convolution.linear {
+		return toLinear(col)
+	}
+	return [4]float64{float64(col.R), float64(col.G), float64(col.B),
+		float64(col.A)}
+}
+
+// sum returns the weighted sums of the channels around the position.
+func (convolution *Convolution) sum(position image.Point,
+	src image.Image) [4]float64 {
+	var sum [4]float64
+	var used float64
+	left := position.X - convolution.width/2
+	top := position.Y - convolution.height/2
+	for i := 0; i < convolution.height; i++ {
+		for j := 0; j < convolution.width; j++ {
+			weight := convolution.kernel[i*convolution.width+j]
+			if weight == 0 {
+				continue
+			}
+			col, ok := sampleAt(src, left+j, top+i, convolution.edge)
+			if !ok {
+				continue
+			}
+			for c, value := range convolution.channels(col) {
+				sum[c] += weight * value
+			}
+			used += weight
+		}
+	}
+	return scaleSum(sum, renormalization(convolution.edge, convolution.total,
+		used))
+}
+
+// separableSum returns the weighted sums of the channels around the position
+// by applying the vertical factor to the horizontal pass of the image.
+func (convolution *Convolution) separableSum(position image.Point,
+	src image.Image) [4]float64 {
+	bounds := src.Bounds()
+	// The pass is prepared with the context by ModifyPixelsContext.
+	values, _ := convolution.pass.get(context.Background(), src,
+		convolution.horizontalPass)
+
+	var sum [4]float64
+	var used float64
+	top := position.Y - len(convolution.vertical)/2
+	for i, weight := range convolution.vertical {
+		y, ok := edgeCoordinate(convolution.edge, top+i, bounds.Min.Y,
+			bounds.Max.Y)
+		if !ok || weight == 0 {
+			continue
+		}
+		offset := ((y-bounds.Min.Y)*bounds.Dx() + position.X - bounds.Min.X) * 4
+		for c := range sum {
+			sum[c] += weight * float64(values[offset+c])
+		}
+		used += weight
+	}
+	return scaleSum(sum, renormalization(convolution.edge,
+		convolution.verticalTotal, used))
+}
+
+// prepare applies the horizontal factor of a separable kernel to the source
+// image unless it is already applied.
+func (convolution *Convolution) prepare(ctx context.Context,
+	src image.Image) error {
+	if convolution.horizontal == nil {
+		return nil
+	}
+	_, err := convolution.pass.get(ctx, src, convolution.horizontalPass)
+	return err
+}
+
+// horizontalPass applies the horizontal factor of the kernel to every pixel of
+// the image and returns the channels of the result. It stops when the context
+// is done and returns the error of the context.
+func (convolution *Convolution) horizontalPass(ctx context.Context,
+	src image.Image) ([]float32, error) {
+	bounds := src.Bounds()
+	values := make([]float32, bounds.Dx()*bounds.Dy()*4)
+	offset := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			var sum [4]float64
+			var used float64
+			left := x - len(convolution.horizontal)/2
+			for j, weight := range convolution.horizontal {
+				if weight == 0 {
+					continue
+				}
+				col, ok := sampleAt(src, left+j, y, convolution.edge)
+				if !ok {
+					continue
+				}
+				for c, value := range convolution.channels(col) {
+					sum[c] += weight * value
+				}
+				used += weight
+			}
+			sum = scaleSum(sum, renormalization(convolution.edge,
+				convolution.horizontalTotal, used))
+			for c := range sum {
+				values[offset+c] = float32(sum[c])
+			}
+			offset += 4
+		}
+	}
+	return values, nil
+}
+
+// sampleAt returns the pixel of the image at the coordinates, which are mapped
+// into the image according to the edge mode. It returns false if the pixel is
+// outside the image and has to be skipped.
+func sampleAt(src image.Image, x, y int, edge string) (color.RGBA, bool) {
+	bounds := src.Bounds()
+	x, okX := edgeCoordinate(edge, x, bounds.Min.X, bounds.Max.X)
+	y, okY := edgeCoordinate(edge, y, bounds.Min.Y, bounds.Max.Y)
+	if !okX || !okY {
+		return color.RGBA{}, false
+	}
+
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba.RGBAAt(x, y), true
+	}
+	return color.RGBAModel.Convert(src.At(x, y)).(color.RGBA), true
+}
+
+// scaleSum multiplies the channel sums by the factor.
+func scaleSum(sum [4]float64, factor float64) [4]float64 {
+	for c := range sum {
+		sum[c] *= factor
+	}
+	return sum
+}
+
+// clampChannel rounds the channel value and limits it to the range from 0 to
+// max.
+func clampChannel(value, max float64) float64 {
+	return math.Max(0, math.Min(max, math.Round(value)))
+}
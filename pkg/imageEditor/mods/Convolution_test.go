@@ -0,0 +1,200 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newGrayImage creates an opaque image with the gray values given row by row.
+func newGrayImage(values [][]uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(values[0]), len(values)))
+	for y, row := range values {
+		for x, value := range row {
+			img.SetRGBA(x, y, color.RGBA{value, value, value, 255})
+		}
+	}
+	return img
+}
+
+func TestNewConvolution_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		kernel  [][]float64
+		options ConvolutionOptions
+	}{
+		{"empty kernel", [][]float64{}, ConvolutionOptions{}},
+		{"even size", [][]float64{{1, 1}, {1, 1}}, ConvolutionOptions{}},
+		{"different row lengths", [][]float64{{1}, {1, 2, 1}, {1}},
+			ConvolutionOptions{}},
+		{"unknown edge", [][]float64{{1}}, ConvolutionOptions{Edge: "repeat"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewConvolution(test.kernel, test.options)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewConvolution_separable(t *testing.T) {
+	convolution, err := NewConvolution([][]float64{
+		{1, 2, 1},
+		{2, 4, 2},
+		{1, 2, 1},
+	}, ConvolutionOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 16.0, convolution.divisor)
+	assert.Equal(t, []float64{0.5, 1, 0.5}, convolution.vertical)
+	assert.Equal(t, []float64{2, 4, 2}, convolution.horizontal)
+
+	convolution, err = NewConvolution([][]float64{
+		{0, -1, 0},
+		{-1, 5, -1},
+		{0, -1, 0},
+	}, ConvolutionOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, convolution.horizontal, "the sharpen kernel is not separable")
+}
+
+func TestNewSeparableConvolution(t *testing.T) {
+	convolution, err := NewSeparableConvolution([]float64{1, 2, 1},
+		[]float64{1, 0, 1}, ConvolutionOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 8.0, convolution.divisor)
+	assert.Equal(t, 4.0, convolution.horizontalTotal)
+	assert.Equal(t, 2.0, convolution.verticalTotal)
+
+	_, err = NewSeparableConvolution([]float64{1, math.NaN(), 1},
+		[]float64{1}, ConvolutionOptions{})
+	assert.Error(t, err)
+	_, err = NewSeparableConvolution([]float64{1, 1}, []float64{1},
+		ConvolutionOptions{})
+	assert.Error(t, err)
+}
+
+func TestConvolution_ModifyPixel(t *testing.T) {
+	img := newGrayImage([][]uint8{
+		{10, 20, 30},
+		{40, 50, 60},
+		{70, 80, 90},
+	})
+	box := [][]float64{{1, 1, 1}, {1, 1, 1}, {1, 1, 1}}
+	tests := []struct {
+		name     string
+		kernel   [][]float64
+		options  ConvolutionOptions
+		position image.Point
+		want     color.RGBA
+	}{
+		{
+			name:     "box center",
+			kernel:   box,
+			position: image.Pt(1, 1),
+			want:     color.RGBA{50, 50, 50, 255},
+		},
+		{
+			name:     "box clamp",
+			kernel:   box,
+			position: image.Pt(0, 0),
+			want:     color.RGBA{23, 23, 23, 255},
+		},
+		{
+			name:     "box mirror",
+			kernel:   box,
+			options:  ConvolutionOptions{Edge: EDGE_MIRROR},
+			position: image.Pt(0, 0),
+			want:     color.RGBA{37, 37, 37, 255},
+		},
+		{
+			name:     "box wrap",
+			kernel:   box,
+			options:  ConvolutionOptions{Edge: EDGE_WRAP},
+			position: image.Pt(0, 0),
+			want:     color.RGBA{50, 50, 50, 255},
+		},
+		{
+			name:     "box transparent",
+			kernel:   box,
+			options:  ConvolutionOptions{Edge: EDGE_TRANSPARENT},
+			position: image.Pt(0, 0),
+			want:     color.RGBA{13, 13, 13, 113},
+		},
+		{
+			name:     "box renormalize",
+			kernel:   box,
+			options:  ConvolutionOptions{Edge: EDGE_RENORMALIZE},
+			position: image.Pt(0, 0),
+			want:     color.RGBA{30, 30, 30, 255},
+		},
+		{
+			name: "sharpen renormalize",
+			kernel: [][]float64{
+				{0, -1, 0},
+				{-1, 5, -1},
+				{0, -1, 0},
+			},
+			options:  ConvolutionOptions{Edge: EDGE_RENORMALIZE},
+			position: image.Pt(0, 0),
+			want:     color.RGBA{0, 0, 0, 255},
+		},
+		{
+			name:     "divisor",
+			kernel:   [][]float64{{2}},
+			options:  ConvolutionOptions{Divisor: 1},
+			position: image.Pt(1, 0),
+			want:     color.RGBA{40, 40, 40, 255},
+		},
+		{
+			name:     "bias",
+			kernel:   [][]float64{{1}},
+			options:  ConvolutionOptions{Bias: 0.5, PreserveAlpha: true},
+			position: image.Pt(0, 0),
+			want:     color.RGBA{138, 138, 138, 255},
+		},
+		{
+			name: "zero sum",
+			kernel: [][]float64{
+				{-1, -1, -1},
+				{-1, 8, -1},
+				{-1, -1, -1},
+			},
+			options:  ConvolutionOptions{PreserveAlpha: true},
+			position: image.Pt(1, 1),
+			want:     color.RGBA{0, 0, 0, 255},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			convolution, err := NewConvolution(test.kernel, test.options)
+			assert.NoError(t, err)
+			col := img.RGBAAt(test.position.X, test.position.Y)
+			got := convolution.ModifyPixel(test.position, col, img)
+			assert.Equal(t, test.want, got,
+				"Convolution.ModifyPixel(%v) = %#v, want %#v",
+				test.position, got, test.want)
+
+			// The separable and the full kernel must give the same result.
+			convolution.horizontal, convolution.vertical = nil, nil
+			got = convolution.ModifyPixel(test.position, col, img)
+			assert.Equal(t, test.want, got,
+				"Convolution.ModifyPixel(%v) without the separable kernel = %#v, want %#v",
+				test.position, got, test.want)
+		})
+	}
+}
+
+func TestConvolution_ModifyPixel_preserveAlpha(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{50, 50, 50, 100})
+	convolution, err := NewConvolution([][]float64{{1}},
+		ConvolutionOptions{Bias: 0.5, PreserveAlpha: true})
+	assert.NoError(t, err)
+
+	got := convolution.ModifyPixel(image.Pt(0, 0), img.RGBAAt(0, 0), img)
+	want := color.RGBA{100, 100, 100, 100}
+	assert.Equal(t, want, got, "the colors must not exceed the alpha")
+}
@@ -20,3 +20,14 @@ func (copy *Copy) ModifyPixel(_ image.Point, c color.RGBA,
 	_ image.Image) color.RGBA {
 	return c
 }
+
+// ModifyRow copies the row of the image.
+func (*Copy) ModifyRow(start image.Point, dst []uint8, src *image.RGBA) {
+	copy(dst, sourceRow(start, dst, src))
+}
+
+// ModifyPixel64 returns the received 16-bit pixel of the image.
+func (*Copy) ModifyPixel64(_ image.Point, c color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	return c
+}
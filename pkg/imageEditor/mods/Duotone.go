@@ -0,0 +1,103 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// NewDuotone creates a new Duotone object that maps the shadows of an image to
+// the shadows color and the highlights to the highlights color. The alpha of
+// the colors is ignored.
+func NewDuotone(shadows, highlights color.Color) *Duotone {
+	duotone := &Duotone{weights: lumaWeights[GRAYSCALE_REC709]}
+	for i, col := range [2]color.Color{shadows, highlights} {
+		nrgba := color.NRGBAModel.Convert(col).(color.NRGBA)
+		duotone.colors[i] = [3]float64{
+			float64(nrgba.R) / 255, float64(nrgba.G) / 255,
+			float64(nrgba.B) / 255,
+		}
+	}
+	for i := range duotone.values {
+		for j, value := range duotone.gradient(float64(i) / 255) {
+			duotone.values[i][j] = uint8(math.Round(value * 255))
+		}
+	}
+	return duotone
+}
+
+// Duotone is a type representing a modifier that replaces the colors of an
+// image with a gradient between two colors by the luma of the pixels.
+type Duotone struct {
+	// colors are the shadows and the highlights colors in the range [0, 1].
+	colors [2][3]float64
+	// weights are the channel weights of the luma out of 1 << 16.
+	weights [3]uint32
+	// values stores the gradient color for each luma value.
+	values [256][3]uint8
+}
+
+// ModifyPixel replaces the color of the image pixel.
+func (duotone *Duotone) ModifyPixel(_ image.Point, col color.RGBA,
+	_ image.Image) color.RGBA {
+	col.R, col.G, col.B = duotone.tone(col.R, col.G, col.B, col.A)
+	return col
+}
+
+// ModifyRow replaces the colors of a row of the image.
+func (duotone *Duotone) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	row := sourceRow(start, dst, src)
+	for i := 0; i < len(dst); i += 4 {
+		dst[i], dst[i+1], dst[i+2] = duotone.tone(row[i], row[i+1], row[i+2],
+			row[i+3])
+		dst[i+3] = row[i+3]
+	}
+}
+
+// ModifyPixel64 replaces the color of the 16-bit image pixel.
+func (duotone *Duotone) ModifyPixel64(_ image.Point, col color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	if col.A == 0 {
+		return col
+	}
+
+	weights := duotone.weights
+	luma := float64(uint64(weights[0])*uint64(col.R)+
+		uint64(weights[1])*uint64(col.G)+uint64(weights[2])*uint64(col.B)) /
+		(1 << 16)
+	alpha := float64(col.A)
+	var channels [3]uint16
+	for i, value := range duotone.gradient(math.Min(1, luma/alpha)) {
+		channels[i] = uint16(math.Round(value * alpha))
+	}
+	col.R, col.G, col.B = channels[0], channels[1], channels[2]
+	return col
+}
+
+// tone returns the premultiplied gradient color for the premultiplied
+// channels.
+func (duotone *Duotone) tone(r, g, b, a uint8) (uint8, uint8, uint8) {
+	if a == 0 {
+		return r, g, b
+	}
+
+	weights := duotone.weights
+	luma := uint8((weights[0]*uint32(r) + weights[1]*uint32(g) +
+		weights[2]*uint32(b) + 1<<15) >> 16)
+	alpha := uint32(a)
+	values := &duotone.values[unpremultiply(luma, alpha)]
+	return premultiply(values[0], alpha), premultiply(values[1], alpha),
+		premultiply(values[2], alpha)
+}
+
+// gradient returns the color of the gradient in the range [0, 1] for the luma
+// in the same range.
+func (duotone *Duotone) gradient(luma float64) [3]float64 {
+	var result [3]float64
+	for i := range result {
+		result[i] = duotone.colors[0][i] +
+			(duotone.colors[1][i]-duotone.colors[0][i])*luma
+	}
+	return result
+}
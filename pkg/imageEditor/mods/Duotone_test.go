@@ -0,0 +1,42 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuotone_ModifyPixel(t *testing.T) {
+	navy := color.NRGBA{0, 0, 128, 255}
+	yellow := color.NRGBA{255, 255, 0, 255}
+	tests := []struct {
+		name       string
+		shadows    color.Color
+		highlights color.Color
+		color      color.RGBA
+		want       color.RGBA
+	}{
+		{"black", navy, yellow, color.RGBA{0, 0, 0, 255},
+			color.RGBA{0, 0, 128, 255}},
+		{"white", navy, yellow, color.RGBA{255, 255, 255, 255},
+			color.RGBA{255, 255, 0, 255}},
+		{"gray", navy, yellow, color.RGBA{128, 128, 128, 255},
+			color.RGBA{128, 128, 64, 255}},
+		{"semi-transparent color", navy, yellow,
+			color.RGBA{128, 128, 128, 128}, color.RGBA{128, 128, 0, 128}},
+		{"transparent color", navy, yellow, color.RGBA{},
+			color.RGBA{}},
+		{"black and white", color.Black, color.White,
+			color.RGBA{0, 255, 0, 255}, color.RGBA{182, 182, 182, 255}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			duotone := NewDuotone(test.shadows, test.highlights)
+			got := duotone.ModifyPixel(image.Point{}, test.color, nil)
+			assert.Equal(t, test.want, got,
+				"ModifyPixel(%#v) = %#v, want %#v", test.color, got, test.want)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package mods
+
+import "math"
+
+// Edge modes set how neighbourhood filters sample pixels outside the image.
+const (
+	// EDGE_CLAMP repeats the nearest edge pixel.
+	EDGE_CLAMP = "clamp"
+	// EDGE_MIRROR reflects the image at its edges.
+	EDGE_MIRROR = "mirror"
+	// EDGE_WRAP continues with the pixels from the opposite edge.
+	EDGE_WRAP = "wrap"
+	// EDGE_TRANSPARENT treats the outside pixels as transparent black.
+	EDGE_TRANSPARENT = "transparent"
+	// EDGE_RENORMALIZE skips the outside pixels and scales the weighted sum as
+	// if the skipped weights were spread over the inside pixels.
+	EDGE_RENORMALIZE = "renormalize"
+)
+
+const DEFAULT_EDGE = EDGE_CLAMP
+
+// ValidateEdge checks whether a string value is a valid edge mode.
+func ValidateEdge(edge string) bool {
+	switch edge {
+	case EDGE_CLAMP, EDGE_MIRROR, EDGE_WRAP, EDGE_TRANSPARENT,
+		EDGE_RENORMALIZE:
+		return true
+	default:
+		return false
+	}
+}
+
+// edgeOrDefault returns the edge mode, or def if it is not valid.
+func edgeOrDefault(edge, def string) string {
+	if ValidateEdge(edge) {
+		return edge
+	}
+	return def
+}
+
+// edgeCoordinate maps the coordinate into the range [min, max) according to
+// the edge mode. It returns false if the pixel has to be skipped.
+func edgeCoordinate(edge string, value, min, max int) (int, bool) {
+	if value >= min && value < max {
+		return value, true
+	}
+
+	size := max - min
+	if size <= 0 {
+		return 0, false
+	}
+
+	switch edge {
+	case EDGE_MIRROR:
+		if size == 1 {
+			return min, true
+		}
+		period := 2 * (size - 1)
+		offset := ((value-min)%period + period) % period
+		if offset >= size {
+			offset = period - offset
+		}
+		return min + offset, true
+	case EDGE_WRAP:
+		return min + ((value-min)%size+size)%size, true
+	case EDGE_TRANSPARENT, EDGE_RENORMALIZE:
+		return 0, false
+	default:
+		if value < min {
+			return min, true
+		}
+		return max - 1, true
+	}
+}
+
+// renormalization returns the factor of a weighted sum in the edge mode. In the
+// renormalize mode it is the ratio of the total weight of the kernel to the
+// weight of the used samples, otherwise and for kernels whose weights sum up to
+// zero it is 1.
+func renormalization(edge string, total, used float64) float64 {
+	if edge != EDGE_RENORMALIZE || math.Abs(total) < 1e-9 ||
+		math.Abs(used) < 1e-9 {
+		return 1
+	}
+	return total / used
+}
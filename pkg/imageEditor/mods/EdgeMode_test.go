@@ -0,0 +1,79 @@
+package mods
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEdge(t *testing.T) {
+	for _, edge := range []string{EDGE_CLAMP, EDGE_MIRROR, EDGE_WRAP,
+		EDGE_TRANSPARENT, EDGE_RENORMALIZE} {
+		assert.True(t, ValidateEdge(edge), "ValidateEdge(%q) = false", edge)
+	}
+	assert.False(t, ValidateEdge(""), `ValidateEdge("") = true`)
+	assert.False(t, ValidateEdge("repeat"), `ValidateEdge("repeat") = true`)
+}
+
+func Test_edgeCoordinate(t *testing.T) {
+	tests := []struct {
+		name   string
+		edge   string
+		value  int
+		want   int
+		wantOk bool
+	}{
+		{"inside", EDGE_TRANSPARENT, 3, 3, true},
+		{"clamp before", EDGE_CLAMP, -2, 1, true},
+		{"clamp after", EDGE_CLAMP, 9, 4, true},
+		{"mirror before", EDGE_MIRROR, -1, 3, true},
+		{"mirror after", EDGE_MIRROR, 6, 2, true},
+		{"mirror far", EDGE_MIRROR, 9, 3, true},
+		{"wrap before", EDGE_WRAP, -1, 3, true},
+		{"wrap after", EDGE_WRAP, 8, 4, true},
+		{"transparent", EDGE_TRANSPARENT, 0, 0, false},
+		{"renormalize", EDGE_RENORMALIZE, 5, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := edgeCoordinate(test.edge, test.value, 1, 5)
+			assert.Equal(t, test.wantOk, ok)
+			if test.wantOk {
+				assert.Equal(t, test.want, got,
+					"edgeCoordinate(%q, %d, 1, 5) = %d, want %d",
+					test.edge, test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func Test_edgeCoordinate_singlePixel(t *testing.T) {
+	for _, edge := range []string{EDGE_CLAMP, EDGE_MIRROR, EDGE_WRAP} {
+		got, ok := edgeCoordinate(edge, -3, 0, 1)
+		assert.True(t, ok)
+		assert.Equal(t, 0, got, "edgeCoordinate(%q, -3, 0, 1) = %d", edge, got)
+	}
+}
+
+func Test_renormalization(t *testing.T) {
+	tests := []struct {
+		name  string
+		edge  string
+		total float64
+		used  float64
+		want  float64
+	}{
+		{"renormalize", EDGE_RENORMALIZE, 9, 4, 2.25},
+		{"other mode", EDGE_TRANSPARENT, 9, 4, 1},
+		{"zero sum kernel", EDGE_RENORMALIZE, 0, 3, 1},
+		{"no used samples", EDGE_RENORMALIZE, 1, 0, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := renormalization(test.edge, test.total, test.used)
+			assert.Equal(t, test.want, got,
+				"renormalization(%q, %g, %g) = %g, want %g",
+				test.edge, test.total, test.used, got, test.want)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// MAX_EXPOSURE_STOPS is the largest exposure change, in either direction,
+// accepted by the pipeline and the server.
+const MAX_EXPOSURE_STOPS = 10
+
+// NewExposure creates a new Exposure object. stops is the exposure change in
+// photographic stops: every stop doubles or halves the amount of light.
+func NewExposure(stops float64) *Exposure {
+	multiplier := math.Pow(2, stops)
+	curve := func(value float64) float64 {
+		// The light is multiplied in the linear space, not in sRGB.
+		return linearToSRGB(srgbToLinear(value) * multiplier)
+	}
+	return &Exposure{newLookupTable(curve), newDeepLookupTable(curve)}
+}
+
+// Exposure is a type representing a modifier that changes the exposure of an
+// image.
+type Exposure struct {
+	// values stores the new values for each channel value.
+	values lookupTable
+	// deepValues stores the new values for each 16-bit channel value.
+	deepValues *deepLookupTable
+}
+
+// ModifyPixel changes the exposure of the image pixel.
+func (exposure *Exposure) ModifyPixel(_ image.Point, col color.RGBA,
+	_ image.Image) color.RGBA {
+	return exposure.values.apply(col)
+}
+
+// srgbToLinear converts the sRGB channel value in the range [0, 1] to linear
+// light.
+func srgbToLinear(value float64) float64 {
+	if value <= 0.04045 {
+		return value / 12.92
+	}
+	return math.Pow((value+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts the linear light value to the sRGB channel value.
+func linearToSRGB(value float64) float64 {
+	if value <= 0.0031308 {
+		return value * 12.92
+	}
+	return 1.055*math.Pow(value, 1/2.4) - 0.055
+}
+
+// ModifyRow changes the exposure of a row of the image.
+func (exposure *Exposure) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	exposure.values.applyRow(dst, sourceRow(start, dst, src))
+}
+
+// ModifyPixel64 changes the exposure of the 16-bit image pixel.
+func (exposure *Exposure) ModifyPixel64(_ image.Point, col color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	return exposure.deepValues.apply64(col)
+}
@@ -0,0 +1,55 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExposure_ModifyPixel(t *testing.T) {
+	tests := []struct {
+		name  string
+		stops float64
+		color color.RGBA
+		want  color.RGBA
+	}{
+		{
+			name:  "unchanged exposure",
+			stops: 0,
+			color: color.RGBA{1, 128, 255, 255},
+			want:  color.RGBA{1, 128, 255, 255},
+		},
+		{
+			name:  "one stop up",
+			stops: 1,
+			color: color.RGBA{0, 128, 255, 255},
+			want:  color.RGBA{0, 176, 255, 255},
+		},
+		{
+			name:  "one stop down",
+			stops: -1,
+			color: color.RGBA{0, 176, 255, 255},
+			want:  color.RGBA{0, 128, 188, 255},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NewExposure(test.stops).ModifyPixel(image.Point{},
+				test.color, nil)
+			assert.Equal(t, test.want, got,
+				"Exposure.ModifyPixel(image.Point{}, %#v, nil) = %#v, want %#v",
+				test.color, got, test.want)
+		})
+	}
+}
+
+func Test_srgbToLinear(t *testing.T) {
+	for i := 0; i <= 255; i++ {
+		value := float64(i) / 255
+		assert.InDelta(t, value, linearToSRGB(srgbToLinear(value)), 1e-9,
+			"conversion of %v must be reversible", value)
+	}
+	assert.InDelta(t, 0.2140, srgbToLinear(0.5), 1e-4)
+}
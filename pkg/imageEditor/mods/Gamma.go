@@ -0,0 +1,52 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// MIN_GAMMA and MAX_GAMMA are the range of the gamma values accepted by the
+// pipeline and the server. Values outside of it make the image almost black or
+// white.
+const (
+	MIN_GAMMA = 0.01
+	MAX_GAMMA = 10
+)
+
+// NewGamma creates a new Gamma object with the given gamma value, which must
+// be positive. Values greater than 1 brighten the midtones, values less than 1
+// darken them.
+func NewGamma(gamma float64) *Gamma {
+	curve := func(value float64) float64 {
+		return math.Pow(value, 1/gamma)
+	}
+	return &Gamma{newLookupTable(curve), newDeepLookupTable(curve)}
+}
+
+// Gamma is a type representing a modifier that applies gamma correction to an
+// image.
+type Gamma struct {
+	// values stores the new values for each channel value.
+	values lookupTable
+	// deepValues stores the new values for each 16-bit channel value.
+	deepValues *deepLookupTable
+}
+
+// ModifyPixel applies gamma correction to the image pixel.
+func (gamma *Gamma) ModifyPixel(_ image.Point, col color.RGBA,
+	_ image.Image) color.RGBA {
+	return gamma.values.apply(col)
+}
+
+// ModifyRow applies the gamma correction to a row of the image.
+func (gamma *Gamma) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	gamma.values.applyRow(dst, sourceRow(start, dst, src))
+}
+
+// ModifyPixel64 applies gamma correction to the 16-bit image pixel.
+func (gamma *Gamma) ModifyPixel64(_ image.Point, col color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	return gamma.deepValues.apply64(col)
+}
@@ -0,0 +1,46 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGamma_ModifyPixel(t *testing.T) {
+	tests := []struct {
+		name  string
+		gamma float64
+		color color.RGBA
+		want  color.RGBA
+	}{
+		{
+			name:  "unchanged gamma",
+			gamma: 1,
+			color: color.RGBA{1, 128, 255, 255},
+			want:  color.RGBA{1, 128, 255, 255},
+		},
+		{
+			name:  "brighter midtones",
+			gamma: 2,
+			color: color.RGBA{0, 64, 255, 255},
+			want:  color.RGBA{0, 128, 255, 255},
+		},
+		{
+			name:  "darker midtones",
+			gamma: 0.5,
+			color: color.RGBA{0, 128, 255, 255},
+			want:  color.RGBA{0, 64, 255, 255},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NewGamma(test.gamma).ModifyPixel(image.Point{},
+				test.color, nil)
+			assert.Equal(t, test.want, got,
+				"Gamma.ModifyPixel(image.Point{}, %#v, nil) = %#v, want %#v",
+				test.color, got, test.want)
+		})
+	}
+}
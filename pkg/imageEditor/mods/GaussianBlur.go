@@ -1,20 +1,56 @@
 package mods
 
 import (
+	"context"
 	"image"
 	"image/color"
 	"math"
+	"runtime"
+	"sync"
 )
 
+// boxBlurSigma is the sigma from which the blur is approximated with three box
+// blurs, whose cost does not depend on the sigma.
+const boxBlurSigma = 8
+
+// MAX_SIGMA is the largest sigma of the gaussian blur. Larger values blur the
+// image as much as MAX_SIGMA.
+const MAX_SIGMA = 1000
+
 // NewGaussianBlur creates a new GaussianBlur object with given sigma value.
-// sigma is the degree of blur.
+// sigma is the degree of blur, which is limited to MAX_SIGMA.
 func NewGaussianBlur(sigma float64) *GaussianBlur {
-	size := int(3 * sigma + 0.5)
-	if size%2 == 0 {
-		size++
+	return NewGaussianBlurWithOptions(sigma, FilterOptions{})
+}
+
+// NewGaussianBlurWithOptions is like NewGaussianBlur, but it uses the edge mode
+// and the linear light of the options. EDGE_RENORMALIZE is used if the edge
+// mode is not valid.
+func NewGaussianBlurWithOptions(sigma float64,
+	options FilterOptions) *GaussianBlur {
+	// The negated comparison also replaces NaN.
+	if !(sigma <= MAX_SIGMA) {
+		sigma = MAX_SIGMA
 	}
+	blur := &GaussianBlur{
+		edge:   edgeOrDefault(options.Edge, EDGE_RENORMALIZE),
+		linear: options.Linear,
+	}
+	if sigma >= boxBlurSigma {
+		blur.boxRadii = boxRadii(sigma, 3)
+	} else {
+		blur.kernel = gaussianKernel(sigma)
+		blur.halfSize = len(blur.kernel) / 2
+	}
+	return blur
+}
 
-	halfSize := size / 2
+// gaussianKernel returns the normalized one-dimensional gaussian kernel of the
+// sigma value. The kernel covers three sigmas on each side, like the box blurs
+// that replace it for a large sigma.
+func gaussianKernel(sigma float64) []float64 {
+	halfSize := int(math.Ceil(3 * sigma))
+	size := 2*halfSize + 1
 
 	kernel := make([]float64, size)
 	var sum float64
@@ -27,57 +63,284 @@ func NewGaussianBlur(sigma float64) *GaussianBlur {
 		kernel[i] /= sum
 	}
 
-	return &GaussianBlur{halfSize, kernel}
+	return kernel
+}
+
+// boxRadii returns the radii of the count box blurs that applied one after
+// another approximate the gaussian blur with the sigma value.
+func boxRadii(sigma float64, count int) []int {
+	n := float64(count)
+	ideal := math.Sqrt(12*sigma*sigma/n + 1)
+	lower := int(ideal)
+	if lower%2 == 0 {
+		lower--
+	}
+	upper := lower + 2
+
+	l := float64(lower)
+	lowerCount := int(math.Round((12*sigma*sigma - n*l*l - 4*n*l - 3*n) /
+		(-4*l - 4)))
+
+	radii := make([]int, count)
+	for i := range radii {
+		if i < lowerCount {
+			radii[i] = lower / 2
+		} else {
+			radii[i] = upper / 2
+		}
+	}
+	return radii
 }
 
 // GaussianBlur represents a type that applies a gaussian blur effect to an
-// image.
+// image. The whole image is blurred in two passes, horizontal and vertical,
+// when the first pixel is modified.
 type GaussianBlur struct {
 	halfSize int
 	kernel   []float64
+	edge     string
+	// boxRadii are the radii of the box blurs that replace the kernel for a
+	// large sigma, or nil.
+	boxRadii []int
+	// linear is true if the image is blurred in linear light.
+	linear bool
+	// blurred stores the blurred source image.
+	blurred sourceCache[*image.RGBA]
 }
 
 // ModifyPixel applies a gaussian blur to an image pixel.
 func (blur *GaussianBlur) ModifyPixel(position image.Point, c color.RGBA,
 	src image.Image) color.RGBA {
+	if !position.In(src.Bounds()) {
+		return c
+	}
+	// The blurred image is prepared with the context by ModifyPixelsContext.
+	blurred, _ := blur.blurred.get(context.Background(), src, blur.blur)
+	return blurred.RGBAAt(position.X, position.Y)
+}
+
+// prepare blurs the source image unless it is already blurred.
+func (blur *GaussianBlur) prepare(ctx context.Context, src image.Image) error {
+	_, err := blur.blurred.get(ctx, src, blur.blur)
+	return err
+}
+
+// blur returns the blurred copy of the image. It stops when the context is done
+// and returns the error of the context.
+func (blur *GaussianBlur) blur(ctx context.Context,
+	src image.Image) (*image.RGBA, error) {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	values := channelValues(src)
+	if blur.linear {
+		linearValues(values)
+	}
+	temp := make([]float32, len(values))
+	rows := channelLines{width * 4, 4, height, width}
+	columns := channelLines{4, width * 4, width, height}
+
+	if blur.boxRadii == nil {
+		if err := kernelPass(ctx, values, temp, rows, blur.kernel,
+			blur.edge); err != nil {
+			return nil, err
+		}
+		if err := kernelPass(ctx, temp, values, columns, blur.kernel,
+			blur.edge); err != nil {
+			return nil, err
+		}
+	} else {
+		// Box blurs commute, so all horizontal passes are done first.
+		for _, lines := range []channelLines{rows, columns} {
+			for _, radius := range blur.boxRadii {
+				if err := boxPass(ctx, values, temp, lines, radius,
+					blur.edge); err != nil {
+					return nil, err
+				}
+				values, temp = temp, values
+			}
+		}
+	}
 
-	var resultR, resultG, resultB, resultA, weight float64
-	imageBounds := src.Bounds()
-	blurBounds := image.Rect(
-		position.X-blur.halfSize,
-		position.Y-blur.halfSize,
-		position.X+blur.halfSize,
-		position.Y+blur.halfSize,
-	)
-
-	for x := blurBounds.Min.X; x <= blurBounds.Max.X; x++ {
-		if x < imageBounds.Min.X || x >= imageBounds.Max.X {
+	dst := image.NewRGBA(bounds)
+	for i := 0; i < len(values); i += 4 {
+		if blur.linear {
+			col := fromLinear(float64(values[i]), float64(values[i+1]),
+				float64(values[i+2]), float64(values[i+3]))
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = col.R, col.G,
+				col.B, col.A
 			continue
 		}
-		for y := blurBounds.Min.Y; y <= blurBounds.Max.Y; y++ {
-			if y < imageBounds.Min.Y || y >= imageBounds.Max.Y {
-				continue
+
+		alpha := clampChannel(float64(values[i+3]), 255)
+		dst.Pix[i] = uint8(clampChannel(float64(values[i]), alpha))
+		dst.Pix[i+1] = uint8(clampChannel(float64(values[i+1]), alpha))
+		dst.Pix[i+2] = uint8(clampChannel(float64(values[i+2]), alpha))
+		dst.Pix[i+3] = uint8(alpha)
+	}
+	return dst, nil
+}
+
+// channelValues returns the premultiplied channels of the image pixels row by
+// row.
+func channelValues(src image.Image) []float32 {
+	bounds := src.Bounds()
+	values := make([]float32, bounds.Dx()*bounds.Dy()*4)
+	i := 0
+	if rgba, ok := src.(*image.RGBA); ok {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			offset := rgba.PixOffset(bounds.Min.X, y)
+			for _, value := range rgba.Pix[offset : offset+bounds.Dx()*4] {
+				values[i] = float32(value)
+				i++
 			}
-			r, g, b, a := src.At(x, y).RGBA()
-			mul := blur.kernel[x-blurBounds.Min.X] * blur.kernel[y-blurBounds.Min.Y]
-
-			resultR += float64(r) * mul
-			resultG += float64(g) * mul
-			resultB += float64(b) * mul
-			resultA += float64(a) * mul
-			weight += mul
 		}
+		return values
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			col := color.RGBAModel.Convert(src.At(x, y)).(color.RGBA)
+			values[i] = float32(col.R)
+			values[i+1] = float32(col.G)
+			values[i+2] = float32(col.B)
+			values[i+3] = float32(col.A)
+			i += 4
+		}
+	}
+	return values
+}
+
+// linearValues converts the premultiplied sRGB channel values to premultiplied
+// linear light.
+func linearValues(values []float32) {
+	for i := 0; i < len(values); i += 4 {
+		col := toLinear(color.RGBA{uint8(values[i]), uint8(values[i+1]),
+			uint8(values[i+2]), uint8(values[i+3])})
+		for c := range col {
+			values[i+c] = float32(col[c])
+		}
+	}
+}
+
+// channelLines describes the rows or the columns of the channel values of an
+// image.
+type channelLines struct {
+	// lineStep is the distance between the first values of two lines.
+	lineStep int
+	// step is the distance between two pixels of a line.
+	step int
+	// count is the number of lines.
+	count int
+	// length is the number of pixels in a line.
+	length int
+}
+
+// each calls pass for ranges of the lines in parallel. The passes stop when the
+// context is done, in which case the error of the context is returned.
+func (lines channelLines) each(ctx context.Context,
+	pass func(start, end int)) error {
+	groupCount := runtime.NumCPU()
+	if groupCount > lines.count {
+		groupCount = lines.count
+	}
+
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(groupCount)
+	for group := 0; group < groupCount; group++ {
+		go func(group int) {
+			defer waitGroup.Done()
+			pass(lines.count*group/groupCount,
+				lines.count*(group+1)/groupCount)
+		}(group)
 	}
+	waitGroup.Wait()
+	return ctx.Err()
+}
 
-	resultR /= weight
-	resultG /= weight
-	resultB /= weight
-	resultA /= weight
+// kernelPass applies the one-dimensional normalized kernel to the lines of src
+// and writes the result to dst. It stops when the context is done and returns
+// the error of the context.
+func kernelPass(ctx context.Context, src, dst []float32, lines channelLines,
+	kernel []float64, edge string) error {
+	halfSize := len(kernel) / 2
+	return lines.each(ctx, func(start, end int) {
+		for line := start; line < end && ctx.Err() == nil; line++ {
+			base := line * lines.lineStep
+			for i := 0; i < lines.length; i++ {
+				var sum [4]float64
+				var used float64
+				for k, weight := range kernel {
+					j, ok := edgeCoordinate(edge, i+k-halfSize, 0, lines.length)
+					if !ok {
+						continue
+					}
+					offset := base + j*lines.step
+					sum[0] += weight * float64(src[offset])
+					sum[1] += weight * float64(src[offset+1])
+					sum[2] += weight * float64(src[offset+2])
+					sum[3] += weight * float64(src[offset+3])
+					used += weight
+				}
+
+				scale := renormalization(edge, 1, used)
+				offset := base + i*lines.step
+				for c := range sum {
+					dst[offset+c] = float32(sum[c] * scale)
+				}
+			}
+		}
+	})
+}
 
-	return color.RGBA{
-		uint8(resultR / 256),
-		uint8(resultG / 256),
-		uint8(resultB / 256),
-		uint8(resultA / 256),
+// boxPass replaces every value of the lines of src with the average of the
+// values within the radius and writes the result to dst. The cost does not
+// depend on the radius, because the sums are calculated from prefix sums. It
+// stops when the context is done and returns the error of the context.
+func boxPass(ctx context.Context, src, dst []float32, lines channelLines,
+	radius int, edge string) error {
+	// The radius is limited to the line length, so that the cost and the
+	// memory stay proportional to the image. A larger radius averages almost
+	// the whole line anyway.
+	if radius > lines.length {
+		radius = lines.length
 	}
+	size := 2*radius + 1
+	return lines.each(ctx, func(start, end int) {
+		// prefix stores the sums of the channels and of the number of used
+		// pixels, from the pixel at -radius to the one before the index.
+		prefix := make([][5]float64, lines.length+size)
+		for line := start; line < end && ctx.Err() == nil; line++ {
+			base := line * lines.lineStep
+			for i := 0; i < lines.length+size-1; i++ {
+				prefix[i+1] = prefix[i]
+				j, ok := edgeCoordinate(edge, i-radius, 0, lines.length)
+				if !ok {
+					continue
+				}
+				offset := base + j*lines.step
+				for c := 0; c < 4; c++ {
+					prefix[i+1][c] += float64(src[offset+c])
+				}
+				prefix[i+1][4]++
+			}
+
+			for i := 0; i < lines.length; i++ {
+				used := prefix[i+size][4] - prefix[i][4]
+				scale := renormalization(edge, float64(size), used) /
+					float64(size)
+				offset := base + i*lines.step
+				for c := 0; c < 4; c++ {
+					dst[offset+c] = float32((prefix[i+size][c] - prefix[i][c]) *
+						scale)
+				}
+			}
+		}
+	})
+}
+
+// ModifyRow copies a row of the blurred image.
+func (blur *GaussianBlur) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	blurred, _ := blur.blurred.get(context.Background(), src, blur.blur)
+	copy(dst, sourceRow(start, dst, blurred))
 }
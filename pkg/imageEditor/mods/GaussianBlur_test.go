@@ -1,7 +1,13 @@
 package mods
 
 import (
+	"context"
+	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -17,58 +23,86 @@ func TestNewGaussianBlur(t *testing.T) {
 			name:  "sigma=1.0",
 			sigma: 1.0,
 			want: &GaussianBlur{
-				halfSize: 1,
+				halfSize: 3,
 				kernel: []float64{
-					0.274068619061197,
-					0.45186276187760605,
-					0.274068619061197,
+					0.004433048175243746,
+					0.05400558262241449,
+					0.24203622937611433,
+					0.3990502796524549,
+					0.24203622937611433,
+					0.05400558262241449,
+					0.004433048175243746,
 				},
+				edge: EDGE_RENORMALIZE,
 			},
 		},
 		{
 			name:  "sigma=1.2",
 			sigma: 1.2,
 			want: &GaussianBlur{
-				halfSize: 2,
+				halfSize: 4,
 				kernel: []float64{
-					0.08562916395501294,
-					0.24266759672960792,
-					0.3434064786307582,
-					0.24266759672960792,
-					0.08562916395501294,
+					0.0012853808857911967,
+					0.01460860354645246,
+					0.08290718675731892,
+					0.23495368672034722,
+					0.33249028418018045,
+					0.23495368672034722,
+					0.08290718675731892,
+					0.01460860354645246,
+					0.0012853808857911967,
 				},
+				edge: EDGE_RENORMALIZE,
 			},
 		},
 		{
 			name:  "sigma=2.0",
 			sigma: 2.0,
 			want: &GaussianBlur{
-				halfSize: 3,
+				halfSize: 6,
 				kernel: []float64{
-					0.07015932695902607,
-					0.13107487896736597,
-					0.19071282356963737,
-					0.21610594100794114,
-					0.19071282356963737,
-					0.13107487896736597,
-					0.07015932695902607,
+					0.0022181958546457652,
+					0.008773134791588384,
+					0.02702315760287952,
+					0.06482518513852682,
+					0.12110939007484811,
+					0.1762131227885508,
+					0.19967562749792106,
+					0.1762131227885508,
+					0.12110939007484811,
+					0.06482518513852682,
+					0.02702315760287952,
+					0.008773134791588384,
+					0.0022181958546457652,
 				},
+				edge: EDGE_RENORMALIZE,
 			},
 		},
 		{
 			name:  "sigma=2.4",
 			sigma: 2.4,
 			want: &GaussianBlur{
-				halfSize: 3,
+				halfSize: 8,
 				kernel: []float64{
-					0.08868143961720941,
-					0.13687640922660566,
-					0.17759311499808242,
-					0.193698072316205,
-					0.17759311499808242,
-					0.13687640922660566,
-					0.08868143961720941,
+					0.0006428481989119644,
+					0.0023637200447722217,
+					0.0073060947010078975,
+					0.018983496595678547,
+					0.04146376865639661,
+					0.07613125501781004,
+					0.11750567944919378,
+					0.15246016286707337,
+					0.16628594893831125,
+					0.15246016286707337,
+					0.11750567944919378,
+					0.07613125501781004,
+					0.04146376865639661,
+					0.018983496595678547,
+					0.0073060947010078975,
+					0.0023637200447722217,
+					0.0006428481989119644,
 				},
+				edge: EDGE_RENORMALIZE,
 			},
 		},
 	}
@@ -77,11 +111,12 @@ func TestNewGaussianBlur(t *testing.T) {
 			got := NewGaussianBlur(test.sigma)
 			assert.Equal(t, test.want, got,
 				"NewGaussianBlur(%g) = %#v, vant %#v",
-				test.sigma, got,  test.want,
+				test.sigma, got, test.want,
 			)
 		})
 	}
 }
+
 func TestGaussianBlur_ModifyPixel(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -91,54 +126,90 @@ func TestGaussianBlur_ModifyPixel(t *testing.T) {
 		wantMatrix   []uint8
 	}{
 		{
-			name:   "sigma=2.0",
-			sigma:  2.0,
-			bounds: image.Rect(0, 0, 2, 2),
+			name:   "sigma=1.0",
+			sigma:  1.0,
+			bounds: image.Rect(0, 0, 9, 1),
 			sourceMatrix: []uint8{
-				1, 45, 3, 4,
-				33, 77, 4, 231,
-				10, 4, 122, 4,
-				4, 5, 10, 23,
+				0, 0, 0, 255,
+				0, 0, 0, 255,
+				0, 0, 0, 255,
+				0, 0, 0, 255,
+				255, 255, 255, 255,
+				0, 0, 0, 255,
+				0, 0, 0, 255,
+				0, 0, 0, 255,
+				0, 0, 0, 255,
 			},
 			wantMatrix: []uint8{
-				11, 34, 34, 64,
-				15, 37, 22, 103,
-				13, 27, 46, 73,
-				10, 24, 27, 64,
+				0, 0, 0, 255,
+				1, 1, 1, 255,
+				14, 14, 14, 255,
+				62, 62, 62, 255,
+				102, 102, 102, 255,
+				62, 62, 62, 255,
+				14, 14, 14, 255,
+				1, 1, 1, 255,
+				0, 0, 0, 255,
 			},
 		},
 		{
-			name:   "sigma=1.0",
-			sigma:  1.0,
-			bounds: image.Rect(0, 0, 2, 2),
+			name:   "sigma=2.0",
+			sigma:  2.0,
+			bounds: image.Rect(0, 0, 4, 3),
 			sourceMatrix: []uint8{
-				0, 2, 3, 4,
-				33, 22, 23, 3,
-				20, 4, 1, 4,
-				4, 5, 0, 0,
+				221, 143, 219, 231,
+				115, 130, 218, 238,
+				23, 65, 60, 97,
+				12, 57, 38, 95,
+				11, 68, 5, 72,
+				115, 167, 189, 202,
+				79, 1, 67, 80,
+				3, 2, 12, 32,
+				76, 3, 99, 123,
+				83, 112, 151, 237,
+				66, 29, 81, 100,
+				127, 1, 21, 150,
 			},
 			wantMatrix: []uint8{
-				13, 7, 6, 3,
-				19, 12, 11, 2,
-				16, 7, 5, 2,
-				11, 7, 4, 1,
+				89, 83, 118, 153,
+				81, 76, 109, 145,
+				74, 67, 97, 135,
+				66, 58, 84, 125,
+				85, 77, 113, 150,
+				80, 71, 105, 143,
+				74, 63, 94, 135,
+				68, 54, 82, 125,
+				83, 72, 108, 148,
+				79, 66, 101, 142,
+				75, 59, 91, 135,
+				71, 50, 80, 126,
 			},
 		},
 		{
 			name:   "sigma=2.4",
 			sigma:  2.4,
-			bounds: image.Rect(2, 2, 4, 4),
+			bounds: image.Rect(2, 2, 5, 5),
 			sourceMatrix: []uint8{
-				0, 2, 3, 4,
-				33, 22, 23, 3,
-				20, 4, 1, 4,
-				4, 5, 0, 0,
+				167, 71, 104, 234,
+				16, 20, 14, 42,
+				7, 17, 144, 147,
+				25, 6, 54, 55,
+				98, 17, 4, 148,
+				0, 0, 0, 0,
+				96, 181, 101, 240,
+				18, 144, 161, 214,
+				99, 86, 34, 101,
 			},
 			wantMatrix: []uint8{
-				14, 8, 6, 2,
-				17, 11, 9, 1,
-				17, 9, 7, 2,
-				12, 8, 5, 1,
+				61, 56, 67, 132,
+				57, 53, 66, 127,
+				53, 51, 65, 122,
+				61, 62, 68, 135,
+				58, 59, 67, 130,
+				54, 56, 65, 124,
+				61, 68, 70, 138,
+				58, 65, 68, 133,
+				55, 62, 66, 127,
 			},
 		},
 		{
@@ -149,25 +220,269 @@ func TestGaussianBlur_ModifyPixel(t *testing.T) {
 			wantMatrix:   []uint8{},
 		},
 	}
-
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			blur := NewGaussianBlur(test.sigma)
 			img := image.NewRGBA(test.bounds)
 			img.Pix = test.sourceMatrix
+			got := modifyImage(NewGaussianBlur(test.sigma), img)
+			assert.Equal(t, test.wantMatrix, got.Pix,
+				"Incorrect pixel modification")
+		})
+	}
+}
+
+func TestGaussianBlur_ModifyPixel_reference(t *testing.T) {
+	tests := []struct {
+		name   string
+		sigma  float64
+		bounds image.Rectangle
+	}{
+		{"sigma=1.0", 1.0, image.Rect(0, 0, 7, 5)},
+		{"sigma=2.0", 2.0, image.Rect(0, 0, 16, 12)},
+		{"sigma=2.4", 2.4, image.Rect(2, 2, 13, 20)},
+		{"sigma=5.0", 5.0, image.Rect(-3, 1, 30, 25)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := newRandomImage(test.bounds, 1)
+			got := modifyImage(NewGaussianBlur(test.sigma), src)
+			want := modifyImage(newReferenceGaussianBlur(test.sigma), src)
+			assertImagesNear(t, want, got, 1)
+		})
+	}
+}
+
+func TestGaussianBlur_ModifyPixel_largeSigma(t *testing.T) {
+	src := newRandomImage(image.Rect(0, 0, 64, 48), 2)
+
+	// The box blurs must be close to the precise gaussian blur, whose kernel
+	// covers three sigmas on each side. The edges wrap, so that the box blurs
+	// applied one after another treat the borders like a single blur.
+	sigma := 10.0
+	size := int(6*sigma) + 1
+	kernel := make([]float64, size)
+	var sum float64
+	for i := range kernel {
+		x := float64(i - size/2)
+		kernel[i] = math.Exp(-x * x / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	precise := &GaussianBlur{halfSize: size / 2, kernel: kernel,
+		edge: EDGE_WRAP}
+
+	got := modifyImage(NewGaussianBlurWithOptions(sigma,
+		FilterOptions{Edge: EDGE_WRAP}), src)
+	want := modifyImage(precise, src)
+	assertImagesNear(t, want, got, 2)
+}
+
+func TestGaussianBlur_ModifyPixel_hugeSigma(t *testing.T) {
+	src := newRandomImage(image.Rect(0, 0, 16, 12), 3)
+	want := modifyImage(NewGaussianBlur(MAX_SIGMA), src)
+	for _, sigma := range []float64{1e300, math.Inf(1), math.NaN()} {
+		blur := NewGaussianBlur(sigma)
+		assert.Equal(t, boxRadii(MAX_SIGMA, 3), blur.boxRadii,
+			"sigma %v must be limited", sigma)
+		assert.Equal(t, want, modifyImage(blur, src))
+	}
+}
+
+func TestGaussianBlur_ModifyPixel_sources(t *testing.T) {
+	rgba := newRandomImage(image.Rect(0, 0, 12, 10), 3)
+	nrgba := image.NewNRGBA(rgba.Bounds())
+	draw.Draw(nrgba, nrgba.Bounds(), rgba, image.Point{}, draw.Src)
+	sub := rgba.SubImage(image.Rect(2, 3, 9, 8)).(*image.RGBA)
+
+	for _, sigma := range []float64{1.5, 12} {
+		blur := NewGaussianBlur(sigma)
+		want := modifyImage(blur, rgba)
+		assertImagesNear(t, want, modifyImage(blur, image.Image(nrgba)), 1)
+
+		got := modifyImage(NewGaussianBlur(sigma), sub)
+		want = modifyImage(NewGaussianBlur(sigma),
+			cloneImage(sub))
+		assertImagesNear(t, want, got, 0)
+	}
+}
+
+func TestModifyPixelsContext_prepared(t *testing.T) {
+	src := newRandomImage(image.Rect(0, 0, 40, 30), 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
 
-			for x := test.bounds.Min.X; x <= test.bounds.Max.X; x++ {
-				for y := test.bounds.Min.Y; y <= test.bounds.Max.Y; y++ {
-					img.SetRGBA(x, y, blur.ModifyPixel(
-						image.Point{x, y},
-						img.RGBAAt(x, y),
-						img,
-					))
-				}
+	gaussian := NewGaussianBlur(2)
+	box := NewGaussianBlur(20)
+	mask := NewUnsharpMask(2, 1, 0, FilterOptions{})
+	convolution := NewBoxBlur(2, FilterOptions{})
+	for _, modifier := range []PixelModifier{gaussian, box, mask,
+		convolution} {
+		got, err := ModifyPixelsContext(ctx, modifier, src, src.Bounds(), 0)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+	}
+	assert.False(t, gaussian.blurred.ok, "the blur must stop")
+	assert.False(t, box.blurred.ok, "the box blurs must stop")
+	assert.False(t, mask.blur.blurred.ok, "the unsharp mask must stop")
+	assert.False(t, convolution.pass.ok, "the horizontal pass must stop")
+
+	got, err := ModifyPixelsContext(context.Background(), convolution, src,
+		src.Bounds(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, ModifyPixels(NewBoxBlur(2, FilterOptions{}), src,
+		src.Bounds()), got, "a cancelled pass must not be stored")
+}
+
+func Test_boxRadii(t *testing.T) {
+	assert.Equal(t, []int{9, 9, 10}, boxRadii(10, 3))
+	assert.Equal(t, []int{3, 3, 4}, boxRadii(4, 3))
+}
+
+func BenchmarkGaussianBlur(b *testing.B) {
+	src := newRandomImage(image.Rect(0, 0, 128, 128), 4)
+	for _, sigma := range []float64{2, 6, 16} {
+		b.Run(fmt.Sprintf("two-pass/sigma=%g", sigma), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				modifyImage(NewGaussianBlur(sigma), src)
 			}
+		})
+		b.Run(fmt.Sprintf("reference/sigma=%g", sigma), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				modifyImage(newReferenceGaussianBlur(sigma), src)
+			}
+		})
+	}
+}
 
-			assert.Equal(t, img.Pix, test.wantMatrix, 
-				"Incorrect pixel modification")
+// referenceGaussianBlur is the blur that samples the whole kernel square for
+// every pixel. It is the previous implementation of GaussianBlur with the
+// renormalize edge mode, kept to check and benchmark the two-pass blur.
+type referenceGaussianBlur struct {
+	halfSize int
+	kernel   []float64
+}
+
+func newReferenceGaussianBlur(sigma float64) *referenceGaussianBlur {
+	kernel := gaussianKernel(sigma)
+	return &referenceGaussianBlur{len(kernel) / 2, kernel}
+}
+
+func (blur *referenceGaussianBlur) ModifyPixel(position image.Point,
+	c color.RGBA, src image.Image) color.RGBA {
+	var resultR, resultG, resultB, resultA, weight float64
+	imageBounds := src.Bounds()
+	blurBounds := image.Rect(
+		position.X-blur.halfSize,
+		position.Y-blur.halfSize,
+		position.X+blur.halfSize,
+		position.Y+blur.halfSize,
+	)
+
+	for x := blurBounds.Min.X; x <= blurBounds.Max.X; x++ {
+		if x < imageBounds.Min.X || x >= imageBounds.Max.X {
+			continue
+		}
+		for y := blurBounds.Min.Y; y <= blurBounds.Max.Y; y++ {
+			if y < imageBounds.Min.Y || y >= imageBounds.Max.Y {
+				continue
+			}
+			r, g, b, a := src.At(x, y).RGBA()
+			mul := blur.kernel[x-blurBounds.Min.X] * blur.kernel[y-blurBounds.Min.Y]
+
+			resultR += float64(r) * mul
+			resultG += float64(g) * mul
+			resultB += float64(b) * mul
+			resultA += float64(a) * mul
+			weight += mul
+		}
+	}
+
+	resultR /= weight
+	resultG /= weight
+	resultB /= weight
+	resultA /= weight
+
+	return color.RGBA{
+		uint8(resultR / 256),
+		uint8(resultG / 256),
+		uint8(resultB / 256),
+		uint8(resultA / 256),
+	}
+}
+
+// newRandomImage creates an image with random premultiplied colors.
+func newRandomImage(bounds image.Rectangle, seed int64) *image.RGBA {
+	random := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(bounds)
+	for i := 0; i < len(img.Pix); i += 4 {
+		alpha := random.Intn(256)
+		img.Pix[i] = uint8(random.Intn(alpha + 1))
+		img.Pix[i+1] = uint8(random.Intn(alpha + 1))
+		img.Pix[i+2] = uint8(random.Intn(alpha + 1))
+		img.Pix[i+3] = uint8(alpha)
+	}
+	return img
+}
+
+// cloneImage returns a copy of the image with its own pixels.
+func cloneImage(src *image.RGBA) *image.RGBA {
+	img := image.NewRGBA(src.Bounds())
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+	return img
+}
+
+// modifyImage applies the modifier to every pixel of the image like
+// ImageEditor.ModifyPixels.
+func modifyImage(modifier PixelModifier, src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			col := color.RGBAModel.Convert(src.At(x, y)).(color.RGBA)
+			dst.SetRGBA(x, y, modifier.ModifyPixel(image.Pt(x, y), col, src))
+		}
+	}
+	return dst
+}
+
+// assertImagesNear checks that the channels of the images differ by at most
+// delta.
+func assertImagesNear(t *testing.T, want, got *image.RGBA, delta int) {
+	t.Helper()
+	assert.Equal(t, want.Bounds(), got.Bounds())
+	for i := range want.Pix {
+		difference := int(want.Pix[i]) - int(got.Pix[i])
+		if difference < -delta || difference > delta {
+			t.Fatalf("channel %d of pixel %d = %d, want %d±%d", i%4, i/4,
+				got.Pix[i], want.Pix[i], delta)
+		}
+	}
+}
+
+func TestGaussianBlur_ModifyPixel_edge(t *testing.T) {
+	img := newGrayImage([][]uint8{
+		{100, 100, 100},
+		{100, 100, 100},
+		{100, 100, 100},
+	})
+	tests := []struct {
+		edge string
+		want color.RGBA
+	}{
+		{EDGE_RENORMALIZE, color.RGBA{100, 100, 100, 255}},
+		{EDGE_CLAMP, color.RGBA{100, 100, 100, 255}},
+		{EDGE_MIRROR, color.RGBA{100, 100, 100, 255}},
+		{EDGE_TRANSPARENT, color.RGBA{48, 48, 48, 123}},
+	}
+	for _, test := range tests {
+		t.Run(test.edge, func(t *testing.T) {
+			blur := NewGaussianBlurWithOptions(1, FilterOptions{Edge: test.edge})
+			got := blur.ModifyPixel(image.Pt(0, 0), img.RGBAAt(0, 0), img)
+			assert.Equal(t, test.want, got,
+				"GaussianBlur.ModifyPixel(image.Pt(0, 0)) with the %s edge = %#v, want %#v",
+				test.edge, got, test.want)
 		})
 	}
 }
@@ -1,25 +1,184 @@
 package mods
 
 import (
+	"errors"
 	"image"
 	"image/color"
+	"math"
 )
 
-// NewGrayscale creates a new Grayscale object.
+// Grayscale modes set how the gray value of a pixel is calculated.
+const (
+	// GRAYSCALE_AVERAGE is the average of the red, green and blue channels.
+	GRAYSCALE_AVERAGE = "average"
+	// GRAYSCALE_REC601 is the luma of the SDTV standard, which weights the
+	// channels by their perceived brightness.
+	GRAYSCALE_REC601 = "rec601"
+	// GRAYSCALE_REC709 is the luma of the HDTV and sRGB standard.
+	GRAYSCALE_REC709 = "rec709"
+	// GRAYSCALE_LIGHTNESS keeps the perceived lightness of the color. The
+	// luminance is calculated from linear light and encoded back to sRGB.
+	GRAYSCALE_LIGHTNESS = "lightness"
+	// GRAYSCALE_DESATURATE is the middle of the largest and the smallest
+	// channel, which removes the saturation of the HSL color.
+	GRAYSCALE_DESATURATE = "desaturate"
+)
+
+const DEFAULT_GRAYSCALE = GRAYSCALE_AVERAGE
+
+// ValidateGrayscaleMode checks whether a string value is a valid grayscale
+// mode.
+func ValidateGrayscaleMode(mode string) bool {
+	switch mode {
+	case GRAYSCALE_AVERAGE, GRAYSCALE_REC601, GRAYSCALE_REC709,
+		GRAYSCALE_LIGHTNESS, GRAYSCALE_DESATURATE:
+		return true
+	default:
+		return false
+	}
+}
+
+// lumaWeights are the channel weights of the luma modes out of 1 << 16.
+var lumaWeights = map[string][3]uint32{
+	GRAYSCALE_REC601: {19595, 38470, 7471},
+	GRAYSCALE_REC709: {13933, 46871, 4732},
+}
+
+// NewGrayscale creates a new Grayscale object with the DEFAULT_GRAYSCALE mode.
 func NewGrayscale() *Grayscale {
-	return new(Grayscale)
+	return &Grayscale{mode: DEFAULT_GRAYSCALE}
+}
+
+// NewGrayscaleWithMode creates a new Grayscale object with the mode. An empty
+// mode is replaced with DEFAULT_GRAYSCALE. Returns an error if the mode is not
+// valid.
+func NewGrayscaleWithMode(mode string) (*Grayscale, error) {
+	if mode == "" {
+		mode = DEFAULT_GRAYSCALE
+	}
+	if !ValidateGrayscaleMode(mode) {
+		return nil, errors.New("unknown grayscale mode " + mode)
+	}
+	return &Grayscale{mode: mode, weights: lumaWeights[mode]}, nil
 }
 
 // Grayscale is a type representing a modifier that converts an image to
 // grayscale.
-type Grayscale struct{}
+type Grayscale struct {
+	mode string
+	// weights are the channel weights of a luma mode.
+	weights [3]uint32
+}
 
 // ModifyPixel converts the image pixel to grayscale.
 func (grayscale *Grayscale) ModifyPixel(_ image.Point, col color.RGBA,
 	_ image.Image) color.RGBA {
-	intensity := (uint16(col.R) + uint16(col.G) + uint16(col.B)) / 3
-	col.R = uint8(intensity)
-	col.G = uint8(intensity)
-	col.B = uint8(intensity)
+	intensity := grayscale.gray(col.R, col.G, col.B, col.A)
+	col.R = intensity
+	col.G = intensity
+	col.B = intensity
 	return col
 }
+
+// ModifyRow converts a row of the image to grayscale.
+func (grayscale *Grayscale) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	row := sourceRow(start, dst, src)
+	for i := 0; i < len(dst); i += 4 {
+		intensity := grayscale.gray(row[i], row[i+1], row[i+2], row[i+3])
+		dst[i] = intensity
+		dst[i+1] = intensity
+		dst[i+2] = intensity
+		dst[i+3] = row[i+3]
+	}
+}
+
+// ModifyPixel64 converts the 16-bit image pixel to grayscale.
+func (grayscale *Grayscale) ModifyPixel64(_ image.Point, col color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	intensity := grayscale.gray64(col.R, col.G, col.B, col.A)
+	col.R = intensity
+	col.G = intensity
+	col.B = intensity
+	return col
+}
+
+// gray returns the premultiplied gray value of the premultiplied channels.
+func (grayscale *Grayscale) gray(r, g, b, a uint8) uint8 {
+	switch grayscale.mode {
+	case GRAYSCALE_AVERAGE:
+		return uint8((uint16(r) + uint16(g) + uint16(b)) / 3)
+	case GRAYSCALE_DESATURATE:
+		high, low := r, r
+		for _, value := range [2]uint8{g, b} {
+			if value > high {
+				high = value
+			}
+			if value < low {
+				low = value
+			}
+		}
+		return uint8((uint16(high) + uint16(low) + 1) / 2)
+	case GRAYSCALE_LIGHTNESS:
+		return lightness(r, g, b, a)
+	default:
+		weights := grayscale.weights
+		return uint8((weights[0]*uint32(r) + weights[1]*uint32(g) +
+			weights[2]*uint32(b) + 1<<15) >> 16)
+	}
+}
+
+// lightness returns the premultiplied sRGB value of the relative luminance of
+// the premultiplied channels.
+func lightness(r, g, b, a uint8) uint8 {
+	if a == 0 {
+		return 0
+	}
+
+	alpha := uint32(a)
+	luminance := 0.2126*linearChannel(r, alpha) +
+		0.7152*linearChannel(g, alpha) + 0.0722*linearChannel(b, alpha)
+	return premultiply(srgbChannel(luminance), alpha)
+}
+
+// gray64 returns the premultiplied gray value of the premultiplied 16-bit
+// channels, like gray.
+func (grayscale *Grayscale) gray64(r, g, b, a uint16) uint16 {
+	switch grayscale.mode {
+	case GRAYSCALE_AVERAGE:
+		return uint16((uint32(r) + uint32(g) + uint32(b)) / 3)
+	case GRAYSCALE_DESATURATE:
+		high, low := r, r
+		for _, value := range [2]uint16{g, b} {
+			if value > high {
+				high = value
+			}
+			if value < low {
+				low = value
+			}
+		}
+		return uint16((uint32(high) + uint32(low) + 1) / 2)
+	case GRAYSCALE_LIGHTNESS:
+		return lightness64(r, g, b, a)
+	default:
+		weights := grayscale.weights
+		return uint16((uint64(weights[0])*uint64(r) +
+			uint64(weights[1])*uint64(g) + uint64(weights[2])*uint64(b) +
+			1<<15) >> 16)
+	}
+}
+
+// lightness64 returns the premultiplied sRGB value of the relative luminance
+// of the premultiplied 16-bit channels.
+func lightness64(r, g, b, a uint16) uint16 {
+	if a == 0 {
+		return 0
+	}
+
+	alpha := float64(a)
+	luminance := 0.2126*srgbToLinear(float64(r)/alpha) +
+		0.7152*srgbToLinear(float64(g)/alpha) +
+		0.0722*srgbToLinear(float64(b)/alpha)
+	return uint16(math.Round(
+		math.Min(1, linearToSRGB(luminance)) * alpha))
+}
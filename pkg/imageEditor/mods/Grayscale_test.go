@@ -8,12 +8,55 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// newGrayscale creates a Grayscale object with a valid mode.
+func newGrayscale(mode string) *Grayscale {
+	grayscale, err := NewGrayscaleWithMode(mode)
+	if err != nil {
+		panic(err)
+	}
+	return grayscale
+}
+
 func Test_NewGrayscale(t *testing.T) {
 	got := NewGrayscale()
-	want := new(Grayscale)
+	want := &Grayscale{mode: DEFAULT_GRAYSCALE}
 	assert.Equal(t, want, got, "NewGrayscale() = %#v, want %#v", got, want)
 }
 
+func TestNewGrayscaleWithMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    *Grayscale
+		wantErr bool
+	}{
+		{GRAYSCALE_AVERAGE, &Grayscale{mode: GRAYSCALE_AVERAGE}, false},
+		{GRAYSCALE_REC709, &Grayscale{GRAYSCALE_REC709,
+			[3]uint32{13933, 46871, 4732}}, false},
+		{"", &Grayscale{mode: DEFAULT_GRAYSCALE}, false},
+		{"sepia", nil, true},
+	}
+	for _, test := range tests {
+		got, err := NewGrayscaleWithMode(test.mode)
+		if test.wantErr {
+			assert.Error(t, err, "mode: %q", test.mode)
+		} else {
+			assert.NoError(t, err, "mode: %q", test.mode)
+		}
+		assert.Equal(t, test.want, got,
+			"NewGrayscaleWithMode(%q) = %#v, want %#v", test.mode, got,
+			test.want)
+	}
+}
+
+func TestValidateGrayscaleMode(t *testing.T) {
+	for _, mode := range []string{GRAYSCALE_AVERAGE, GRAYSCALE_REC601,
+		GRAYSCALE_REC709, GRAYSCALE_LIGHTNESS, GRAYSCALE_DESATURATE} {
+		assert.True(t, ValidateGrayscaleMode(mode), mode)
+	}
+	assert.False(t, ValidateGrayscaleMode(""))
+	assert.False(t, ValidateGrayscaleMode("luma"))
+}
+
 func TestGrayscale_ModifyPixel(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -52,3 +95,40 @@ func TestGrayscale_ModifyPixel(t *testing.T) {
 		})
 	}
 }
+
+func TestGrayscale_ModifyPixel_modes(t *testing.T) {
+	green := color.RGBA{0, 255, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	orange := color.RGBA{200, 100, 50, 255}
+	// The half transparent green is premultiplied.
+	halfGreen := color.RGBA{0, 128, 0, 128}
+	tests := []struct {
+		mode  string
+		color color.RGBA
+		want  uint8
+	}{
+		{GRAYSCALE_AVERAGE, green, 85},
+		{GRAYSCALE_REC601, green, 150},
+		{GRAYSCALE_REC601, blue, 29},
+		{GRAYSCALE_REC601, orange, 124},
+		{GRAYSCALE_REC601, halfGreen, 75},
+		{GRAYSCALE_REC709, green, 182},
+		{GRAYSCALE_REC709, blue, 18},
+		{GRAYSCALE_REC709, orange, 118},
+		{GRAYSCALE_LIGHTNESS, green, 220},
+		{GRAYSCALE_LIGHTNESS, blue, 76},
+		{GRAYSCALE_LIGHTNESS, orange, 128},
+		{GRAYSCALE_LIGHTNESS, halfGreen, 110},
+		{GRAYSCALE_LIGHTNESS, color.RGBA{}, 0},
+		{GRAYSCALE_DESATURATE, green, 128},
+		{GRAYSCALE_DESATURATE, orange, 125},
+		{GRAYSCALE_DESATURATE, halfGreen, 64},
+	}
+
+	for _, test := range tests {
+		got := newGrayscale(test.mode).ModifyPixel(image.Point{}, test.color,
+			nil)
+		want := color.RGBA{test.want, test.want, test.want, test.color.A}
+		assert.Equal(t, want, got, "%s: %v", test.mode, test.color)
+	}
+}
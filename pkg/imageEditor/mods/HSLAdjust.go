@@ -0,0 +1,105 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// NewHSLAdjust creates a new HSLAdjust object. hue is the rotation of the hue
+// in degrees. saturation and lightness multiply the saturation and the
+// lightness of the colors, so 1 keeps them unchanged and 0 makes the colors
+// gray or black. Negative multipliers are replaced with 0. Only the colors with
+// the hues in the range are changed; gray colors have no hue, so they are
+// changed only if the range contains all hues.
+func NewHSLAdjust(hue, saturation, lightness float64,
+	hues HueRange) *HSLAdjust {
+	return &HSLAdjust{
+		hue:        hue,
+		saturation: math.Max(0, saturation),
+		lightness:  math.Max(0, lightness),
+		hues:       hues,
+	}
+}
+
+// HSLAdjust is a type representing a modifier that changes the hue, the
+// saturation and the lightness of the colors of an image.
+type HSLAdjust struct {
+	hue        float64
+	saturation float64
+	lightness  float64
+	hues       HueRange
+}
+
+// ModifyPixel changes the hue, the saturation and the lightness of the image
+// pixel.
+func (adjust *HSLAdjust) ModifyPixel(_ image.Point, col color.RGBA,
+	_ image.Image) color.RGBA {
+	col.R, col.G, col.B = adjust.adjust8(col.R, col.G, col.B, col.A)
+	return col
+}
+
+// ModifyRow changes the hue, the saturation and the lightness of a row of the
+// image.
+func (adjust *HSLAdjust) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	row := sourceRow(start, dst, src)
+	for i := 0; i < len(dst); i += 4 {
+		dst[i], dst[i+1], dst[i+2] = adjust.adjust8(row[i], row[i+1],
+			row[i+2], row[i+3])
+		dst[i+3] = row[i+3]
+	}
+}
+
+// ModifyPixel64 changes the hue, the saturation and the lightness of the
+// 16-bit image pixel.
+func (adjust *HSLAdjust) ModifyPixel64(_ image.Point, col color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	if col.A == 0 {
+		return col
+	}
+
+	alpha := float64(col.A)
+	r, g, b := adjust.adjust(float64(col.R)/alpha, float64(col.G)/alpha,
+		float64(col.B)/alpha)
+	col.R = uint16(math.Round(r * alpha))
+	col.G = uint16(math.Round(g * alpha))
+	col.B = uint16(math.Round(b * alpha))
+	return col
+}
+
+// adjust8 returns the adjusted premultiplied channels.
+func (adjust *HSLAdjust) adjust8(r, g, b, a uint8) (uint8, uint8, uint8) {
+	if a == 0 {
+		return r, g, b
+	}
+
+	alpha := float64(a)
+	newR, newG, newB := adjust.adjust(float64(r)/alpha, float64(g)/alpha,
+		float64(b)/alpha)
+	return uint8(math.Round(newR * alpha)), uint8(math.Round(newG * alpha)),
+		uint8(math.Round(newB * alpha))
+}
+
+// adjust returns the adjusted channels in the range [0, 1].
+func (adjust *HSLAdjust) adjust(r, g, b float64) (float64, float64, float64) {
+	// Premultiplied channels can be larger than the alpha after rounding.
+	r, g, b = math.Min(1, r), math.Min(1, g), math.Min(1, b)
+	h, s, l := rgbToHSL(r, g, b)
+
+	weight := 1.0
+	if !adjust.hues.IsAll() {
+		if s == 0 {
+			return r, g, b
+		}
+		weight = adjust.hues.weight(h)
+	}
+	if weight == 0 {
+		return r, g, b
+	}
+
+	h += adjust.hue * weight
+	s = math.Min(1, s*(1+(adjust.saturation-1)*weight))
+	l = math.Min(1, l*(1+(adjust.lightness-1)*weight))
+	return hslToRGB(h, s, l)
+}
@@ -0,0 +1,51 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHSLAdjust_ModifyPixel(t *testing.T) {
+	reds, _ := HueRangeByName(HUES_REDS)
+	tests := []struct {
+		name   string
+		adjust *HSLAdjust
+		color  color.RGBA
+		want   color.RGBA
+	}{
+		{"no change", NewHSLAdjust(0, 1, 1, HueRange{}),
+			color.RGBA{10, 128, 240, 255}, color.RGBA{10, 128, 240, 255}},
+		{"hue rotation", NewHSLAdjust(120, 1, 1, HueRange{}),
+			color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}},
+		{"negative hue rotation", NewHSLAdjust(-120, 1, 1, HueRange{}),
+			color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}},
+		{"no saturation", NewHSLAdjust(0, 0, 1, HueRange{}),
+			color.RGBA{255, 0, 0, 255}, color.RGBA{128, 128, 128, 255}},
+		{"half saturation", NewHSLAdjust(0, 0.5, 1, HueRange{}),
+			color.RGBA{255, 0, 0, 255}, color.RGBA{191, 64, 64, 255}},
+		{"half lightness", NewHSLAdjust(0, 1, 0.5, HueRange{}),
+			color.RGBA{255, 0, 0, 255}, color.RGBA{128, 0, 0, 255}},
+		{"gray", NewHSLAdjust(90, 2, 1.5, HueRange{}),
+			color.RGBA{100, 100, 100, 255}, color.RGBA{150, 150, 150, 255}},
+		{"target hue", NewHSLAdjust(120, 1, 1, reds),
+			color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}},
+		{"other hue", NewHSLAdjust(120, 1, 1, reds),
+			color.RGBA{0, 0, 255, 255}, color.RGBA{0, 0, 255, 255}},
+		{"gray with a hue range", NewHSLAdjust(0, 1, 0.5, reds),
+			color.RGBA{100, 100, 100, 255}, color.RGBA{100, 100, 100, 255}},
+		{"semi-transparent color", NewHSLAdjust(120, 1, 1, HueRange{}),
+			color.RGBA{128, 0, 0, 128}, color.RGBA{0, 128, 0, 128}},
+		{"transparent color", NewHSLAdjust(120, 1, 1, HueRange{}),
+			color.RGBA{}, color.RGBA{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.adjust.ModifyPixel(image.Point{}, test.color, nil)
+			assert.Equal(t, test.want, got,
+				"ModifyPixel(%#v) = %#v, want %#v", test.color, got, test.want)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package mods
+
+// Names of the built-in hue ranges
+const (
+	HUES_ALL      = "all"
+	HUES_REDS     = "reds"
+	HUES_YELLOWS  = "yellows"
+	HUES_GREENS   = "greens"
+	HUES_CYANS    = "cyans"
+	HUES_BLUES    = "blues"
+	HUES_MAGENTAS = "magentas"
+)
+
+// hueRangeCenters are the centers of the built-in hue ranges in degrees.
+var hueRangeCenters = map[string]float64{
+	HUES_REDS:     0,
+	HUES_YELLOWS:  60,
+	HUES_GREENS:   120,
+	HUES_CYANS:    180,
+	HUES_BLUES:    240,
+	HUES_MAGENTAS: 300,
+}
+
+// HueRange is a range of hues that a modifier changes. The hues within Width/2
+// degrees of Center are changed fully, and the change fades out over the next
+// Feather degrees on both sides. The zero HueRange contains all hues.
+type HueRange struct {
+	// Center is the middle of the range in degrees.
+	Center float64
+	// Width is the angle of the fully changed hues in degrees. A range that
+	// is not positive or at least 360 contains all hues.
+	Width float64
+	// Feather is the angle of the fading change on each side in degrees.
+	Feather float64
+}
+
+// HueRangeByName returns the built-in hue range with the given name. An empty
+// name returns all hues. The second value reports whether the range exists.
+func HueRangeByName(name string) (HueRange, bool) {
+	if name == "" || name == HUES_ALL {
+		return HueRange{}, true
+	}
+
+	center, ok := hueRangeCenters[name]
+	if !ok {
+		return HueRange{}, false
+	}
+	// The ranges are 60 degrees apart, so the neighbouring ranges overlap
+	// only in their feathers.
+	return HueRange{Center: center, Width: 30, Feather: 30}, true
+}
+
+// IsAll reports whether the range contains all hues.
+func (hues HueRange) IsAll() bool {
+	return hues.Width <= 0 || hues.Width >= 360
+}
+
+// weight returns how much the hue in degrees is changed, from 0 to 1.
+func (hues HueRange) weight(hue float64) float64 {
+	if hues.IsAll() {
+		return 1
+	}
+
+	outside := hueDistance(hue, hues.Center) - hues.Width/2
+	switch {
+	case outside <= 0:
+		return 1
+	case outside >= hues.Feather:
+		return 0
+	default:
+		return 1 - outside/hues.Feather
+	}
+}
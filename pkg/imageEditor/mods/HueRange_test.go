@@ -0,0 +1,45 @@
+package mods
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHueRangeByName(t *testing.T) {
+	hues, ok := HueRangeByName(HUES_BLUES)
+	assert.True(t, ok)
+	assert.Equal(t, HueRange{Center: 240, Width: 30, Feather: 30}, hues)
+
+	for _, name := range []string{"", HUES_ALL} {
+		hues, ok = HueRangeByName(name)
+		assert.True(t, ok)
+		assert.True(t, hues.IsAll(), "%q must contain all hues", name)
+	}
+
+	_, ok = HueRangeByName("oranges")
+	assert.False(t, ok)
+}
+
+func TestHueRange_weight(t *testing.T) {
+	reds, _ := HueRangeByName(HUES_REDS)
+	tests := []struct {
+		name string
+		hues HueRange
+		hue  float64
+		want float64
+	}{
+		{"all hues", HueRange{}, 200, 1},
+		{"center", reds, 0, 1},
+		{"inside", reds, 350, 1},
+		{"feather", reds, 30, 0.5},
+		{"feather before zero", reds, 330, 0.5},
+		{"outside", reds, 90, 0},
+		{"without feather", HueRange{Center: 120, Width: 60}, 151, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.InDelta(t, test.want, test.hues.weight(test.hue), 1e-9)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package mods
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ImageOperation is an interface that defines a method to produce a new image
+// from the whole source image. Unlike PixelModifier, an operation can read the
+// image any number of times, e.g. to build a histogram, and the result can have
+// different bounds. Apply must not change the pixels of src.
+type ImageOperation interface {
+	Apply(src *image.RGBA) (*image.RGBA, error)
+}
+
+// ContextOperation is an ImageOperation that stops when the context is done
+// and returns the error of the context. The editor uses ApplyContext instead of
+// Apply if the operation implements it.
+type ContextOperation interface {
+	ImageOperation
+	ApplyContext(ctx context.Context, src *image.RGBA) (*image.RGBA, error)
+}
+
+// NewPixelOperation creates a new PixelOperation object that applies the
+// modifier.
+func NewPixelOperation(modifier PixelModifier) *PixelOperation {
+	return &PixelOperation{modifier}
+}
+
+// PixelOperation is an ImageOperation that applies a PixelModifier to every
+// pixel of the image.
+type PixelOperation struct {
+	modifier PixelModifier
+}
+
+// Apply returns the image with every pixel modified.
+func (operation *PixelOperation) Apply(src *image.RGBA) (*image.RGBA, error) {
+	return operation.ApplyContext(context.Background(), src)
+}
+
+// ApplyContext is like Apply, but it stops when the context is done and
+// returns the error of the context.
+func (operation *PixelOperation) ApplyContext(ctx context.Context,
+	src *image.RGBA) (*image.RGBA, error) {
+	return ModifyPixelsContext(ctx, operation.modifier, src, src.Bounds(), 0)
+}
+
+// tilePixels is the approximate number of pixels in a tile of rows that a
+// worker modifies at once. The source and the destination rows of a tile fit in
+// the processor cache.
+const tilePixels = 1 << 15
+
+// ModifyPixels applies the modifier to the pixels of src within the bounds in
+// parallel and returns the new image with the bounds. The modifier sees the
+// whole src, which can be larger than the bounds. The pixels of an *image.RGBA
+// source are read directly, and a RowModifier modifies them by rows.
+func ModifyPixels(modifier PixelModifier, src image.Image,
+	bounds image.Rectangle) *image.RGBA {
+	dst, _ := ModifyPixelsContext(context.Background(), modifier, src, bounds,
+		0)
+	return dst
+}
+
+// ModifyPixelsContext is like ModifyPixels, but it stops when the context is
+// done and returns the error of the context. The rows are split into tiles,
+// which are modified by the given number of workers, or by runtime.NumCPU()
+// workers if the number is not positive.
+func ModifyPixelsContext(ctx context.Context, modifier PixelModifier,
+	src image.Image, bounds image.Rectangle, workers int) (*image.RGBA, error) {
+	if preparer, ok := modifier.(sourcePreparer); ok {
+		if err := preparer.prepare(ctx, src); err != nil {
+			return nil, err
+		}
+	}
+
+	dst := image.NewRGBA(bounds)
+	err := modifyRows(ctx, rowFunc(modifier, src, bounds, dst), bounds, workers)
+	if err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// ModifyPixels64Context is like ModifyPixelsContext, but it modifies a 16-bit
+// image and returns the 16-bit result.
+func ModifyPixels64Context(ctx context.Context, modifier PixelModifier64,
+	src *image.RGBA64, bounds image.Rectangle,
+	workers int) (*image.RGBA64, error) {
+	dst := image.NewRGBA64(bounds)
+	modifyRow := func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dst.SetRGBA64(x, y, modifier.ModifyPixel64(image.Pt(x, y),
+				src.RGBA64At(x, y), src))
+		}
+	}
+	if err := modifyRows(ctx, modifyRow, bounds, workers); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// modifyRows calls modifyRow for every row of the bounds. The rows are split
+// into tiles, which are modified by the given number of workers, or by
+// runtime.NumCPU() workers if the number is not positive. It stops when the
+// context is done and returns the error of the context.
+func modifyRows(ctx context.Context, modifyRow func(y int),
+	bounds image.Rectangle, workers int) error {
+	tileRows := 1
+	if bounds.Dx() > 0 && bounds.Dx() < tilePixels {
+		tileRows = tilePixels / bounds.Dx()
+	}
+	tileCount := (bounds.Dy() + tileRows - 1) / tileRows
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > tileCount {
+		workers = tileCount
+	}
+
+	// The workers take the next tile until all tiles are modified, so that a
+	// slow part of the image does not keep the other workers idle.
+	var nextTile atomic.Int64
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer waitGroup.Done()
+
+			for ctx.Err() == nil {
+				tile := int(nextTile.Add(1)) - 1
+				if tile >= tileCount {
+					return
+				}
+
+				startRow := bounds.Min.Y + tile*tileRows
+				endRow := startRow + tileRows
+				if endRow > bounds.Max.Y {
+					endRow = bounds.Max.Y
+				}
+				for y := startRow; y < endRow; y++ {
+					modifyRow(y)
+				}
+			}
+		}()
+	}
+
+	waitGroup.Wait()
+	return ctx.Err()
+}
+
+// rowFunc returns the function that modifies a row of pixels of src within the
+// bounds and writes it to dst.
+func rowFunc(modifier PixelModifier, src image.Image, bounds image.Rectangle,
+	dst *image.RGBA) func(y int) {
+	rgba, isRGBA := src.(*image.RGBA)
+	rowModifier, isRowModifier := modifier.(RowModifier)
+	switch {
+	case isRGBA && isRowModifier && bounds.In(rgba.Bounds()):
+		return func(y int) {
+			offset := dst.PixOffset(bounds.Min.X, y)
+			rowModifier.ModifyRow(image.Pt(bounds.Min.X, y),
+				dst.Pix[offset:offset+bounds.Dx()*4], rgba)
+		}
+	case isRGBA:
+		return func(y int) {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				dst.SetRGBA(x, y, modifier.ModifyPixel(image.Pt(x, y),
+					rgba.RGBAAt(x, y), src))
+			}
+		}
+	default:
+		return func(y int) {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				oldColor := color.RGBAModel.Convert(src.At(x, y)).(color.RGBA)
+				dst.SetRGBA(x, y, modifier.ModifyPixel(image.Pt(x, y),
+					oldColor, src))
+			}
+		}
+	}
+}
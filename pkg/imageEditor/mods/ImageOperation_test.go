@@ -0,0 +1,147 @@
+package mods
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPixelOperation_Apply(t *testing.T) {
+	src := newRandomImage(image.Rect(1, 2, 9, 7), 5)
+	got, err := NewPixelOperation(NewNegative()).Apply(src)
+	assert.NoError(t, err)
+	assert.Equal(t, modifyImage(NewNegative(), src), got)
+}
+
+func TestModifyPixels(t *testing.T) {
+	src := newGrayImage([][]uint8{
+		{10, 20, 30},
+		{40, 50, 60},
+		{70, 80, 90},
+	})
+	bounds := image.Rect(1, 1, 2, 2)
+
+	got := ModifyPixels(NewBoxBlur(1, FilterOptions{Edge: EDGE_TRANSPARENT}),
+		src, bounds)
+	assert.Equal(t, bounds, got.Bounds())
+	assert.Equal(t, []uint8{50, 50, 50, 255}, got.Pix,
+		"the modifier must see the pixels outside the bounds")
+}
+
+// pixelModifier hides the ModifyRow method of a modifier.
+type pixelModifier struct {
+	modifier PixelModifier
+}
+
+func (modifier pixelModifier) ModifyPixel(position image.Point,
+	col color.RGBA, src image.Image) color.RGBA {
+	return modifier.modifier.ModifyPixel(position, col, src)
+}
+
+func TestModifyPixels_paths(t *testing.T) {
+	random := newRandomImage(image.Rect(0, 0, 23, 17), 7)
+	nrgba := image.NewNRGBA(random.Bounds())
+	draw.Draw(nrgba, nrgba.Bounds(), random, image.Point{}, draw.Src)
+	rgba := image.NewRGBA(nrgba.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), nrgba, image.Point{}, draw.Src)
+	bounds := image.Rect(2, 1, 20, 16)
+
+	for _, modifier := range []PixelModifier{NewNegative(), NewGamma(0.5)} {
+		want := ModifyPixels(pixelModifier{modifier}, nrgba, bounds)
+		assert.Equal(t, want, ModifyPixels(pixelModifier{modifier}, rgba,
+			bounds), "the pixels of *image.RGBA must be read directly")
+		assert.Equal(t, want, ModifyPixels(modifier, rgba, bounds),
+			"the rows must be modified like the pixels")
+	}
+}
+
+// cancellingModifier counts the modified pixels and cancels the context when
+// it modifies the first one.
+type cancellingModifier struct {
+	cancel context.CancelFunc
+	count  int
+}
+
+func (modifier *cancellingModifier) ModifyPixel(position image.Point,
+	col color.RGBA, src image.Image) color.RGBA {
+	modifier.cancel()
+	modifier.count++
+	return col
+}
+
+func TestModifyPixelsContext(t *testing.T) {
+	// The rows of the image are split into 5 tiles of 8 rows.
+	src := newRandomImage(image.Rect(0, 0, 4096, 40), 8)
+	want := ModifyPixels(NewNegative(), src, src.Bounds())
+
+	t.Run("workers", func(t *testing.T) {
+		for _, workers := range []int{-1, 0, 1, 3, 100} {
+			got, err := ModifyPixelsContext(context.Background(), NewNegative(),
+				src, src.Bounds(), workers)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got, "workers: %d", workers)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		got, err := ModifyPixelsContext(ctx, NewNegative(), src, src.Bounds(), 0)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+	})
+
+	t.Run("cancelled while modifying", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		modifier := &cancellingModifier{cancel: cancel}
+		got, err := ModifyPixelsContext(ctx, modifier, src, src.Bounds(), 1)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+		assert.Equal(t, 4096*8, modifier.count,
+			"the tiles after the cancellation must be skipped")
+	})
+
+	t.Run("empty bounds", func(t *testing.T) {
+		got, err := ModifyPixelsContext(context.Background(), NewNegative(),
+			src, image.Rect(3, 3, 3, 9), 0)
+		assert.NoError(t, err)
+		assert.True(t, got.Bounds().Empty())
+	})
+}
+
+func TestModifyPixels64Context(t *testing.T) {
+	src := image.NewRGBA64(image.Rect(0, 0, 300, 200))
+	for i := 0; i < len(src.Pix); i += 8 {
+		// Every pixel gets a different 16-bit red channel.
+		red := uint16(i / 8 % 0x10000)
+		src.Pix[i], src.Pix[i+1] = uint8(red>>8), uint8(red)
+		src.Pix[i+6], src.Pix[i+7] = 0xff, 0xff
+	}
+
+	t.Run("workers", func(t *testing.T) {
+		for _, workers := range []int{0, 1, 3} {
+			got, err := ModifyPixels64Context(context.Background(),
+				NewNegative(), src, src.Bounds(), workers)
+			assert.NoError(t, err)
+			for _, x := range []int{0, 1, 255, 299} {
+				assert.Equal(t, color.RGBA64{0xffff - uint16(x), 0xffff, 0xffff,
+					0xffff}, got.RGBA64At(x, 0), "workers: %d, x: %d", workers,
+					x)
+			}
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		got, err := ModifyPixels64Context(ctx, NewNegative(), src,
+			src.Bounds(), 0)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, got)
+	})
+}
@@ -0,0 +1,72 @@
+package mods
+
+// NewSharpen creates a Convolution that sharpens an image. DEFAULT_EDGE is used
+// if the edge mode of the options is not valid, as in the other built-in
+// kernels.
+func NewSharpen(options FilterOptions) *Convolution {
+	return mustConvolution(NewConvolution([][]float64{
+		{0, -1, 0},
+		{-1, 5, -1},
+		{0, -1, 0},
+	}, ConvolutionOptions{
+		Edge:          edgeOrDefault(options.Edge, DEFAULT_EDGE),
+		PreserveAlpha: true,
+		Linear:        options.Linear,
+	}))
+}
+
+// NewEmboss creates a Convolution that makes an image look raised, lit from
+// the top left corner.
+func NewEmboss(options FilterOptions) *Convolution {
+	return mustConvolution(NewConvolution([][]float64{
+		{-2, -1, 0},
+		{-1, 1, 1},
+		{0, 1, 2},
+	}, ConvolutionOptions{
+		Edge:          edgeOrDefault(options.Edge, DEFAULT_EDGE),
+		PreserveAlpha: true,
+		Linear:        options.Linear,
+	}))
+}
+
+// NewEdgeDetect creates a Convolution that keeps only the edges of an image
+// on a black background.
+func NewEdgeDetect(options FilterOptions) *Convolution {
+	return mustConvolution(NewConvolution([][]float64{
+		{-1, -1, -1},
+		{-1, 8, -1},
+		{-1, -1, -1},
+	}, ConvolutionOptions{
+		Edge:          edgeOrDefault(options.Edge, DEFAULT_EDGE),
+		PreserveAlpha: true,
+		Linear:        options.Linear,
+	}))
+}
+
+// NewBoxBlur creates a Convolution that replaces every pixel with the average
+// of the square around it. radius is the distance from the pixel to the sides
+// of the square and is at least 1.
+func NewBoxBlur(radius int, options FilterOptions) *Convolution {
+	if radius < 1 {
+		radius = 1
+	}
+
+	kernel := make([]float64, 2*radius+1)
+	for i := range kernel {
+		kernel[i] = 1
+	}
+	return mustConvolution(NewSeparableConvolution(kernel, kernel,
+		ConvolutionOptions{
+			Edge:   edgeOrDefault(options.Edge, DEFAULT_EDGE),
+			Linear: options.Linear,
+		}))
+}
+
+// mustConvolution returns the convolution of a built-in kernel, which is never
+// incorrect.
+func mustConvolution(convolution *Convolution, err error) *Convolution {
+	if err != nil {
+		panic(err)
+	}
+	return convolution
+}
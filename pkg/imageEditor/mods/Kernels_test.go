@@ -0,0 +1,54 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKernels(t *testing.T) {
+	img := newGrayImage([][]uint8{
+		{10, 20, 30},
+		{40, 50, 60},
+		{70, 80, 90},
+	})
+	tests := []struct {
+		name        string
+		convolution *Convolution
+		want        color.RGBA
+	}{
+		{"sharpen", NewSharpen(FilterOptions{}), color.RGBA{50, 50, 50, 255}},
+		{"emboss", NewEmboss(FilterOptions{}),
+			color.RGBA{255, 255, 255, 255}},
+		{"edge detect", NewEdgeDetect(FilterOptions{}),
+			color.RGBA{0, 0, 0, 255}},
+		{"box blur", NewBoxBlur(1, FilterOptions{}),
+			color.RGBA{50, 50, 50, 255}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.convolution.ModifyPixel(image.Pt(1, 1),
+				img.RGBAAt(1, 1), img)
+			assert.Equal(t, test.want, got,
+				"ModifyPixel(image.Pt(1, 1)) = %#v, want %#v", got, test.want)
+		})
+	}
+}
+
+func TestNewBoxBlur(t *testing.T) {
+	blur := NewBoxBlur(2, FilterOptions{Edge: EDGE_WRAP})
+	assert.Equal(t, []float64{1, 1, 1, 1, 1}, blur.horizontal)
+	assert.Equal(t, 25.0, blur.divisor)
+	assert.Nil(t, blur.kernel, "the separable kernel must not be built")
+	assert.Equal(t, EDGE_WRAP, blur.edge)
+
+	blur = NewBoxBlur(0, FilterOptions{})
+	assert.Equal(t, 3, blur.width, "the radius must be at least 1")
+	assert.Equal(t, DEFAULT_EDGE, blur.edge)
+
+	blur = NewBoxBlur(1, FilterOptions{Edge: "repeat"})
+	assert.Equal(t, DEFAULT_EDGE, blur.edge,
+		"an unknown edge mode must be replaced with the default one")
+}
@@ -0,0 +1,87 @@
+package mods
+
+import (
+	"image/color"
+	"sort"
+)
+
+// FilterOptions are the options of the filters that combine neighbouring
+// pixels.
+type FilterOptions struct {
+	// Edge is the edge mode of the pixels outside the image. The default edge
+	// mode of the filter is used if it is not valid.
+	Edge string
+	// Linear makes the filter combine the pixels in linear light instead of
+	// the gamma-encoded sRGB values, the way light itself mixes. Blurs then keep
+	// the brightness of the image instead of darkening the edges between
+	// bright and dark colors.
+	Linear bool
+}
+
+// srgbLinear stores the linear light value of each sRGB channel value.
+var srgbLinear = func() (values [256]float64) {
+	for i := range values {
+		values[i] = srgbToLinear(float64(i) / 255)
+	}
+	return values
+}()
+
+// srgbThresholds stores the linear light values halfway between the
+// neighbouring sRGB channel values. The number of thresholds below a linear
+// value is its rounded sRGB channel value.
+var srgbThresholds = func() (thresholds [255]float64) {
+	for i := range thresholds {
+		thresholds[i] = srgbToLinear((float64(i) + 0.5) / 255)
+	}
+	return thresholds
+}()
+
+// toLinear returns the premultiplied linear light channels of the
+// premultiplied sRGB color. The channels are in the range from 0 to 255, like
+// the alpha.
+func toLinear(col color.RGBA) [4]float64 {
+	if col.A == 0 {
+		return [4]float64{}
+	}
+
+	alpha := uint32(col.A)
+	scale := float64(col.A)
+	return [4]float64{
+		linearChannel(col.R, alpha) * scale,
+		linearChannel(col.G, alpha) * scale,
+		linearChannel(col.B, alpha) * scale,
+		scale,
+	}
+}
+
+// linearChannel returns the linear light value in the range [0, 1] of the
+// premultiplied sRGB channel value.
+func linearChannel(value uint8, alpha uint32) float64 {
+	if alpha < 255 {
+		value = unpremultiply(value, alpha)
+	}
+	return srgbLinear[value]
+}
+
+// fromLinear returns the premultiplied sRGB color of the premultiplied linear
+// light channels, which are limited to the alpha. The values are in the range
+// from 0 to 255.
+func fromLinear(r, g, b, alpha float64) color.RGBA {
+	a := clampChannel(alpha, 255)
+	if a == 0 {
+		return color.RGBA{}
+	}
+
+	return color.RGBA{
+		premultiply(srgbChannel(r/a), uint32(a)),
+		premultiply(srgbChannel(g/a), uint32(a)),
+		premultiply(srgbChannel(b/a), uint32(a)),
+		uint8(a),
+	}
+}
+
+// srgbChannel returns the rounded sRGB channel value of the linear light value
+// in the range [0, 1]. Values outside the range are clamped.
+func srgbChannel(value float64) uint8 {
+	return uint8(sort.SearchFloat64s(srgbThresholds[:], value))
+}
@@ -0,0 +1,109 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_toLinear(t *testing.T) {
+	for value := 0; value < 256; value++ {
+		col := color.RGBA{uint8(value), uint8(value), uint8(value), 255}
+		linear := toLinear(col)
+		assert.Equal(t, col, fromLinear(linear[0], linear[1], linear[2],
+			linear[3]), "opaque colors must not change")
+	}
+
+	assert.Equal(t, [4]float64{}, toLinear(color.RGBA{}))
+	half := toLinear(color.RGBA{64, 0, 128, 128})
+	assert.InDelta(t, 0.2158*128, half[0], 0.01, "the colors are premultiplied")
+	assert.InDelta(t, 128, half[2], 0.01)
+	assert.Equal(t, 128.0, half[3])
+}
+
+func Test_fromLinear(t *testing.T) {
+	tests := []struct {
+		name       string
+		r, g, b, a float64
+		want       color.RGBA
+	}{
+		{"half of the light", 127.5, 0, 255, 255, color.RGBA{188, 0, 255, 255}},
+		{"premultiplied", 63.75, 0, 127.5, 127.5, color.RGBA{94, 0, 128, 128}},
+		{"clamped to alpha", 300, -5, 100, 100, color.RGBA{100, 0, 100, 100}},
+		{"transparent", 10, 10, 10, 0.2, color.RGBA{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, fromLinear(test.r, test.g, test.b,
+				test.a))
+		})
+	}
+}
+
+func TestFilterOptions_Linear(t *testing.T) {
+	// Black and white columns average to about the half of the light, which is
+	// 188 in sRGB, while the average of the sRGB values is 128. The pixel of
+	// the box blur has two white neighbours.
+	stripes := make([][]uint8, 4)
+	for y := range stripes {
+		stripes[y] = make([]uint8, 16)
+		for x := 0; x < 16; x += 2 {
+			stripes[y][x] = 255
+		}
+	}
+	src := newGrayImage(stripes)
+
+	tests := []struct {
+		name     string
+		modifier func(options FilterOptions) PixelModifier
+		want     uint8
+		wantSRGB uint8
+	}{
+		{"gaussian blur", func(options FilterOptions) PixelModifier {
+			return NewGaussianBlurWithOptions(3, options)
+		}, 188, 128},
+		{"box blur", func(options FilterOptions) PixelModifier {
+			return NewBoxBlur(1, options)
+		}, 213, 170},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := FilterOptions{Edge: EDGE_WRAP}
+			got := test.modifier(options).ModifyPixel(image.Pt(7, 1),
+				src.RGBAAt(7, 1), src)
+			assert.InDelta(t, test.wantSRGB, got.R, 8)
+
+			options.Linear = true
+			got = test.modifier(options).ModifyPixel(image.Pt(7, 1),
+				src.RGBAAt(7, 1), src)
+			assert.InDelta(t, test.want, got.R, 8)
+			assert.Equal(t, got.R, got.G)
+			assert.Equal(t, got.R, got.B)
+		})
+	}
+}
+
+func TestFilterOptions_Linear_transparent(t *testing.T) {
+	// A red pixel on a transparent background keeps its color when blurred.
+	src := image.NewRGBA(image.Rect(0, 0, 5, 5))
+	src.SetRGBA(2, 2, color.RGBA{255, 0, 0, 255})
+	modifiers := []PixelModifier{
+		NewGaussianBlurWithOptions(1, FilterOptions{Linear: true}),
+		NewBoxBlur(1, FilterOptions{Linear: true}),
+		NewUnsharpMask(1, 1, 0, FilterOptions{Linear: true}),
+	}
+	for _, modifier := range modifiers {
+		got := modifyImage(modifier, src)
+		for i := 0; i < len(got.Pix); i += 4 {
+			if got.Pix[i+3] == 0 {
+				continue
+			}
+			assert.Equal(t, got.Pix[i+3], got.Pix[i], "%T: %v", modifier,
+				got.Pix[i:i+4])
+			assert.Zero(t, got.Pix[i+1])
+			assert.Zero(t, got.Pix[i+2])
+		}
+	}
+}
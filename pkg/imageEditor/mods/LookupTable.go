@@ -0,0 +1,142 @@
+package mods
+
+import (
+	"image/color"
+	"math"
+	"sync"
+)
+
+// lookupTable stores the precomputed new value for each channel value.
+type lookupTable [256]uint8
+
+// newLookupTable creates a lookupTable by applying the function to every
+// channel value normalized to the range [0, 1]. The results of the function
+// are clamped to the same range.
+func newLookupTable(function func(value float64) float64) lookupTable {
+	table := lookupTable{}
+	for i := range table {
+		value := function(float64(i) / 255)
+		table[i] = uint8(math.Round(math.Max(0, math.Min(1, value)) * 255))
+	}
+	return table
+}
+
+// apply replaces the color channels with the values from the table. The
+// channels of semi-transparent colors are unpremultiplied before the lookup,
+// so the table sees the same values as for an opaque color.
+func (table *lookupTable) apply(col color.RGBA) color.RGBA {
+	switch col.A {
+	case 0:
+		return col
+	case 255:
+		col.R = table[col.R]
+		col.G = table[col.G]
+		col.B = table[col.B]
+		return col
+	}
+
+	alpha := uint32(col.A)
+	col.R = premultiply(table[unpremultiply(col.R, alpha)], alpha)
+	col.G = premultiply(table[unpremultiply(col.G, alpha)], alpha)
+	col.B = premultiply(table[unpremultiply(col.B, alpha)], alpha)
+	return col
+}
+
+// unpremultiply returns the channel value without the alpha applied.
+func unpremultiply(value uint8, alpha uint32) uint8 {
+	result := (uint32(value)*255 + alpha/2) / alpha
+	if result > 255 {
+		return 255
+	}
+	return uint8(result)
+}
+
+// premultiply returns the channel value with the alpha applied.
+func premultiply(value uint8, alpha uint32) uint8 {
+	return uint8((uint32(value)*alpha + 127) / 255)
+}
+
+// applyRow replaces the color channels of the packed pixels of src with the
+// values from the table and writes the result to dst, like apply.
+func (table *lookupTable) applyRow(dst, src []uint8) {
+	for i := 0; i < len(dst); i += 4 {
+		alpha := src[i+3]
+		switch alpha {
+		case 0:
+			dst[i], dst[i+1], dst[i+2] = src[i], src[i+1], src[i+2]
+		case 255:
+			dst[i] = table[src[i]]
+			dst[i+1] = table[src[i+1]]
+			dst[i+2] = table[src[i+2]]
+		default:
+			a := uint32(alpha)
+			dst[i] = premultiply(table[unpremultiply(src[i], a)], a)
+			dst[i+1] = premultiply(table[unpremultiply(src[i+1], a)], a)
+			dst[i+2] = premultiply(table[unpremultiply(src[i+2], a)], a)
+		}
+		dst[i+3] = alpha
+	}
+}
+
+// newDeepLookupTable creates a deepLookupTable for the function, like
+// newLookupTable. The values are calculated on the first use, as most images
+// never need them.
+func newDeepLookupTable(function func(value float64) float64) *deepLookupTable {
+	return &deepLookupTable{function: function}
+}
+
+// deepLookupTable stores the precomputed new value for each 16-bit channel
+// value.
+type deepLookupTable struct {
+	function func(value float64) float64
+	once     sync.Once
+	values   []uint16
+}
+
+// apply64 replaces the color channels with the values from the table, like
+// lookupTable.apply.
+func (table *deepLookupTable) apply64(col color.RGBA64) color.RGBA64 {
+	values := table.lookup()
+	switch col.A {
+	case 0:
+		return col
+	case 0xffff:
+		col.R = values[col.R]
+		col.G = values[col.G]
+		col.B = values[col.B]
+		return col
+	}
+
+	alpha := uint32(col.A)
+	col.R = premultiply64(values[unpremultiply64(col.R, alpha)], alpha)
+	col.G = premultiply64(values[unpremultiply64(col.G, alpha)], alpha)
+	col.B = premultiply64(values[unpremultiply64(col.B, alpha)], alpha)
+	return col
+}
+
+// lookup returns the values of the table, calculating them on the first call.
+func (table *deepLookupTable) lookup() []uint16 {
+	table.once.Do(func() {
+		table.values = make([]uint16, 1<<16)
+		for i := range table.values {
+			value := table.function(float64(i) / 0xffff)
+			table.values[i] = uint16(
+				math.Round(math.Max(0, math.Min(1, value)) * 0xffff))
+		}
+	})
+	return table.values
+}
+
+// unpremultiply64 returns the 16-bit channel value without the alpha applied.
+func unpremultiply64(value uint16, alpha uint32) uint16 {
+	result := (uint32(value)*0xffff + alpha/2) / alpha
+	if result > 0xffff {
+		return 0xffff
+	}
+	return uint16(result)
+}
+
+// premultiply64 returns the 16-bit channel value with the alpha applied.
+func premultiply64(value uint16, alpha uint32) uint16 {
+	return uint16((uint32(value)*alpha + 0x7fff) / 0xffff)
+}
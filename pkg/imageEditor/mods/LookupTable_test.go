@@ -0,0 +1,88 @@
+package mods
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newLookupTable(t *testing.T) {
+	table := newLookupTable(func(value float64) float64 {
+		return value * 2
+	})
+	assert.Equal(t, uint8(0), table[0])
+	assert.Equal(t, uint8(100), table[50])
+	assert.Equal(t, uint8(255), table[128], "values must be clamped")
+	assert.Equal(t, uint8(255), table[255], "values must be clamped")
+}
+
+func Test_lookupTable_apply(t *testing.T) {
+	negative := newLookupTable(func(value float64) float64 {
+		return 1 - value
+	})
+	tests := []struct {
+		name  string
+		color color.RGBA
+		want  color.RGBA
+	}{
+		{
+			name:  "opaque color",
+			color: color.RGBA{0, 100, 255, 255},
+			want:  color.RGBA{255, 155, 0, 255},
+		},
+		{
+			name:  "transparent color",
+			color: color.RGBA{0, 0, 0, 0},
+			want:  color.RGBA{0, 0, 0, 0},
+		},
+		{
+			name:  "semi-transparent color",
+			color: color.RGBA{0, 64, 128, 128},
+			want:  color.RGBA{128, 64, 0, 128},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := negative.apply(test.color)
+			assert.Equal(t, test.want, got,
+				"lookupTable.apply(%#v) = %#v, want %#v",
+				test.color, got, test.want)
+		})
+	}
+}
+
+func Test_deepLookupTable_apply64(t *testing.T) {
+	negative := newDeepLookupTable(func(value float64) float64 {
+		return 1 - value
+	})
+	tests := []struct {
+		name  string
+		color color.RGBA64
+		want  color.RGBA64
+	}{
+		{
+			name:  "opaque color",
+			color: color.RGBA64{0, 0x1234, 0xffff, 0xffff},
+			want:  color.RGBA64{0xffff, 0xedcb, 0, 0xffff},
+		},
+		{
+			name:  "transparent color",
+			color: color.RGBA64{0, 0, 0, 0},
+			want:  color.RGBA64{0, 0, 0, 0},
+		},
+		{
+			name:  "semi-transparent color",
+			color: color.RGBA64{0, 0x4000, 0x8000, 0x8000},
+			want:  color.RGBA64{0x8000, 0x4000, 0, 0x8000},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := negative.apply64(test.color)
+			assert.Equal(t, test.want, got,
+				"deepLookupTable.apply64(%#v) = %#v, want %#v",
+				test.color, got, test.want)
+		})
+	}
+}
@@ -30,3 +30,24 @@ func (negative *Negative) ModifyPixel(_ image.Point, col color.RGBA,
 	col.B = negative.invertValues[col.B]
 	return col
 }
+
+// ModifyRow inverts a row of the image.
+func (negative *Negative) ModifyRow(start image.Point, dst []uint8,
+	src *image.RGBA) {
+	row := sourceRow(start, dst, src)
+	for i := 0; i < len(dst); i += 4 {
+		dst[i] = negative.invertValues[row[i]]
+		dst[i+1] = negative.invertValues[row[i+1]]
+		dst[i+2] = negative.invertValues[row[i+2]]
+		dst[i+3] = row[i+3]
+	}
+}
+
+// ModifyPixel64 inverts a 16-bit image pixel.
+func (*Negative) ModifyPixel64(_ image.Point, col color.RGBA64,
+	_ *image.RGBA64) color.RGBA64 {
+	col.R = 0xffff - col.R
+	col.G = 0xffff - col.G
+	col.B = 0xffff - col.B
+	return col
+}
@@ -0,0 +1,17 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+)
+
+// PixelModifier64 is a PixelModifier that can also modify a pixel with 16 bits
+// per channel. It is used for high bit depth images, so that the channels keep
+// their precision instead of being rounded to 8 bits.
+type PixelModifier64 interface {
+	PixelModifier
+	// ModifyPixel64 returns the modified pixel of src at the position. col is
+	// the premultiplied color of the pixel.
+	ModifyPixel64(position image.Point, col color.RGBA64,
+		src *image.RGBA64) color.RGBA64
+}
@@ -0,0 +1,66 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPixelModifier64_ModifyPixel64(t *testing.T) {
+	tests := []struct {
+		name     string
+		modifier PixelModifier64
+	}{
+		{"copy", NewCopy()},
+		{"negative", NewNegative()},
+		{"grayscale", NewGrayscale()},
+		{"grayscale rec601", newGrayscale(GRAYSCALE_REC601)},
+		{"grayscale lightness", newGrayscale(GRAYSCALE_LIGHTNESS)},
+		{"grayscale desaturate", newGrayscale(GRAYSCALE_DESATURATE)},
+		{"channel mixer", mustChannelMixer([][]float64{
+			{0.393, 0.769, 0.189, 0},
+			{0.349, 0.686, 0.168, 0},
+			{-0.5, 0.2, 1.5, 0.1},
+		})},
+		{"sepia", NewSepia(0.8)},
+		{"tint", NewTint(color.NRGBA{255, 128, 0, 200}, 0.6)},
+		{"duotone", NewDuotone(color.NRGBA{0, 0, 128, 255},
+			color.NRGBA{255, 255, 0, 255})},
+		{"color balance", NewColorBalance([3]float64{0.3, 0, -0.2},
+			[3]float64{0, 0.4, 0}, [3]float64{-0.5, 0, 0.1})},
+		{"hsl adjust", NewHSLAdjust(40, 1.5, 0.8, HueRange{})},
+		{"hsl adjust with hues", NewHSLAdjust(-60, 0.5, 1.2,
+			HueRange{Center: 200, Width: 90, Feather: 45})},
+		{"brightness", NewBrightness(0.2)},
+		{"contrast", NewContrast(-0.3)},
+		{"gamma", NewGamma(2.2)},
+		{"exposure", NewExposure(1)},
+	}
+	src := newRandomImage(image.Rect(-2, 3, 15, 11), 6)
+	src64 := image.NewRGBA64(src.Rect)
+	draw.Draw(src64, src64.Rect, src, src.Rect.Min, draw.Src)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+				for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+					position := image.Pt(x, y)
+					want := test.modifier.ModifyPixel(position,
+						src.RGBAAt(x, y), src)
+					got := test.modifier.ModifyPixel64(position,
+						src64.RGBA64At(x, y), src64)
+					wantValues := []uint8{want.R, want.G, want.B, want.A}
+					gotValues := []uint16{got.R, got.G, got.B, got.A}
+					// The 8-bit channels of semi-transparent pixels are
+					// rounded when they are unpremultiplied.
+					for i, value := range gotValues {
+						assert.InDelta(t, wantValues[i], float64(value)/257, 2,
+							"channel %d of the pixel %v", i, position)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package mods
+
+import "image"
+
+// RowModifier is a PixelModifier that can also modify a whole row of packed
+// pixels at once. It is used for *image.RGBA sources, so that the pixels are
+// not converted to colors one by one.
+type RowModifier interface {
+	PixelModifier
+	// ModifyRow writes the modified pixels of the row of src that starts at
+	// the position to dst. dst holds the premultiplied RGBA channels of
+	// len(dst)/4 pixels, all of them inside src.
+	ModifyRow(start image.Point, dst []uint8, src *image.RGBA)
+}
+
+// sourceRow returns the channels of the row of src that starts at the position
+// and has the length of dst.
+func sourceRow(start image.Point, dst []uint8, src *image.RGBA) []uint8 {
+	offset := src.PixOffset(start.X, start.Y)
+	return src.Pix[offset : offset+len(dst)]
+}
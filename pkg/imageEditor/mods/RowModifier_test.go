@@ -0,0 +1,59 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowModifier_ModifyRow(t *testing.T) {
+	tests := []struct {
+		name     string
+		modifier RowModifier
+	}{
+		{"copy", NewCopy()},
+		{"negative", NewNegative()},
+		{"grayscale", NewGrayscale()},
+		{"grayscale rec709", newGrayscale(GRAYSCALE_REC709)},
+		{"grayscale lightness", newGrayscale(GRAYSCALE_LIGHTNESS)},
+		{"grayscale desaturate", newGrayscale(GRAYSCALE_DESATURATE)},
+		{"channel mixer", mustChannelMixer([][]float64{
+			{0.393, 0.769, 0.189, 0},
+			{0.349, 0.686, 0.168, 0},
+			{-0.5, 0.2, 1.5, 0.1},
+		})},
+		{"sepia", NewSepia(0.8)},
+		{"tint", NewTint(color.NRGBA{255, 128, 0, 200}, 0.6)},
+		{"duotone", NewDuotone(color.NRGBA{0, 0, 128, 255},
+			color.NRGBA{255, 255, 0, 255})},
+		{"color balance", NewColorBalance([3]float64{0.3, 0, -0.2},
+			[3]float64{0, 0.4, 0}, [3]float64{-0.5, 0, 0.1})},
+		{"hsl adjust", NewHSLAdjust(40, 1.5, 0.8, HueRange{})},
+		{"hsl adjust with hues", NewHSLAdjust(-60, 0.5, 1.2,
+			HueRange{Center: 200, Width: 90, Feather: 45})},
+		{"brightness", NewBrightness(0.2)},
+		{"contrast", NewContrast(-0.3)},
+		{"gamma", NewGamma(2.2)},
+		{"exposure", NewExposure(1)},
+		{"gaussian blur", NewGaussianBlur(1.5)},
+	}
+	src := newRandomImage(image.Rect(-2, 3, 15, 11), 6)
+	start := image.Pt(1, 5)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dst := make([]uint8, 10*4)
+			test.modifier.ModifyRow(start, dst, src)
+
+			want := make([]uint8, 0, len(dst))
+			for x := start.X; x < start.X+10; x++ {
+				col := test.modifier.ModifyPixel(image.Pt(x, start.Y),
+					src.RGBAAt(x, start.Y), src)
+				want = append(want, col.R, col.G, col.B, col.A)
+			}
+			assert.Equal(t, want, dst,
+				"ModifyRow must give the same pixels as ModifyPixel")
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package mods
+
+import "math"
+
+// sepiaMatrix is the channel matrix of the full sepia tone.
+var sepiaMatrix = [3][4]float64{
+	{0.393, 0.769, 0.189, 0},
+	{0.349, 0.686, 0.168, 0},
+	{0.272, 0.534, 0.131, 0},
+}
+
+// NewSepia creates a ChannelMixer that gives an image the brown tone of old
+// photographs. strength is in the range [0, 1]: 0 keeps the image unchanged and
+// 1 applies the full tone. Values outside the range are clamped.
+func NewSepia(strength float64) *ChannelMixer {
+	strength = math.Max(0, math.Min(1, strength))
+
+	var matrix [3][4]float64
+	for i, row := range sepiaMatrix {
+		for j, weight := range row {
+			matrix[i][j] = weight * strength
+		}
+		matrix[i][i] += 1 - strength
+	}
+	return newChannelMixer(matrix)
+}
@@ -0,0 +1,40 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSepia(t *testing.T) {
+	tests := []struct {
+		name     string
+		strength float64
+		color    color.RGBA
+		want     color.RGBA
+	}{
+		{"no strength", 0, color.RGBA{100, 150, 200, 255},
+			color.RGBA{100, 150, 200, 255}},
+		{"full strength", 1, color.RGBA{100, 150, 200, 255},
+			color.RGBA{192, 171, 133, 255}},
+		{"half strength", 0.5, color.RGBA{100, 150, 200, 255},
+			color.RGBA{146, 161, 167, 255}},
+		{"clamped strength", 2, color.RGBA{100, 150, 200, 255},
+			color.RGBA{192, 171, 133, 255}},
+		{"white", 1, color.RGBA{255, 255, 255, 255},
+			color.RGBA{255, 255, 239, 255}},
+		{"semi-transparent color", 1, color.RGBA{50, 75, 100, 128},
+			color.RGBA{96, 86, 67, 128}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NewSepia(test.strength).ModifyPixel(image.Point{},
+				test.color, nil)
+			assert.Equal(t, test.want, got,
+				"NewSepia(%v).ModifyPixel(%#v) = %#v, want %#v",
+				test.strength, test.color, got, test.want)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package mods
+
+import (
+	"context"
+	"image"
+	"sync"
+)
+
+// sourcePreparer is implemented by the modifiers that calculate a value from
+// the whole source image. ModifyPixelsContext prepares the value before the
+// pixels are modified, so that the calculation stops when the context is done.
+type sourcePreparer interface {
+	prepare(ctx context.Context, src image.Image) error
+}
+
+// sourceCache stores a value calculated from a whole source image, so that
+// modifiers that need more than the neighbourhood of a pixel calculate it once
+// per image. The source must not change while it is modified, which holds for
+// the images of the editor. It is safe for concurrent use.
+type sourceCache[T any] struct {
+	mutex sync.RWMutex
+	src   image.Image
+	value T
+	ok    bool
+}
+
+// get returns the value of the image, calculating it if the image differs from
+// the one of the stored value. The error of the calculation, which is only
+// returned when the context is done, is returned and nothing is stored.
+func (cache *sourceCache[T]) get(ctx context.Context, src image.Image,
+	calculate func(ctx context.Context, src image.Image) (T, error)) (T, error) {
+	cache.mutex.RLock()
+	value, ok := cache.value, cache.ok && cache.src == src
+	cache.mutex.RUnlock()
+	if ok {
+		return value, nil
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if !cache.ok || cache.src != src {
+		value, err := calculate(ctx, src)
+		if err != nil {
+			return value, err
+		}
+		cache.value = value
+		cache.src = src
+		cache.ok = true
+	}
+	return cache.value, nil
+}
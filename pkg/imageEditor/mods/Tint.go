@@ -0,0 +1,23 @@
+package mods
+
+import (
+	"image/color"
+	"math"
+)
+
+// NewTint creates a ChannelMixer that blends the colors of an image with the
+// tint color. amount is in the range [0, 1]: 0 keeps the image unchanged and 1
+// fills it with the color. The amount is also multiplied by the alpha of the
+// color, so a transparent color does not change the image. Values outside the
+// range are clamped.
+func NewTint(tint color.Color, amount float64) *ChannelMixer {
+	col := color.NRGBAModel.Convert(tint).(color.NRGBA)
+	amount = math.Max(0, math.Min(1, amount)) * float64(col.A) / 255
+
+	var matrix [3][4]float64
+	for i, value := range [3]uint8{col.R, col.G, col.B} {
+		matrix[i][i] = 1 - amount
+		matrix[i][3] = float64(value) / 255 * amount
+	}
+	return newChannelMixer(matrix)
+}
@@ -0,0 +1,44 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTint(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	tests := []struct {
+		name   string
+		tint   color.Color
+		amount float64
+		color  color.RGBA
+		want   color.RGBA
+	}{
+		{"no amount", red, 0, color.RGBA{0, 100, 255, 255},
+			color.RGBA{0, 100, 255, 255}},
+		{"full amount", red, 1, color.RGBA{0, 100, 255, 255},
+			color.RGBA{255, 0, 0, 255}},
+		{"half amount", red, 0.5, color.RGBA{0, 100, 255, 255},
+			color.RGBA{128, 50, 128, 255}},
+		{"clamped amount", red, -1, color.RGBA{0, 100, 255, 255},
+			color.RGBA{0, 100, 255, 255}},
+		{"semi-transparent tint", color.NRGBA{255, 0, 0, 128}, 1,
+			color.RGBA{0, 100, 255, 255}, color.RGBA{128, 50, 127, 255}},
+		{"transparent tint", color.NRGBA{}, 1, color.RGBA{0, 100, 255, 255},
+			color.RGBA{0, 100, 255, 255}},
+		{"semi-transparent color", red, 1, color.RGBA{0, 50, 128, 128},
+			color.RGBA{128, 0, 0, 128}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NewTint(test.tint, test.amount).ModifyPixel(image.Point{},
+				test.color, nil)
+			assert.Equal(t, test.want, got,
+				"NewTint(%v, %v).ModifyPixel(%#v) = %#v, want %#v",
+				test.tint, test.amount, test.color, got, test.want)
+		})
+	}
+}
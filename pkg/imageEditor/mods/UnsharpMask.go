@@ -0,0 +1,77 @@
+package mods
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+)
+
+// NewUnsharpMask creates a new UnsharpMask object. sigma is the degree of the
+// blur that finds the details, amount is the strength of the sharpening, e.g.
+// 1 doubles the contrast of the details, and threshold is the minimal
+// difference from the blurred image, as a fraction of the full range, that is
+// sharpened. The blur uses the edge mode of the options, or DEFAULT_EDGE if it
+// is not valid. With the linear option the difference is also found in linear
+// light.
+func NewUnsharpMask(sigma, amount, threshold float64,
+	options FilterOptions) *UnsharpMask {
+	options.Edge = edgeOrDefault(options.Edge, DEFAULT_EDGE)
+	return &UnsharpMask{
+		blur:      NewGaussianBlurWithOptions(sigma, options),
+		amount:    amount,
+		threshold: threshold * 255,
+		linear:    options.Linear,
+	}
+}
+
+// UnsharpMask is a type representing a modifier that sharpens an image by
+// adding the difference between the image and its blurred copy.
+type UnsharpMask struct {
+	blur      *GaussianBlur
+	amount    float64
+	threshold float64
+	linear    bool
+}
+
+// prepare blurs the source image unless it is already blurred.
+func (mask *UnsharpMask) prepare(ctx context.Context, src image.Image) error {
+	return mask.blur.prepare(ctx, src)
+}
+
+// ModifyPixel sharpens the image pixel.
+func (mask *UnsharpMask) ModifyPixel(position image.Point, col color.RGBA,
+	src image.Image) color.RGBA {
+	blurred := mask.blur.ModifyPixel(position, col, src)
+	alpha := float64(col.A)
+	if mask.linear {
+		value, blurredValue := toLinear(col), toLinear(blurred)
+		return fromLinear(
+			mask.sharpen(value[0], blurredValue[0]),
+			mask.sharpen(value[1], blurredValue[1]),
+			mask.sharpen(value[2], blurredValue[2]),
+			alpha)
+	}
+	return color.RGBA{
+		mask.sharpenChannel(col.R, blurred.R, alpha),
+		mask.sharpenChannel(col.G, blurred.G, alpha),
+		mask.sharpenChannel(col.B, blurred.B, alpha),
+		col.A,
+	}
+}
+
+// sharpenChannel returns the sharpened channel value.
+func (mask *UnsharpMask) sharpenChannel(value, blurred uint8,
+	alpha float64) uint8 {
+	return uint8(clampChannel(mask.sharpen(float64(value), float64(blurred)),
+		alpha))
+}
+
+// sharpen returns the sharpened value, which is not limited to the range.
+func (mask *UnsharpMask) sharpen(value, blurred float64) float64 {
+	difference := value - blurred
+	if math.Abs(difference) < mask.threshold {
+		return value
+	}
+	return value + mask.amount*difference
+}
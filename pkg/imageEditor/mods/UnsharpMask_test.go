@@ -0,0 +1,55 @@
+package mods
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnsharpMask_ModifyPixel(t *testing.T) {
+	img := newGrayImage([][]uint8{
+		{100, 100, 100},
+		{100, 160, 100},
+		{100, 100, 100},
+	})
+	tests := []struct {
+		name      string
+		amount    float64
+		threshold float64
+		position  image.Point
+		want      color.RGBA
+	}{
+		{
+			name:     "detail",
+			amount:   1,
+			position: image.Pt(1, 1),
+			want:     color.RGBA{210, 210, 210, 255},
+		},
+		{
+			name:     "no amount",
+			amount:   0,
+			position: image.Pt(1, 1),
+			want:     color.RGBA{160, 160, 160, 255},
+		},
+		{
+			name:      "below threshold",
+			amount:    1,
+			threshold: 0.5,
+			position:  image.Pt(1, 1),
+			want:      color.RGBA{160, 160, 160, 255},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mask := NewUnsharpMask(1, test.amount, test.threshold,
+				FilterOptions{})
+			col := img.RGBAAt(test.position.X, test.position.Y)
+			got := mask.ModifyPixel(test.position, col, img)
+			assert.Equal(t, test.want, got,
+				"UnsharpMask.ModifyPixel(%v) = %#v, want %#v",
+				test.position, got, test.want)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Package resample provides resampling kernels and functions for changing the
+// pixel dimensions of images.
+package resample
+
+import "math"
+
+// Names of the supported resampling kernels.
+const (
+	NEAREST  = "nearest"
+	BILINEAR = "bilinear"
+	BICUBIC  = "bicubic"
+	LANCZOS3 = "lanczos3"
+)
+
+// DEFAULT_KERNEL is the name of the kernel used when no kernel is specified.
+const DEFAULT_KERNEL = LANCZOS3
+
+// Kernel is a resampling filter. Weight is evaluated on the interval
+// [-Support, Support]. A kernel with zero support takes the nearest sample.
+type Kernel struct {
+	// Name is the name of the kernel.
+	Name string
+	// Support is the radius of the kernel in source pixels.
+	Support float64
+	// Weight returns the weight of a sample at the distance x.
+	Weight func(x float64) float64
+}
+
+// NearestNeighbor takes the value of the closest source pixel.
+var NearestNeighbor = Kernel{NEAREST, 0, nil}
+
+// Bilinear interpolates linearly between the two closest source pixels.
+var Bilinear = Kernel{BILINEAR, 1, func(x float64) float64 {
+	x = math.Abs(x)
+	if x < 1 {
+		return 1 - x
+	}
+	return 0
+}}
+
+// Bicubic is the Catmull-Rom cubic kernel.
+var Bicubic = Kernel{BICUBIC, 2, func(x float64) float64 {
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return (1.5*x-2.5)*x*x + 1
+	case x < 2:
+		return ((-0.5*x+2.5)*x-4)*x + 2
+	default:
+		return 0
+	}
+}}
+
+// Lanczos3 is the Lanczos kernel with three lobes.
+var Lanczos3 = Kernel{LANCZOS3, 3, func(x float64) float64 {
+	x = math.Abs(x)
+	if x < 3 {
+		return sinc(x) * sinc(x/3)
+	}
+	return 0
+}}
+
+// KernelByName returns the kernel with the given name. An empty name returns
+// the default kernel. The second value reports whether the kernel exists.
+func KernelByName(name string) (Kernel, bool) {
+	switch name {
+	case "":
+		return KernelByName(DEFAULT_KERNEL)
+	case NEAREST:
+		return NearestNeighbor, true
+	case BILINEAR:
+		return Bilinear, true
+	case BICUBIC:
+		return Bicubic, true
+	case LANCZOS3:
+		return Lanczos3, true
+	default:
+		return Kernel{}, false
+	}
+}
+
+// sinc is the normalized sinc function.
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	x *= math.Pi
+	return math.Sin(x) / x
+}
@@ -0,0 +1,79 @@
+package resample
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKernelByName(t *testing.T) {
+	tests := []struct {
+		name     string
+		kernel   string
+		want     string
+		wantIsOk bool
+	}{
+		{
+			name:     "empty name",
+			kernel:   "",
+			want:     DEFAULT_KERNEL,
+			wantIsOk: true,
+		},
+		{
+			name:     "nearest",
+			kernel:   NEAREST,
+			want:     NEAREST,
+			wantIsOk: true,
+		},
+		{
+			name:     "bilinear",
+			kernel:   BILINEAR,
+			want:     BILINEAR,
+			wantIsOk: true,
+		},
+		{
+			name:     "bicubic",
+			kernel:   BICUBIC,
+			want:     BICUBIC,
+			wantIsOk: true,
+		},
+		{
+			name:     "lanczos3",
+			kernel:   LANCZOS3,
+			want:     LANCZOS3,
+			wantIsOk: true,
+		},
+		{
+			name:     "unknown kernel",
+			kernel:   "beleberda",
+			want:     "",
+			wantIsOk: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := KernelByName(test.kernel)
+			assert.Equal(t, test.wantIsOk, ok, "KernelByName(%q)", test.kernel)
+			assert.Equal(t, test.want, got.Name,
+				"KernelByName(%q).Name = %q, want %q",
+				test.kernel, got.Name, test.want)
+		})
+	}
+}
+
+func TestKernel_Weight(t *testing.T) {
+	kernels := []Kernel{Bilinear, Bicubic, Lanczos3}
+
+	for _, kernel := range kernels {
+		t.Run(kernel.Name, func(t *testing.T) {
+			assert.InDelta(t, 1, kernel.Weight(0), 1e-9,
+				"%s.Weight(0) must be 1", kernel.Name)
+			assert.InDelta(t, 0, kernel.Weight(1), 1e-9,
+				"%s.Weight(1) must be 0", kernel.Name)
+			assert.Equal(t, 0.0, kernel.Weight(kernel.Support),
+				"%s.Weight(%g) must be 0", kernel.Name, kernel.Support)
+			assert.Equal(t, kernel.Weight(0.3), kernel.Weight(-0.3),
+				"%s must be symmetric", kernel.Name)
+		})
+	}
+}
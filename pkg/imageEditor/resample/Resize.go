@@ -0,0 +1,240 @@
+package resample
+
+import (
+	"image"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// Resize returns a copy of src scaled to the given width and height using the
+// given kernel. The bounds of the result start at the origin. If width or
+// height is not positive, an empty image is returned.
+func Resize(src *image.RGBA, width, height int, kernel Kernel) *image.RGBA {
+	if width <= 0 || height <= 0 || src.Rect.Empty() {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	resize(src.Rect.Dx(), src.Rect.Dy(), width, height, kernel,
+		func(y int, values []float32) {
+			srcRow := src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y):]
+			for i := range values {
+				values[i] = float32(srcRow[i])
+			}
+		},
+		func(y int, values []float32) {
+			dstRow := dst.Pix[y*dst.Stride : y*dst.Stride+width*4]
+			for x := 0; x < width*4; x += 4 {
+				alpha := clamp(values[x+3])
+				// Colors are premultiplied, so no channel may exceed alpha.
+				dstRow[x] = clampTo(values[x], alpha)
+				dstRow[x+1] = clampTo(values[x+1], alpha)
+				dstRow[x+2] = clampTo(values[x+2], alpha)
+				dstRow[x+3] = alpha
+			}
+		})
+	return dst
+}
+
+// Resize64 is like Resize, but it scales a 16-bit image and keeps its depth.
+func Resize64(src *image.RGBA64, width, height int,
+	kernel Kernel) *image.RGBA64 {
+	if width <= 0 || height <= 0 || src.Rect.Empty() {
+		return image.NewRGBA64(image.Rect(0, 0, 0, 0))
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+	resize(src.Rect.Dx(), src.Rect.Dy(), width, height, kernel,
+		func(y int, values []float32) {
+			srcRow := src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y):]
+			for i := range values {
+				values[i] = float32(uint16(srcRow[i*2])<<8 |
+					uint16(srcRow[i*2+1]))
+			}
+		},
+		func(y int, values []float32) {
+			dstRow := dst.Pix[y*dst.Stride : y*dst.Stride+width*8]
+			for i := 0; i < width*4; i += 4 {
+				alpha := clampTo16(values[i+3], 0xffff)
+				channels := [4]uint16{
+					clampTo16(values[i], alpha),
+					clampTo16(values[i+1], alpha),
+					clampTo16(values[i+2], alpha),
+					alpha,
+				}
+				for c, value := range channels {
+					dstRow[(i+c)*2] = uint8(value >> 8)
+					dstRow[(i+c)*2+1] = uint8(value)
+				}
+			}
+		})
+	return dst
+}
+
+// resize scales the srcWidth x srcHeight image to width x height. readRow
+// fills the values with the premultiplied channels of the source row y, and
+// writeRow stores the values of the destination row y.
+func resize(srcWidth, srcHeight, width, height int, kernel Kernel,
+	readRow, writeRow func(y int, values []float32)) {
+	columns := contributions(srcWidth, width, kernel)
+	rows := contributions(srcHeight, height, kernel)
+
+	// The horizontal pass keeps the intermediate values in floating point so
+	// that rounding happens only once.
+	temp := make([]float32, width*srcHeight*4)
+	parallel(srcHeight, func(start, end int) {
+		srcRow := make([]float32, srcWidth*4)
+		for y := start; y < end; y++ {
+			readRow(y, srcRow)
+			tempRow := temp[y*width*4 : (y+1)*width*4]
+			for x, contribution := range columns {
+				var r, g, b, a float32
+				for i, weight := range contribution.weights {
+					offset := (contribution.start + i) * 4
+					r += srcRow[offset] * weight
+					g += srcRow[offset+1] * weight
+					b += srcRow[offset+2] * weight
+					a += srcRow[offset+3] * weight
+				}
+				tempRow[x*4] = r
+				tempRow[x*4+1] = g
+				tempRow[x*4+2] = b
+				tempRow[x*4+3] = a
+			}
+		}
+	})
+
+	parallel(height, func(start, end int) {
+		dstRow := make([]float32, width*4)
+		for y := start; y < end; y++ {
+			contribution := rows[y]
+			for x := 0; x < width; x++ {
+				var r, g, b, a float32
+				for i, weight := range contribution.weights {
+					offset := ((contribution.start+i)*width + x) * 4
+					r += temp[offset] * weight
+					g += temp[offset+1] * weight
+					b += temp[offset+2] * weight
+					a += temp[offset+3] * weight
+				}
+				dstRow[x*4] = r
+				dstRow[x*4+1] = g
+				dstRow[x*4+2] = b
+				dstRow[x*4+3] = a
+			}
+			writeRow(y, dstRow)
+		}
+	})
+}
+
+// contribution describes which source pixels, and with which weights, make up
+// a single destination pixel.
+type contribution struct {
+	start   int
+	weights []float32
+}
+
+// contributions calculates the contributions of the source pixels for every
+// pixel of a line scaled from srcLength to dstLength.
+func contributions(srcLength, dstLength int, kernel Kernel) []contribution {
+	result := make([]contribution, dstLength)
+	scale := float64(srcLength) / float64(dstLength)
+
+	if kernel.Support <= 0 || kernel.Weight == nil {
+		for i := range result {
+			index := int((float64(i) + 0.5) * scale)
+			if index >= srcLength {
+				index = srcLength - 1
+			}
+			result[i] = contribution{index, []float32{1}}
+		}
+		return result
+	}
+
+	// When downscaling the kernel is stretched to cover every source pixel.
+	filterScale := math.Max(scale, 1)
+	support := kernel.Support * filterScale
+
+	for i := range result {
+		center := (float64(i)+0.5)*scale - 0.5
+		start := int(math.Ceil(center - support))
+		end := int(math.Floor(center + support))
+		if start < 0 {
+			start = 0
+		}
+		if end > srcLength-1 {
+			end = srcLength - 1
+		}
+
+		weights := make([]float32, end-start+1)
+		var sum float64
+		for j := start; j <= end; j++ {
+			weight := kernel.Weight((float64(j) - center) / filterScale)
+			weights[j-start] = float32(weight)
+			sum += weight
+		}
+		if sum != 0 {
+			for j := range weights {
+				weights[j] = float32(float64(weights[j]) / sum)
+			}
+		}
+		result[i] = contribution{start, weights}
+	}
+
+	return result
+}
+
+// parallel splits the range [0, count) into parts and processes them in
+// separate goroutines.
+func parallel(count int, process func(start, end int)) {
+	groupCount := runtime.NumCPU()
+	if groupCount > count {
+		groupCount = count
+	}
+
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(groupCount)
+	perGroup := count / groupCount
+
+	for group := 0; group < groupCount; group++ {
+		start := perGroup * group
+		end := start + perGroup
+		if group == groupCount-1 {
+			end = count
+		}
+		go func() {
+			defer waitGroup.Done()
+			process(start, end)
+		}()
+	}
+
+	waitGroup.Wait()
+}
+
+// clamp rounds the value and limits it to the range of uint8.
+func clamp(value float32) uint8 {
+	return clampTo(value, 255)
+}
+
+// clampTo rounds the value and limits it to the range [0, limit].
+func clampTo(value float32, limit uint8) uint8 {
+	if value <= 0 {
+		return 0
+	}
+	if value >= float32(limit) {
+		return limit
+	}
+	return uint8(value + 0.5)
+}
+
+// clampTo16 rounds the value and limits it to the range [0, limit].
+func clampTo16(value float32, limit uint16) uint16 {
+	if value <= 0 {
+		return 0
+	}
+	if value >= float32(limit) {
+		return limit
+	}
+	return uint16(value + 0.5)
+}
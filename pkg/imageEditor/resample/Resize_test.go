@@ -0,0 +1,160 @@
+package resample
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		name         string
+		kernel       Kernel
+		bounds       image.Rectangle
+		sourceMatrix []uint8
+		width        int
+		height       int
+		wantMatrix   []uint8
+	}{
+		{
+			name:   "nearest upscaling",
+			kernel: NearestNeighbor,
+			bounds: image.Rect(0, 0, 2, 1),
+			sourceMatrix: []uint8{
+				10, 20, 30, 255, 40, 50, 60, 255,
+			},
+			width:  4,
+			height: 1,
+			wantMatrix: []uint8{
+				10, 20, 30, 255, 10, 20, 30, 255,
+				40, 50, 60, 255, 40, 50, 60, 255,
+			},
+		},
+		{
+			name:   "bilinear downscaling",
+			kernel: Bilinear,
+			bounds: image.Rect(1, 1, 3, 3),
+			sourceMatrix: []uint8{
+				0, 0, 0, 255, 100, 100, 100, 255,
+				100, 100, 100, 255, 200, 200, 200, 255,
+			},
+			width:      1,
+			height:     1,
+			wantMatrix: []uint8{100, 100, 100, 255},
+		},
+		{
+			name:   "lanczos3 downscaling",
+			kernel: Lanczos3,
+			bounds: image.Rect(0, 0, 4, 1),
+			sourceMatrix: []uint8{
+				0, 0, 0, 0, 0, 0, 0, 0,
+				255, 255, 255, 255, 255, 255, 255, 255,
+			},
+			width:  2,
+			height: 1,
+			wantMatrix: []uint8{
+				18, 18, 18, 18, 237, 237, 237, 237,
+			},
+		},
+		{
+			name:         "empty destination",
+			kernel:       Bicubic,
+			bounds:       image.Rect(0, 0, 1, 1),
+			sourceMatrix: []uint8{1, 2, 3, 4},
+			width:        0,
+			height:       1,
+			wantMatrix:   []uint8{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src := image.NewRGBA(test.bounds)
+			src.Pix = test.sourceMatrix
+
+			got := Resize(src, test.width, test.height, test.kernel)
+
+			if test.width > 0 && test.height > 0 {
+				assert.Equal(t, image.Rect(0, 0, test.width, test.height),
+					got.Rect, "incorrect bounds of the resized image")
+			}
+			assert.Equal(t, test.wantMatrix, got.Pix, "incorrect pixels")
+		})
+	}
+}
+
+func TestResize_solidColor(t *testing.T) {
+	solid := color.RGBA{120, 60, 30, 255}
+	src := image.NewRGBA(image.Rect(0, 0, 7, 5))
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3] =
+			solid.R, solid.G, solid.B, solid.A
+	}
+
+	kernels := []Kernel{NearestNeighbor, Bilinear, Bicubic, Lanczos3}
+	for _, kernel := range kernels {
+		for _, size := range []image.Point{{3, 2}, {13, 11}} {
+			got := Resize(src, size.X, size.Y, kernel)
+			for y := 0; y < size.Y; y++ {
+				for x := 0; x < size.X; x++ {
+					assert.Equal(t, solid, got.RGBAAt(x, y),
+						"%s: pixel (%d, %d) of %v", kernel.Name, x, y, size)
+				}
+			}
+		}
+	}
+}
+
+func TestResize64(t *testing.T) {
+	src := image.NewRGBA64(image.Rect(1, 1, 3, 3))
+	src.SetRGBA64(1, 1, color.RGBA64{0, 0, 0, 0xffff})
+	src.SetRGBA64(2, 1, color.RGBA64{1001, 2001, 3001, 0xffff})
+	src.SetRGBA64(1, 2, color.RGBA64{1001, 2001, 3001, 0xffff})
+	src.SetRGBA64(2, 2, color.RGBA64{2002, 4002, 6002, 0xffff})
+
+	got := Resize64(src, 1, 1, Bilinear)
+	assert.Equal(t, image.Rect(0, 0, 1, 1), got.Rect)
+	assert.Equal(t, color.RGBA64{1001, 2001, 3001, 0xffff}, got.RGBA64At(0, 0),
+		"the precision of 16 bits must be kept")
+
+	got = Resize64(src, 4, 3, NearestNeighbor)
+	assert.Equal(t, src.RGBA64At(2, 1), got.RGBA64At(3, 0))
+	assert.Equal(t, src.RGBA64At(1, 2), got.RGBA64At(0, 2))
+
+	assert.Empty(t, Resize64(src, 0, 1, Bicubic).Pix)
+}
+
+func TestResize64_sameAsResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 7, 5))
+	for i := 0; i < len(src.Pix); i += 4 {
+		alpha := uint8(i * 7)
+		src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3] =
+			alpha/2, alpha/3, alpha, alpha
+	}
+	src64 := image.NewRGBA64(src.Rect)
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 7; x++ {
+			src64.Set(x, y, src.At(x, y))
+		}
+	}
+
+	for _, kernel := range []Kernel{NearestNeighbor, Bilinear, Bicubic,
+		Lanczos3} {
+		want := Resize(src, 4, 9, kernel)
+		got := Resize64(src64, 4, 9, kernel)
+		for y := 0; y < 9; y++ {
+			for x := 0; x < 4; x++ {
+				w, g := want.RGBAAt(x, y), got.RGBA64At(x, y)
+				for c, pair := range [][2]int{
+					{int(w.R), int(g.R >> 8)}, {int(w.G), int(g.G >> 8)},
+					{int(w.B), int(g.B >> 8)}, {int(w.A), int(g.A >> 8)},
+				} {
+					assert.InDelta(t, pair[0], pair[1], 1,
+						"%s: channel %d of pixel (%d, %d)", kernel.Name, c,
+						x, y)
+				}
+			}
+		}
+	}
+}